@@ -0,0 +1,50 @@
+// Command fakemonzo runs a local fake Monzo API for development. See package
+// go-monzo-wallet/internal/fakemonzo for how to point the app at it.
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go-monzo-wallet/internal/fakemonzo"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8081", "address to listen on")
+	fixturesPath := flag.String("fixtures", "", "JSON fixtures file (defaults to the bundled fixtures)")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	failureRate := flag.Float64("failure-rate", 0, "fraction of requests, between 0 and 1, answered with a 500 error")
+	flag.Parse()
+
+	var (
+		fixtures *fakemonzo.Fixtures
+		err      error
+	)
+	if *fixturesPath != "" {
+		fixtures, err = fakemonzo.LoadFixtures(*fixturesPath)
+	} else {
+		fixtures, err = fakemonzo.DefaultFixtures()
+	}
+	if err != nil {
+		logrus.Error("loading fixtures: ", err)
+		os.Exit(1)
+	}
+
+	srv := fakemonzo.New(fixtures)
+	srv.SetLatency(*latency)
+	srv.SetFailureRate(*failureRate)
+
+	logrus.Infof("fake Monzo API listening on http://%s", *addr)
+	httpSrv := &http.Server{
+		Addr:              *addr,
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if err := httpSrv.ListenAndServe(); err != nil {
+		logrus.Error(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,17 @@
+// Command go-monzo-wallet-cli runs the subcommands of the app without its
+// window, see package go-monzo-wallet/internal/cli. Unlike the app, it does
+// not link the UI toolkit, so it builds without cgo and the display server
+// libraries, e.g. on a plain CI image:
+//
+//	CGO_ENABLED=0 go build ./cmd/go-monzo-wallet-cli
+package main
+
+import (
+	"os"
+
+	"go-monzo-wallet/internal/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AlertLog records the alerts that were raised, e.g. in the cache of a
+// Wallet, so that they are not raised again.
+type AlertLog interface {
+	// Alerted reports whether the alert with key was recorded.
+	Alerted(key string) (bool, error)
+	// SetAlerted records that the alert with key was raised at the given
+	// time.
+	SetAlerted(key string, at time.Time) error
+}
+
+// alertRecord holds the alerts raised by a monitor, kept in memory and in an
+// optional AlertLog. Callers synchronize access.
+type alertRecord struct {
+	log    AlertLog
+	raised map[string]bool
+}
+
+func newAlertRecord(log AlertLog) *alertRecord {
+	return &alertRecord{log: log, raised: make(map[string]bool)}
+}
+
+// alerted reports whether the alert with key was raised. An alert that
+// cannot be read from the log counts as not raised.
+func (a *alertRecord) alerted(key string) bool {
+	if a.raised[key] {
+		return true
+	}
+	if a.log == nil {
+		return false
+	}
+	alerted, err := a.log.Alerted(key)
+	if err != nil {
+		logrus.Info("reading alerts:", err)
+	}
+	if alerted {
+		a.raised[key] = true
+	}
+	return alerted
+}
+
+// raise reports whether the alert with key was not raised before and records
+// it.
+func (a *alertRecord) raise(key string, now time.Time) bool {
+	if a.alerted(key) {
+		return false
+	}
+	a.raised[key] = true
+	if a.log != nil {
+		if err := a.log.SetAlerted(key, now); err != nil {
+			logrus.Info("recording alert:", err)
+		}
+	}
+	return true
+}
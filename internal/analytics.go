@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"sort"
+	"time"
+)
+
+// SpendTotal is the money spent on a category or at a merchant.
+type SpendTotal struct {
+	// Key is the category, e.g. "groceries", or the merchant name.
+	Key string
+	// Amount is the total spent, as a positive amount.
+	Amount Money
+	Count  int
+}
+
+// MonthTotals is the money that came in and went out of an account in a
+// calendar month.
+type MonthTotals struct {
+	// Month is the start of the month.
+	Month time.Time
+	// Income and Outgoings are positive amounts.
+	Income    Money
+	Outgoings Money
+	// ByCategory is the outgoings by category, largest first.
+	ByCategory []SpendTotal
+}
+
+// BalancePoint is the balance of an account at the end of a day.
+type BalancePoint struct {
+	// Day is the start of the day.
+	Day     time.Time
+	Balance Money
+}
+
+// Analytics are aggregates of the transactions of an account over a period,
+// see Analyze.
+type Analytics struct {
+	From time.Time
+	To   time.Time
+	// Income and Outgoings are the positive totals of the period.
+	Income    Money
+	Outgoings Money
+	// ByCategory and ByMerchant are the outgoings of the period, largest
+	// first.
+	ByCategory []SpendTotal
+	ByMerchant []SpendTotal
+	// Months has an entry for every calendar month the period touches,
+	// oldest first.
+	Months []MonthTotals
+	// Balance has the running balance at the end of every day of the period
+	// up to today, oldest first.
+	Balance []BalancePoint
+}
+
+// Analyze aggregates the transactions of the account created in [from, to).
+// Months and days start at midnight in loc. Declined transactions and those
+// in another currency than the account are left out. The running balance is
+// worked out backwards from the current balance of the account.
+func Analyze(account *Account, from, to time.Time, loc *time.Location) *Analytics {
+	currency := account.Balance.Currency
+	a := &Analytics{
+		From:      from,
+		To:        to,
+		Income:    NewMoney(0, currency),
+		Outgoings: NewMoney(0, currency),
+	}
+
+	var transactions []*Transaction
+	for _, transaction := range account.Transactions {
+		if transaction.Status == TransactionDeclined || transaction.Amount.Currency != currency {
+			continue
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	for month := startOfMonth(from.In(loc)); month.Before(to); month = month.AddDate(0, 1, 0) {
+		a.Months = append(a.Months, MonthTotals{
+			Month:     month,
+			Income:    NewMoney(0, currency),
+			Outgoings: NewMoney(0, currency),
+		})
+	}
+
+	byCategory := make(map[string]*SpendTotal)
+	byMerchant := make(map[string]*SpendTotal)
+	monthCategories := make([]map[string]*SpendTotal, len(a.Months))
+	for i := range monthCategories {
+		monthCategories[i] = make(map[string]*SpendTotal)
+	}
+
+	for _, transaction := range transactions {
+		if transaction.Created.Before(from) || !transaction.Created.Before(to) {
+			continue
+		}
+		month := monthIndex(a.Months, transaction.Created.In(loc))
+
+		amount := transaction.Amount.Amount
+		if amount > 0 {
+			a.Income.Amount += amount
+			if month >= 0 {
+				a.Months[month].Income.Amount += amount
+			}
+			continue
+		}
+
+		a.Outgoings.Amount -= amount
+		addSpend(byCategory, transaction.Category, -amount, currency)
+		addSpend(byMerchant, transaction.Title(), -amount, currency)
+		if month >= 0 {
+			a.Months[month].Outgoings.Amount -= amount
+			addSpend(monthCategories[month], transaction.Category, -amount, currency)
+		}
+	}
+
+	a.ByCategory = sortedSpend(byCategory)
+	a.ByMerchant = sortedSpend(byMerchant)
+	for i := range a.Months {
+		a.Months[i].ByCategory = sortedSpend(monthCategories[i])
+	}
+	a.Balance = runningBalance(account.Balance, transactions, from, to, loc)
+	return a
+}
+
+// runningBalance returns the balance at the end of every day in [from, to)
+// up to today, starting from the current balance and undoing the
+// transactions created since.
+func runningBalance(balance Money, transactions []*Transaction, from, to time.Time, loc *time.Location) []BalancePoint {
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+	first := startOfDay(from.In(loc))
+	if !first.Before(to) {
+		return nil
+	}
+
+	newestFirst := append([]*Transaction(nil), transactions...)
+	sort.SliceStable(newestFirst, func(i, j int) bool {
+		return newestFirst[i].Created.After(newestFirst[j].Created)
+	})
+
+	var points []BalancePoint
+	running := balance.Amount
+	next := 0
+	for day := startOfDay(to.Add(-time.Nanosecond).In(loc)); !day.Before(first); day = day.AddDate(0, 0, -1) {
+		end := day.AddDate(0, 0, 1)
+		for next < len(newestFirst) && !newestFirst[next].Created.Before(end) {
+			running -= newestFirst[next].Amount.Amount
+			next++
+		}
+		points = append(points, BalancePoint{Day: day, Balance: NewMoney(running, balance.Currency)})
+	}
+
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+	return points
+}
+
+// monthIndex returns the index of the month t falls in, or -1.
+func monthIndex(months []MonthTotals, t time.Time) int {
+	for i := len(months) - 1; i >= 0; i-- {
+		if !t.Before(months[i].Month) {
+			return i
+		}
+	}
+	return -1
+}
+
+func addSpend(totals map[string]*SpendTotal, key string, amount int64, currency string) {
+	total, ok := totals[key]
+	if !ok {
+		total = &SpendTotal{Key: key, Amount: NewMoney(0, currency)}
+		totals[key] = total
+	}
+	total.Amount.Amount += amount
+	total.Count++
+}
+
+// sortedSpend returns the totals, largest first and by key for equal
+// amounts.
+func sortedSpend(totals map[string]*SpendTotal) []SpendTotal {
+	sorted := make([]SpendTotal, 0, len(totals))
+	for _, total := range totals {
+		sorted = append(sorted, *total)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Amount.Amount != sorted[j].Amount.Amount {
+			return sorted[i].Amount.Amount > sorted[j].Amount.Amount
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+	return sorted
+}
+
+func startOfMonth(t time.Time) time.Time {
+	y, m, _ := t.Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// MonthsBack returns the period of the last n calendar months including the
+// current one, in loc.
+func MonthsBack(n int, now time.Time, loc *time.Location) (from, to time.Time) {
+	current := startOfMonth(now.In(loc))
+	return current.AddDate(0, 1-n, 0), current.AddDate(0, 1, 0)
+}
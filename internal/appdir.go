@@ -0,0 +1,25 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// appDirName is the name of the directory the app keeps its files in, below
+// the OS user config directory.
+const appDirName = "go-monzo-wallet"
+
+// AppDataDir returns the directory the app keeps its files in, creating it if
+// it does not exist yet.
+func AppDataDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(configDir, appDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
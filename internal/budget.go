@@ -0,0 +1,180 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultBudgetWarning is the percentage of a budget's limit at which a
+// warning is raised if the config does not set one.
+const DefaultBudgetWarning = 80
+
+// Budget is a monthly spending limit for a category, kept in the config.
+type Budget struct {
+	// Category is the transaction category, e.g. "groceries".
+	Category string
+	// Limit is the most that should be spent on the category in a
+	// calendar month.
+	Limit Money
+}
+
+// BudgetLevel is how close the spending in a budget's category is to its
+// limit.
+type BudgetLevel int
+
+const (
+	BudgetOK       BudgetLevel = iota
+	BudgetWarning              // at or over the warning threshold
+	BudgetExceeded             // over the limit
+)
+
+// BudgetStatus is the spending against a budget in a month.
+type BudgetStatus struct {
+	Budget Budget
+	// Spent is the positive total spent on the category so far this month.
+	Spent Money
+	// Projected is what will have been spent by the end of the month if
+	// spending continues at the average daily rate so far.
+	Projected Money
+	// Progress is Spent as a fraction of the limit. It exceeds 1 once the
+	// budget is exceeded.
+	Progress float64
+	Level    BudgetLevel
+}
+
+// ProjectedOverspend returns by how much the budget is projected to be
+// exceeded at the end of the month, or zero.
+func (s *BudgetStatus) ProjectedOverspend() Money {
+	over := s.Projected.Amount - s.Budget.Limit.Amount
+	if over < 0 {
+		over = 0
+	}
+	return NewMoney(over, s.Budget.Limit.Currency)
+}
+
+// EvaluateBudgets returns the status of every budget in the calendar month
+// of now in loc, from the outgoing transactions of accounts in the currency of
+// the budget. Declined transactions do not count. A budget reaches
+// BudgetWarning once warningPercent of its limit is spent.
+func EvaluateBudgets(budgets []Budget, accounts Accounts, now time.Time, loc *time.Location, warningPercent int) []BudgetStatus {
+	now = now.In(loc)
+	monthStart := startOfMonth(now)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+
+	// Spending so far is projected over the whole month by the share of the
+	// month that has passed, counting today as a full day.
+	daysInMonth := monthEnd.AddDate(0, 0, -1).Day()
+	daysPassed := now.Day()
+
+	statuses := make([]BudgetStatus, len(budgets))
+	for i, budget := range budgets {
+		var spent int64
+		for _, account := range accounts {
+			for _, transaction := range account.Transactions {
+				if transaction.Status == TransactionDeclined ||
+					transaction.Category != budget.Category ||
+					transaction.Amount.Currency != budget.Limit.Currency ||
+					transaction.Amount.Amount >= 0 ||
+					transaction.Created.Before(monthStart) ||
+					!transaction.Created.Before(monthEnd) {
+					continue
+				}
+				spent -= transaction.Amount.Amount
+			}
+		}
+
+		status := BudgetStatus{
+			Budget:    budget,
+			Spent:     NewMoney(spent, budget.Limit.Currency),
+			Projected: NewMoney(spent*int64(daysInMonth)/int64(daysPassed), budget.Limit.Currency),
+		}
+		if budget.Limit.Amount > 0 {
+			status.Progress = float64(spent) / float64(budget.Limit.Amount)
+		}
+		switch {
+		case spent > budget.Limit.Amount:
+			status.Level = BudgetExceeded
+		case status.Progress*100 >= float64(warningPercent):
+			status.Level = BudgetWarning
+		}
+		statuses[i] = status
+	}
+	return statuses
+}
+
+// BudgetMonitor evaluates the budgets of a wallet and reports those that
+// reach a level that was not reported for their category in the same month,
+// so that each warning is raised once, also across runs of the app if the
+// alerts are recorded in an AlertLog.
+type BudgetMonitor struct {
+	mtx            sync.Mutex
+	budgets        []Budget
+	warningPercent int
+	alerts         *alertRecord
+}
+
+// NewBudgetMonitor returns a monitor of budgets, see SetBudgets, that
+// records the alerts it reports in log. Without a log, they are only kept in
+// memory.
+func NewBudgetMonitor(budgets []Budget, warningPercent int, log AlertLog) *BudgetMonitor {
+	m := &BudgetMonitor{alerts: newAlertRecord(log)}
+	m.SetBudgets(budgets, warningPercent)
+	return m
+}
+
+// SetBudgets replaces the budgets and the percentage of their limits at
+// which they reach BudgetWarning.
+func (m *BudgetMonitor) SetBudgets(budgets []Budget, warningPercent int) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.budgets = append([]Budget(nil), budgets...)
+	m.warningPercent = warningPercent
+}
+
+// Budgets returns the budgets and the warning percentage.
+func (m *BudgetMonitor) Budgets() ([]Budget, int) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return append([]Budget(nil), m.budgets...), m.warningPercent
+}
+
+// Status evaluates the budgets against accounts, see EvaluateBudgets.
+func (m *BudgetMonitor) Status(accounts Accounts, now time.Time) []BudgetStatus {
+	budgets, warningPercent := m.Budgets()
+	return EvaluateBudgets(budgets, accounts, now, time.Local, warningPercent)
+}
+
+// Check evaluates the budgets against accounts and returns the statuses at
+// a level that was not returned for their category this month, nor a higher
+// one. Levels start over every month.
+func (m *BudgetMonitor) Check(accounts Accounts, now time.Time) []BudgetStatus {
+	statuses := m.Status(accounts, now)
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	month := startOfMonth(now.In(time.Local))
+	var crossed []BudgetStatus
+next:
+	for _, status := range statuses {
+		if status.Level == BudgetOK {
+			continue
+		}
+		for level := status.Level + 1; level <= BudgetExceeded; level++ {
+			if m.alerts.alerted(budgetAlertKey(month, status.Budget.Category, level)) {
+				continue next
+			}
+		}
+		if m.alerts.raise(budgetAlertKey(month, status.Budget.Category, status.Level), now) {
+			crossed = append(crossed, status)
+		}
+	}
+	return crossed
+}
+
+// budgetAlertKey identifies the alert of a budget's category reaching level
+// in the month, see AlertLog.
+func budgetAlertKey(month time.Time, category string, level BudgetLevel) string {
+	return fmt.Sprintf("budget/%s/%s/%d", month.Format("2006-01"), category, level)
+}
@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// bst is a fixed zone rather than Europe/London, so that the month
+// boundaries do not depend on the tz database of the machine running the
+// tests.
+var bst = time.FixedZone("BST", 60*60)
+
+// spending returns a settled payment of amount minor units at t.
+func spending(t time.Time, amount int64, category, currency string) *Transaction {
+	return &Transaction{
+		ID:       "tx_" + t.Format("20060102150405"),
+		Created:  t,
+		Status:   TransactionSettled,
+		Amount:   NewMoney(-amount, currency),
+		Category: category,
+	}
+}
+
+func TestEvaluateBudgets(t *testing.T) {
+	groceries := Budget{Category: "groceries", Limit: NewMoney(10000, "GBP")}
+	aug := func(day, hour int) time.Time { return time.Date(2022, time.August, day, hour, 0, 0, 0, bst) }
+	feb := func(day, hour int) time.Time { return time.Date(2022, time.February, day, hour, 0, 0, 0, bst) }
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		transactions  []*Transaction
+		wantSpent     int64
+		wantProjected int64
+		wantLevel     BudgetLevel
+	}{
+		{
+			name: "under budget",
+			now:  aug(31, 12),
+			transactions: []*Transaction{
+				spending(aug(3, 9), 2000, "groceries", "GBP"),
+				spending(aug(20, 9), 3000, "groceries", "GBP"),
+			},
+			wantSpent:     5000,
+			wantProjected: 5000,
+			wantLevel:     BudgetOK,
+		},
+		{
+			name: "exactly at the warning threshold",
+			now:  aug(31, 12),
+			transactions: []*Transaction{
+				spending(aug(3, 9), 8000, "groceries", "GBP"),
+			},
+			wantSpent:     8000,
+			wantProjected: 8000,
+			wantLevel:     BudgetWarning,
+		},
+		{
+			name: "just under the warning threshold",
+			now:  aug(31, 12),
+			transactions: []*Transaction{
+				spending(aug(3, 9), 7999, "groceries", "GBP"),
+			},
+			wantSpent:     7999,
+			wantProjected: 7999,
+			wantLevel:     BudgetOK,
+		},
+		{
+			name: "exactly at the limit",
+			now:  aug(31, 12),
+			transactions: []*Transaction{
+				spending(aug(3, 9), 10000, "groceries", "GBP"),
+			},
+			wantSpent:     10000,
+			wantProjected: 10000,
+			wantLevel:     BudgetWarning,
+		},
+		{
+			name: "over budget",
+			now:  aug(31, 12),
+			transactions: []*Transaction{
+				spending(aug(3, 9), 6000, "groceries", "GBP"),
+				spending(aug(30, 9), 6000, "groceries", "GBP"),
+			},
+			wantSpent:     12000,
+			wantProjected: 12000,
+			wantLevel:     BudgetExceeded,
+		},
+		{
+			name: "projected overspend partway through a 31 day month",
+			now:  aug(10, 12),
+			transactions: []*Transaction{
+				spending(aug(2, 9), 4000, "groceries", "GBP"),
+			},
+			wantSpent:     4000,
+			wantProjected: 12400, // 40.00 in 10 of 31 days
+			wantLevel:     BudgetOK,
+		},
+		{
+			name: "february",
+			now:  feb(14, 12),
+			transactions: []*Transaction{
+				spending(time.Date(2022, time.January, 31, 23, 59, 0, 0, bst), 9000, "groceries", "GBP"),
+				spending(feb(1, 0), 3000, "groceries", "GBP"),
+				spending(feb(14, 11), 3000, "groceries", "GBP"),
+			},
+			wantSpent:     6000,
+			wantProjected: 12000, // 60.00 in 14 of 28 days
+			wantLevel:     BudgetOK,
+		},
+		{
+			name: "month boundary in the local time zone",
+			now:  aug(31, 12),
+			transactions: []*Transaction{
+				// 31 July 23:30 in UTC, but 1 August in the zone.
+				spending(time.Date(2022, time.July, 31, 23, 30, 0, 0, time.UTC), 2000, "groceries", "GBP"),
+				// 31 August 23:30 in the zone.
+				spending(time.Date(2022, time.August, 31, 22, 30, 0, 0, time.UTC), 1000, "groceries", "GBP"),
+				// 1 September 00:30 in the zone.
+				spending(time.Date(2022, time.August, 31, 23, 30, 0, 0, time.UTC), 5000, "groceries", "GBP"),
+			},
+			wantSpent:     3000,
+			wantProjected: 3000,
+			wantLevel:     BudgetOK,
+		},
+		{
+			name: "other currencies, categories and declined or incoming transactions",
+			now:  aug(31, 12),
+			transactions: []*Transaction{
+				spending(aug(3, 9), 1500, "groceries", "GBP"),
+				spending(aug(4, 9), 20000, "groceries", "EUR"),
+				spending(aug(5, 9), 20000, "eating_out", "GBP"),
+				{
+					ID:            "tx_declined",
+					Created:       aug(6, 9),
+					Status:        TransactionDeclined,
+					DeclineReason: "INSUFFICIENT_FUNDS",
+					Amount:        NewMoney(-20000, "GBP"),
+					Category:      "groceries",
+				},
+				{
+					ID:       "tx_refund",
+					Created:  aug(7, 9),
+					Status:   TransactionSettled,
+					Amount:   NewMoney(20000, "GBP"),
+					Category: "groceries",
+				},
+			},
+			wantSpent:     1500,
+			wantProjected: 1500,
+			wantLevel:     BudgetOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accounts := Accounts{{ID: "acc_1", Transactions: test.transactions}}
+			statuses := EvaluateBudgets([]Budget{groceries}, accounts, test.now, bst, DefaultBudgetWarning)
+			if len(statuses) != 1 {
+				t.Fatalf("got %d statuses, want 1", len(statuses))
+			}
+			status := statuses[0]
+
+			if want := NewMoney(test.wantSpent, "GBP"); status.Spent != want {
+				t.Errorf("spent %v, want %v", status.Spent, want)
+			}
+			if want := NewMoney(test.wantProjected, "GBP"); status.Projected != want {
+				t.Errorf("projected %v, want %v", status.Projected, want)
+			}
+			if status.Level != test.wantLevel {
+				t.Errorf("level %d, want %d", status.Level, test.wantLevel)
+			}
+			overspend := test.wantProjected - groceries.Limit.Amount
+			if overspend < 0 {
+				overspend = 0
+			}
+			if want := NewMoney(overspend, "GBP"); status.ProjectedOverspend() != want {
+				t.Errorf("projected overspend %v, want %v", status.ProjectedOverspend(), want)
+			}
+		})
+	}
+}
+
+func TestBudgetMonitorAlertsOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	budgets := []Budget{{Category: "groceries", Limit: NewMoney(10000, "GBP")}}
+	aug := func(day int) time.Time { return time.Date(2022, time.August, day, 12, 0, 0, 0, time.UTC) }
+	sep := time.Date(2022, time.September, 20, 12, 0, 0, 0, time.UTC)
+
+	// check runs a new monitor, as on a launch of the app, and returns the
+	// levels it reported.
+	check := func(now time.Time, transactions ...*Transaction) []BudgetLevel {
+		t.Helper()
+		store, err := OpenStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+
+		accounts := Accounts{{ID: "acc_1", Transactions: transactions}}
+		var levels []BudgetLevel
+		for _, status := range NewBudgetMonitor(budgets, DefaultBudgetWarning, store).Check(accounts, now) {
+			levels = append(levels, status.Level)
+		}
+		return levels
+	}
+
+	warning := spending(aug(3), 8500, "groceries", "GBP")
+	exceeded := spending(aug(10), 2000, "groceries", "GBP")
+	refund := &Transaction{ID: "tx_refund", Created: aug(12), Status: TransactionSettled,
+		Amount: NewMoney(2000, "GBP"), Category: "groceries"}
+	tests := []struct {
+		name         string
+		now          time.Time
+		transactions []*Transaction
+		want         []BudgetLevel
+	}{
+		{"warning", aug(5), []*Transaction{warning}, []BudgetLevel{BudgetWarning}},
+		{"warning after a restart", aug(6), []*Transaction{warning}, nil},
+		{"exceeded", aug(15), []*Transaction{warning, exceeded}, []BudgetLevel{BudgetExceeded}},
+		{"warning after exceeded", aug(16), []*Transaction{warning, exceeded, refund}, nil},
+		{"next month", sep, []*Transaction{spending(sep, 9000, "groceries", "GBP")}, []BudgetLevel{BudgetWarning}},
+	}
+
+	for _, test := range tests {
+		if got := check(test.now, test.transactions...); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got levels %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	monitor := NewBudgetMonitor(budgets, DefaultBudgetWarning, nil)
+	accounts := Accounts{{ID: "acc_1", Transactions: []*Transaction{warning}}}
+	if statuses := monitor.Check(accounts, aug(5)); len(statuses) != 1 {
+		t.Errorf("monitor without a log reported %d budgets, want 1", len(statuses))
+	}
+	if statuses := monitor.Check(accounts, aug(5)); len(statuses) != 0 {
+		t.Errorf("monitor without a log reported %d budgets again, want none", len(statuses))
+	}
+}
@@ -0,0 +1,301 @@
+// Package cli implements the subcommands that run the wallet without a
+// window, so it can be scripted on CI machines and used over SSH:
+//
+//	go-monzo-wallet login [--profile NAME] [--types TYPES]
+//	go-monzo-wallet profiles [--format json|table]
+//	go-monzo-wallet accounts [--format json|table]
+//	go-monzo-wallet balance [--account ID] [--format json|table]
+//	go-monzo-wallet transactions [--account ID] [--since DATE] [--format json|csv|table]
+//	go-monzo-wallet pots [--account ID] [--format json|table]
+//	go-monzo-wallet export [--account ID] [--format csv|ofx|qif] [--from DATE] [--to DATE] [-o FILE]
+//
+// Every command accepts the flags that override the config, e.g. -client-id;
+// environment variables such as MONZO_WALLET_CLIENT_ID do the same.
+//
+// The commands share the config, profiles, token stores and offline cache of
+// the app. Unless --offline is given they sync the cache first, which needs
+// the startup password. It is read from the MONZO_WALLET_PASSPHRASE environment
+// variable if set, otherwise from the terminal or standard input.
+//
+// The app runs the commands given as its first argument, and so does the
+// go-monzo-wallet-cli command, which builds without cgo as it does not link
+// the window.
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-monzo-wallet/internal"
+	"golang.org/x/term"
+)
+
+// PassphraseEnv is the environment variable the startup password is read
+// from, for scripts that cannot type it.
+const PassphraseEnv = "MONZO_WALLET_PASSPHRASE"
+
+// command is a subcommand. run receives the arguments after the command
+// name.
+type command struct {
+	name    string
+	summary string
+	run     func(e *env, args []string) error
+}
+
+var commands = []command{
+	{"login", "log in to Monzo in a browser and store the token", runLogin},
+	{"profiles", "list the profiles", runProfiles},
+	{"accounts", "list the accounts", runAccounts},
+	{"balance", "show the balance of the accounts", runBalance},
+	{"transactions", "list the transactions of an account", runTransactions},
+	{"pots", "list the pots of the accounts", runPots},
+	{"export", "write a CSV, OFX or QIF statement of an account", runExport},
+}
+
+// IsCommand reports whether name is a subcommand or a request for help, in
+// which case the app should call Run instead of opening its window.
+func IsCommand(name string) bool {
+	switch name {
+	case "help", "-h", "-help", "--help":
+		return true
+	}
+	_, ok := findCommand(name)
+	return ok
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// Run runs the subcommand named by args[0] with the remaining arguments and
+// returns the exit code of the process.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) == 0 {
+		e.usage()
+		return 2
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		if IsCommand(args[0]) {
+			e.usage()
+			return 0
+		}
+		fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		e.usage()
+		return 2
+	}
+
+	if err := cmd.run(e, args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		fmt.Fprintf(stderr, "%s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+// env is what the commands read from and write to.
+type env struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+
+	// pass is the startup password once it was read.
+	pass *string
+}
+
+func (e *env) usage() {
+	fmt.Fprintf(e.stderr, "Usage: %s <command> [flags]\n\nCommands:\n", filepath.Base(os.Args[0]))
+	for _, cmd := range commands {
+		fmt.Fprintf(e.stderr, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(e.stderr, "\nRun %s <command> -h for the flags of a command.\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(e.stderr, "Without a command, go-monzo-wallet opens the app window.\n")
+}
+
+// flagSet returns a flag set for the command that reports errors instead of
+// exiting. It has the flags that override the config.
+func (e *env) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	internal.AddConfigFlags(fs)
+	return fs
+}
+
+// loadConfig loads the config, explaining how to create one if there is
+// none.
+func loadConfig() (*internal.Config, error) {
+	cfg, err := internal.LoadConfig()
+	if errors.Is(err, internal.ErrNoConfig) {
+		path, _ := internal.ConfigPath()
+		return nil, fmt.Errorf("no config found at %s: run the app once to set it up, "+
+			"or set %s_CLIENT_ID and %[2]s_CLIENT_SECRET", path, internal.ConfigEnvPrefix)
+	}
+	return cfg, err
+}
+
+// passphrase returns the startup password from PassphraseEnv, or asks for it
+// the first time.
+func (e *env) passphrase() (string, error) {
+	if e.pass != nil {
+		return *e.pass, nil
+	}
+	passphrase, err := e.readPassphrase()
+	if err != nil {
+		return "", err
+	}
+	e.pass = &passphrase
+	return passphrase, nil
+}
+
+func (e *env) readPassphrase() (string, error) {
+	if passphrase, ok := os.LookupEnv(PassphraseEnv); ok {
+		return passphrase, nil
+	}
+
+	if f, ok := e.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(e.stderr, "Startup password: ")
+		passphrase, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(e.stderr)
+		return string(passphrase), err
+	}
+
+	line, err := bufio.NewReader(e.stdin).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("reading the startup password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// openTokenStore unlocks the token store of the profile. The store of the
+// default profile is always unlocked first, since it is the one that checks
+// the startup password; the others would accept any password.
+func (e *env) openTokenStore(profile *internal.Profile) (*internal.TokenStore, error) {
+	dir, err := internal.AppDataDir()
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := e.passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := internal.OpenTokenStore(filepath.Join(dir, internal.TokenFileName), passphrase)
+	if err != nil || profile.ID == internal.DefaultProfileID {
+		return store, err
+	}
+	return internal.OpenProfileTokenStore(dir, profile, passphrase)
+}
+
+// openWallet returns a wallet with the accounts of the offline cache. Unless
+// offline is true, the cache is synced first. The caller must call Shutdown
+// on the wallet.
+func (e *env) openWallet(offline bool) (*internal.Wallet, error) {
+	dir, err := internal.AppDataDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *internal.Config
+	if !offline {
+		if cfg, err = loadConfig(); err != nil {
+			return nil, err
+		}
+	}
+
+	profiles, err := internal.LoadProfiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet := internal.NewWallet(nil)
+	wallet.SetProfiles(profiles)
+	if err := wallet.OpenCache(filepath.Join(dir, internal.StoreFileName)); err != nil {
+		return nil, fmt.Errorf("opening the offline cache, is the app running? %w", err)
+	}
+
+	if offline {
+		if !wallet.LoadedWallet() {
+			wallet.Shutdown()
+			return nil, errors.New("the offline cache is empty, run without --offline first")
+		}
+		return wallet, nil
+	}
+
+	if err := e.sync(wallet, dir, cfg); err != nil {
+		wallet.Shutdown()
+		return nil, err
+	}
+	return wallet, nil
+}
+
+func (e *env) sync(wallet *internal.Wallet, dir string, cfg *internal.Config) error {
+	store, err := e.openTokenStore(internal.DefaultProfile())
+	if err != nil {
+		return err
+	}
+	token, err := store.Load()
+	if errors.Is(err, internal.ErrNoToken) {
+		return errors.New("not logged in, run the login command first")
+	}
+	if err != nil {
+		return err
+	}
+
+	wallet.UseProvider(cfg.Provider())
+	wallet.Authenticate(store.TokenSource(context.Background(), cfg.OAuth2(), token))
+
+	passphrase, err := e.passphrase()
+	if err != nil {
+		return err
+	}
+	missing, err := wallet.UnlockProfiles(context.Background(), dir, cfg.OAuth2(), passphrase)
+	if err != nil {
+		return err
+	}
+	for _, profile := range missing {
+		fmt.Fprintf(e.stderr, "Skipping profile %s, which is not logged in. Run the login command with --profile %q.\n",
+			profile.Name, profile.Name)
+	}
+
+	err = wallet.Syncer().Sync(context.Background())
+	var profileErrs internal.ProfileErrors
+	if !errors.As(err, &profileErrs) {
+		return err
+	}
+	// The profiles that did load are used, as long as there are any.
+	if len(profileErrs) < len(wallet.Profiles())-len(missing) {
+		for _, profileErr := range profileErrs {
+			fmt.Fprintf(e.stderr, "Skipping profile %s: %v\n", profileErr.Profile.Name, profileError(profileErr))
+		}
+		return nil
+	}
+	return profileError(profileErrs[0])
+}
+
+// profileError explains how to fix an expired login of a profile, or returns
+// any other error unchanged.
+func profileError(err *internal.ProfileError) error {
+	if !errors.Is(err, internal.ErrTokenRevoked) {
+		return err
+	}
+	if err.Profile.ID == internal.DefaultProfileID {
+		return errors.New("the login has expired, run the login command again")
+	}
+	return fmt.Errorf("the login of profile %s has expired, run the login command again with --profile %q",
+		err.Profile.Name, err.Profile.Name)
+}
@@ -0,0 +1,428 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/internal/export"
+)
+
+// dateFormat is the format of date flags. Full RFC 3339 times are accepted
+// as well.
+const dateFormat = "2006-01-02"
+
+func runLogin(e *env, args []string) error {
+	fs := e.flagSet("login")
+	profileName := fs.String("profile", "", "name or ID of the profile to log in to, created if it does not exist (defaults to the first login)")
+	typesFlag := fs.String("types", internal.AccountTypeRetail, "comma separated account types of a new profile: "+
+		strings.Join(internal.AccountTypes, ", "))
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	dir, err := internal.AppDataDir()
+	if err != nil {
+		return err
+	}
+	profiles, err := internal.LoadProfiles(dir)
+	if err != nil {
+		return err
+	}
+
+	profile := profiles[0]
+	created := false
+	if *profileName != "" {
+		if profile = findProfile(profiles, *profileName); profile == nil {
+			accountTypes, err := parseAccountTypes(*typesFlag)
+			if err != nil {
+				return err
+			}
+			if profile, err = internal.NewProfile(*profileName, accountTypes); err != nil {
+				return err
+			}
+			created = true
+		}
+	}
+
+	store, err := e.openTokenStore(profile)
+	if err != nil {
+		return err
+	}
+
+	wallet := internal.NewWallet(cfg.Provider())
+	token, err := wallet.ConnectWith(context.Background(), cfg.OAuth2(), func(authURL string) error {
+		fmt.Fprintf(e.stderr, "Open this URL in a browser to log in:\n\n  %s\n\n", authURL)
+		fmt.Fprintf(e.stderr, "If the browser runs on another machine, it cannot reach the final redirect to 127.0.0.1.\n"+
+			"Copy the URL it fails to load and open it on this machine instead, e.g. with curl.\n")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := store.Save(token); err != nil {
+		return err
+	}
+	if created {
+		if err := internal.SaveProfiles(dir, append(profiles, profile)); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(e.stderr, "Logged in. Approve the access request in the Monzo app before syncing.")
+	return nil
+}
+
+func runProfiles(e *env, args []string) error {
+	fs := e.flagSet("profiles")
+	format := fs.String("format", "table", "output format: json or table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, formatJSON, formatTable); err != nil {
+		return err
+	}
+
+	dir, err := internal.AppDataDir()
+	if err != nil {
+		return err
+	}
+	profiles, err := internal.LoadProfiles(dir)
+	if err != nil {
+		return err
+	}
+
+	if *format == formatJSON {
+		return writeJSON(e.stdout, profiles)
+	}
+
+	t := newTable(e.stdout, "ID", "NAME", "ACCOUNT TYPES")
+	for _, profile := range profiles {
+		t.row(profile.ID, profile.Name, strings.Join(profile.AccountTypes, ","))
+	}
+	return t.flush()
+}
+
+func runAccounts(e *env, args []string) error {
+	fs := e.flagSet("accounts")
+	offline := fs.Bool("offline", false, "use the offline cache without syncing")
+	format := fs.String("format", "table", "output format: json or table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, formatJSON, formatTable); err != nil {
+		return err
+	}
+
+	wallet, err := e.openWallet(*offline)
+	if err != nil {
+		return err
+	}
+	defer wallet.Shutdown()
+
+	accounts := wallet.AccountsList()
+	if *format == formatJSON {
+		views := make([]accountView, 0, len(accounts))
+		for _, account := range accounts {
+			views = append(views, newAccountView(account))
+		}
+		return writeJSON(e.stdout, views)
+	}
+
+	t := newTable(e.stdout, "ID", "PROFILE", "TYPE", "ACCOUNT NUMBER", "SORT CODE", "BALANCE")
+	for _, account := range accounts {
+		profile := ""
+		if p := wallet.Profile(account.ProfileID); p != nil {
+			profile = p.Name
+		}
+		t.row(account.ID, profile, account.Type, account.AccountNumber, account.SortCode, account.Balance.String())
+	}
+	return t.flush()
+}
+
+func runBalance(e *env, args []string) error {
+	fs := e.flagSet("balance")
+	offline := fs.Bool("offline", false, "use the offline cache without syncing")
+	accountID := fs.String("account", "", "ID or account number of the account (defaults to all)")
+	format := fs.String("format", "table", "output format: json or table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, formatJSON, formatTable); err != nil {
+		return err
+	}
+
+	wallet, err := e.openWallet(*offline)
+	if err != nil {
+		return err
+	}
+	defer wallet.Shutdown()
+
+	accounts, err := selectAccounts(wallet.AccountsList(), *accountID)
+	if err != nil {
+		return err
+	}
+
+	if *format == formatJSON {
+		views := make([]balanceView, 0, len(accounts))
+		for _, account := range accounts {
+			views = append(views, balanceView{
+				AccountID:  account.ID,
+				Balance:    newMoneyView(account.Balance),
+				SpendToday: newMoneyView(account.SpendToday),
+			})
+		}
+		return writeJSON(e.stdout, views)
+	}
+
+	t := newTable(e.stdout, "ACCOUNT NUMBER", "BALANCE", "SPENT TODAY")
+	for _, account := range accounts {
+		t.row(account.AccountNumber, account.Balance.String(), account.SpendToday.String())
+	}
+	return t.flush()
+}
+
+func runTransactions(e *env, args []string) error {
+	fs := e.flagSet("transactions")
+	offline := fs.Bool("offline", false, "use the offline cache without syncing")
+	accountID := fs.String("account", "", "ID or account number of the account (required if there are several)")
+	since := fs.String("since", "", "only list transactions created since this date, as YYYY-MM-DD or RFC 3339")
+	format := fs.String("format", "table", "output format: json, csv or table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, formatJSON, formatCSV, formatTable); err != nil {
+		return err
+	}
+	from, err := parseDate(*since)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+
+	wallet, err := e.openWallet(*offline)
+	if err != nil {
+		return err
+	}
+	defer wallet.Shutdown()
+
+	account, err := selectAccount(wallet.AccountsList(), *accountID)
+	if err != nil {
+		return err
+	}
+	transactions := account.Query(internal.TransactionQuery{From: from, Sort: internal.OldestFirst}).Transactions
+
+	switch *format {
+	case formatJSON:
+		views := make([]transactionView, 0, len(transactions))
+		for _, transaction := range transactions {
+			views = append(views, newTransactionView(transaction))
+		}
+		return writeJSON(e.stdout, views)
+	case formatCSV:
+		return export.WriteCSV(e.stdout, transactions, export.Options{
+			Columns: []export.Column{export.ColumnID, export.ColumnDate, export.ColumnTime,
+				export.ColumnTitle, export.ColumnCategory, export.ColumnAmount,
+				export.ColumnCurrency, export.ColumnStatus, export.ColumnNotes},
+		})
+	}
+
+	t := newTable(e.stdout, "DATE", "TITLE", "CATEGORY", "AMOUNT", "STATUS")
+	for _, transaction := range transactions {
+		t.row(transaction.Created.Local().Format("2006-01-02 15:04"), transaction.Title(),
+			transaction.Category, transaction.Amount.String(), string(transaction.Status))
+	}
+	return t.flush()
+}
+
+func runPots(e *env, args []string) error {
+	fs := e.flagSet("pots")
+	offline := fs.Bool("offline", false, "use the offline cache without syncing")
+	accountID := fs.String("account", "", "ID or account number of the account (defaults to all)")
+	format := fs.String("format", "table", "output format: json or table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := checkFormat(*format, formatJSON, formatTable); err != nil {
+		return err
+	}
+
+	wallet, err := e.openWallet(*offline)
+	if err != nil {
+		return err
+	}
+	defer wallet.Shutdown()
+
+	accounts, err := selectAccounts(wallet.AccountsList(), *accountID)
+	if err != nil {
+		return err
+	}
+
+	if *format == formatJSON {
+		var views []potView
+		for _, account := range accounts {
+			for _, pot := range account.Pots {
+				views = append(views, newPotView(account, pot))
+			}
+		}
+		if views == nil {
+			views = []potView{}
+		}
+		return writeJSON(e.stdout, views)
+	}
+
+	t := newTable(e.stdout, "ACCOUNT NUMBER", "ID", "NAME", "BALANCE", "GOAL", "LOCKED")
+	for _, account := range accounts {
+		for _, pot := range account.Pots {
+			goal := ""
+			if !pot.Goal.IsZero() {
+				goal = pot.Goal.String()
+			}
+			locked := ""
+			if pot.Locked {
+				locked = "yes"
+			}
+			t.row(account.AccountNumber, pot.ID, pot.Name, pot.Balance.String(), goal, locked)
+		}
+	}
+	return t.flush()
+}
+
+func runExport(e *env, args []string) error {
+	fs := e.flagSet("export")
+	offline := fs.Bool("offline", false, "use the offline cache without syncing")
+	accountID := fs.String("account", "", "ID or account number of the account (required if there are several)")
+	formatFlag := fs.String("format", "csv", "statement format: csv, ofx or qif")
+	columnsFlag := fs.String("columns", "", "comma separated CSV columns (defaults to date,title,category,amount,currency,status,notes)")
+	fromFlag := fs.String("from", "", "first day to export, as YYYY-MM-DD")
+	toFlag := fs.String("to", "", "last day to export, as YYYY-MM-DD")
+	outPath := fs.String("o", "", "output file (defaults to standard output)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	format, err := export.ParseFormat(*formatFlag)
+	if err != nil {
+		return err
+	}
+	var opts export.Options
+	if *columnsFlag != "" {
+		if opts.Columns, err = export.ParseColumns(*columnsFlag); err != nil {
+			return err
+		}
+	}
+	if opts.From, err = parseDate(*fromFlag); err != nil {
+		return fmt.Errorf("invalid --from: %w", err)
+	}
+	if opts.To, err = parseDate(*toFlag); err != nil {
+		return fmt.Errorf("invalid --to: %w", err)
+	}
+	if !opts.To.IsZero() {
+		// The last day is included.
+		opts.To = opts.To.AddDate(0, 0, 1)
+	}
+
+	wallet, err := e.openWallet(*offline)
+	if err != nil {
+		return err
+	}
+	defer wallet.Shutdown()
+
+	account, err := selectAccount(wallet.AccountsList(), *accountID)
+	if err != nil {
+		return err
+	}
+
+	if *outPath == "" {
+		return export.Write(e.stdout, format, account, opts)
+	}
+	f, err := os.Create(*outPath)
+	if err != nil {
+		return err
+	}
+	if err := export.Write(f, format, account, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// parseDate parses a date flag in local time. An empty value returns the
+// zero time.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(dateFormat, s, time.Local)
+}
+
+// findProfile returns the profile with the given name or ID, or nil.
+func findProfile(profiles []*internal.Profile, name string) *internal.Profile {
+	for _, profile := range profiles {
+		if profile.ID == name || profile.Name == name {
+			return profile
+		}
+	}
+	return nil
+}
+
+// parseAccountTypes parses a comma separated list of account types.
+func parseAccountTypes(s string) ([]string, error) {
+	var accountTypes []string
+	for _, accountType := range strings.Split(s, ",") {
+		accountType = strings.TrimSpace(accountType)
+		if accountType == "" {
+			continue
+		}
+		known := false
+		for _, t := range internal.AccountTypes {
+			known = known || t == accountType
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown account type %q", accountType)
+		}
+		accountTypes = append(accountTypes, accountType)
+	}
+	return accountTypes, nil
+}
+
+// selectAccount returns the account with the given ID or account number, or
+// the only account if id is empty.
+func selectAccount(accounts internal.Accounts, id string) (*internal.Account, error) {
+	if id == "" {
+		if len(accounts) != 1 {
+			return nil, fmt.Errorf("%d accounts found, choose one with --account", len(accounts))
+		}
+		return accounts[0], nil
+	}
+
+	selected, err := selectAccounts(accounts, id)
+	if err != nil {
+		return nil, err
+	}
+	return selected[0], nil
+}
+
+// selectAccounts returns the account with the given ID or account number,
+// or all accounts if id is empty.
+func selectAccounts(accounts internal.Accounts, id string) (internal.Accounts, error) {
+	if id == "" {
+		return accounts, nil
+	}
+	for _, account := range accounts {
+		if account.ID == id || account.AccountNumber == id {
+			return internal.Accounts{account}, nil
+		}
+	}
+	return nil, errors.New("account " + id + " not found")
+}
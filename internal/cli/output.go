@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go-monzo-wallet/internal"
+)
+
+// Output formats.
+const (
+	formatJSON  = "json"
+	formatCSV   = "csv"
+	formatTable = "table"
+)
+
+func checkFormat(format string, supported ...string) error {
+	for _, f := range supported {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported format %q, use %s", format, strings.Join(supported, ", "))
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// table writes aligned columns for people to read.
+type table struct {
+	tw *tabwriter.Writer
+}
+
+func newTable(w io.Writer, header ...string) *table {
+	t := &table{tw: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)}
+	t.row(header...)
+	return t
+}
+
+func (t *table) row(cells ...string) {
+	fmt.Fprintln(t.tw, strings.Join(cells, "\t"))
+}
+
+func (t *table) flush() error {
+	return t.tw.Flush()
+}
+
+// The JSON output is decoupled from the internal types so that it stays
+// stable for scripts. Amounts are in minor units, like in the Monzo API.
+
+type moneyView struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func newMoneyView(m internal.Money) moneyView {
+	return moneyView{Amount: m.Amount, Currency: m.Currency}
+}
+
+type accountView struct {
+	ID            string    `json:"id"`
+	ProfileID     string    `json:"profile_id"`
+	Type          string    `json:"type"`
+	AccountNumber string    `json:"account_number"`
+	SortCode      string    `json:"sort_code"`
+	Created       string    `json:"created"`
+	Balance       moneyView `json:"balance"`
+}
+
+func newAccountView(account *internal.Account) accountView {
+	return accountView{
+		ID:            account.ID,
+		ProfileID:     account.ProfileID,
+		Type:          account.Type,
+		AccountNumber: account.AccountNumber,
+		SortCode:      account.SortCode,
+		Created:       account.Created,
+		Balance:       newMoneyView(account.Balance),
+	}
+}
+
+type balanceView struct {
+	AccountID  string    `json:"account_id"`
+	Balance    moneyView `json:"balance"`
+	SpendToday moneyView `json:"spend_today"`
+}
+
+type transactionView struct {
+	ID          string     `json:"id"`
+	Created     time.Time  `json:"created"`
+	Settled     *time.Time `json:"settled,omitempty"`
+	Status      string     `json:"status"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Category    string     `json:"category"`
+	Notes       string     `json:"notes,omitempty"`
+	Amount      moneyView  `json:"amount"`
+	LocalAmount moneyView  `json:"local_amount"`
+}
+
+func newTransactionView(transaction *internal.Transaction) transactionView {
+	view := transactionView{
+		ID:          transaction.ID,
+		Created:     transaction.Created,
+		Status:      string(transaction.Status),
+		Title:       transaction.Title(),
+		Description: transaction.Description,
+		Category:    transaction.Category,
+		Notes:       transaction.Notes,
+		Amount:      newMoneyView(transaction.Amount),
+		LocalAmount: newMoneyView(transaction.LocalAmount),
+	}
+	if !transaction.Settled.IsZero() {
+		settled := transaction.Settled
+		view.Settled = &settled
+	}
+	return view
+}
+
+type potView struct {
+	ID        string     `json:"id"`
+	AccountID string     `json:"account_id"`
+	Name      string     `json:"name"`
+	Balance   moneyView  `json:"balance"`
+	Goal      *moneyView `json:"goal,omitempty"`
+	Locked    bool       `json:"locked"`
+}
+
+func newPotView(account *internal.Account, pot *internal.Pot) potView {
+	view := potView{
+		ID:        pot.ID,
+		AccountID: account.ID,
+		Name:      pot.Name,
+		Balance:   newMoneyView(pot.Balance),
+		Locked:    pot.Locked,
+	}
+	if !pot.Goal.IsZero() {
+		goal := newMoneyView(pot.Goal)
+		view.Goal = &goal
+	}
+	return view
+}
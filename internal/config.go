@@ -0,0 +1,626 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+)
+
+// ConfigFileName is the name of the config file in AppDataDir.
+const ConfigFileName = "config.json"
+
+// ConfigVersion is the version of the config schema written by UpdateConfig.
+// LoadConfig upgrades files of older versions.
+const ConfigVersion = 1
+
+// ConfigEnvPrefix prefixes the environment variables that override config
+// fields, e.g. MONZO_WALLET_CLIENT_ID. MONZO_WALLET_CONFIG overrides the path
+// of the config file.
+const ConfigEnvPrefix = "MONZO_WALLET"
+
+// Endpoints of the Monzo OAuth server.
+const (
+	MonzoAuthURL  = "https://auth.monzo.com/"
+	MonzoTokenURL = "https://api.monzo.com/oauth2/token"
+)
+
+// Themes of the app.
+const (
+	ThemeLight = "light"
+	ThemeDark  = "dark"
+)
+
+// MinSyncInterval is the shortest sync interval accepted, to stay well below
+// the rate limits of the API.
+const MinSyncInterval = time.Minute
+
+// ErrNoConfig is returned by LoadConfig if there is no config file and the
+// environment does not provide a client either, e.g. on the first run.
+var ErrNoConfig = errors.New("no config found")
+
+// Config keys, also the names of the JSON fields and, upper cased, of the
+// environment variables after ConfigEnvPrefix.
+const (
+	ConfigKeyVersion       = "version"
+	ConfigKeyClientID      = "client_id"
+	ConfigKeyClientSecret  = "client_secret"
+	ConfigKeyRedirectURL   = "redirect_url"
+	ConfigKeyAuthURL       = "auth_url"
+	ConfigKeyTokenURL      = "token_url"
+	ConfigKeyAPIURL        = "api_url"
+	ConfigKeySyncInterval  = "sync_interval"
+	ConfigKeyTheme         = "theme"
+	ConfigKeyLanguage      = "language"
+	ConfigKeyCurrency      = "currency"
+	ConfigKeyWebhookURL    = "webhook_url"
+	ConfigKeyWebhookAddr   = "webhook_addr"
+	ConfigKeyBudgets       = "budgets"
+	ConfigKeyBudgetWarning = "budget_warning"
+	ConfigKeyAutoLock      = "auto_lock"
+	ConfigKeyHideBalances  = "hide_balances"
+)
+
+// Config is the configuration of the app, shared by the UI and the command
+// line.
+type Config struct {
+	Version int `mapstructure:"version"`
+	// ClientID and ClientSecret identify the OAuth client registered with
+	// Monzo.
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+	// RedirectURL is the redirect URL of the client. Only its path is used;
+	// the browser is redirected to a loopback address chosen at login.
+	RedirectURL string `mapstructure:"redirect_url"`
+	// AuthURL, TokenURL and APIURL are the Monzo endpoints, or those of a
+	// local fake server.
+	AuthURL  string `mapstructure:"auth_url"`
+	TokenURL string `mapstructure:"token_url"`
+	APIURL   string `mapstructure:"api_url"`
+	// SyncInterval is how often the wallet is synced in the background.
+	SyncInterval time.Duration `mapstructure:"sync_interval"`
+	// Theme is ThemeLight or ThemeDark.
+	Theme string `mapstructure:"theme"`
+	// Language is the BCP 47 tag of the language of the UI.
+	Language string `mapstructure:"language"`
+	// Currency is the ISO 4217 code of the default currency of budgets, used
+	// for the limits of budgets that do not set one.
+	Currency string `mapstructure:"currency"`
+	// WebhookURL is the public URL at which Monzo reaches the webhook
+	// receiver, e.g. that of a tunnel to WebhookAddr. Webhooks are disabled
+	// if it is empty.
+	WebhookURL string `mapstructure:"webhook_url"`
+	// WebhookAddr is the local address the webhook receiver listens on.
+	WebhookAddr string `mapstructure:"webhook_addr"`
+	// Budgets are the monthly spending limits by category.
+	Budgets []BudgetConfig `mapstructure:"budgets"`
+	// BudgetWarning is the percentage of a budget's limit at which a
+	// warning is raised.
+	BudgetWarning int `mapstructure:"budget_warning"`
+	// AutoLock is how long the app may be idle before it locks, zero to
+	// never lock.
+	AutoLock time.Duration `mapstructure:"auto_lock"`
+	// HideBalances turns on privacy mode, which hides all balances and
+	// other amounts of money.
+	HideBalances bool `mapstructure:"hide_balances"`
+}
+
+// BudgetConfig is a Budget as kept in the config file.
+type BudgetConfig struct {
+	Category string `mapstructure:"category" json:"category"`
+	// Limit is a decimal amount in major units, e.g. "250.00".
+	Limit string `mapstructure:"limit" json:"limit"`
+	// Currency is the ISO 4217 code of the limit. The currency of the
+	// config is used if it is empty.
+	Currency string `mapstructure:"currency" json:"currency,omitempty"`
+}
+
+// configFile is the JSON format of the config file.
+type configFile struct {
+	Version       int            `json:"version"`
+	ClientID      string         `json:"client_id"`
+	ClientSecret  string         `json:"client_secret"`
+	RedirectURL   string         `json:"redirect_url"`
+	AuthURL       string         `json:"auth_url"`
+	TokenURL      string         `json:"token_url"`
+	APIURL        string         `json:"api_url"`
+	SyncInterval  string         `json:"sync_interval"`
+	Theme         string         `json:"theme"`
+	Language      string         `json:"language"`
+	Currency      string         `json:"currency"`
+	WebhookURL    string         `json:"webhook_url,omitempty"`
+	WebhookAddr   string         `json:"webhook_addr"`
+	Budgets       []BudgetConfig `json:"budgets"`
+	BudgetWarning int            `json:"budget_warning"`
+	AutoLock      string         `json:"auto_lock"`
+	HideBalances  bool           `json:"hide_balances"`
+}
+
+// DefaultConfig returns the config with the default value of every field and
+// no client.
+func DefaultConfig() *Config {
+	return &Config{
+		Version:       ConfigVersion,
+		RedirectURL:   "http://127.0.0.1" + defaultCallbackPath,
+		AuthURL:       MonzoAuthURL,
+		TokenURL:      MonzoTokenURL,
+		APIURL:        MonzoBaseURL,
+		SyncInterval:  DefaultSyncInterval,
+		Theme:         ThemeLight,
+		Language:      language.English.String(),
+		Currency:      "GBP",
+		WebhookAddr:   DefaultWebhookAddr,
+		Budgets:       []BudgetConfig{},
+		BudgetWarning: DefaultBudgetWarning,
+	}
+}
+
+// configFlags is the flag set registered by AddConfigFlags.
+var configFlags *flag.FlagSet
+
+// AddConfigFlags registers flags on fs that override the config, e.g.
+// --client-id for client_id, and --config for the path of the config file.
+// LoadConfig applies the flags that are set once fs is parsed.
+func AddConfigFlags(fs *flag.FlagSet) {
+	fs.String("config", "", "path of the config file (defaults to "+ConfigFileName+" in the app data directory)")
+	fs.String(flagName(ConfigKeyClientID), "", "OAuth client ID")
+	fs.String(flagName(ConfigKeyClientSecret), "", "OAuth client secret")
+	fs.String(flagName(ConfigKeyRedirectURL), "", "OAuth redirect URL")
+	fs.String(flagName(ConfigKeyAPIURL), "", "base URL of the API")
+	fs.String(flagName(ConfigKeySyncInterval), "", "how often to sync in the background, e.g. 5m")
+	fs.String(flagName(ConfigKeyTheme), "", "theme: light or dark")
+	fs.String(flagName(ConfigKeyLanguage), "", "language of the UI, e.g. en")
+	fs.String(flagName(ConfigKeyCurrency), "", "default currency of budget limits, e.g. GBP")
+	fs.String(flagName(ConfigKeyWebhookURL), "", "public URL of the webhook receiver, enables webhooks")
+	fs.String(flagName(ConfigKeyWebhookAddr), "", "local address of the webhook receiver")
+	fs.String(flagName(ConfigKeyBudgetWarning), "", "percentage of a budget at which to warn, e.g. 80")
+	fs.String(flagName(ConfigKeyAutoLock), "", "how long the app may be idle before it locks, e.g. 5m, or 0 to never lock")
+	fs.String(flagName(ConfigKeyHideBalances), "", "hide all balances and amounts (privacy mode): true or false")
+	configFlags = fs
+}
+
+// flagName returns the name of the flag that overrides a config key.
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// ConfigPath returns the path of the config file: the --config flag if set,
+// else the MONZO_WALLET_CONFIG environment variable, else ConfigFileName in
+// AppDataDir.
+func ConfigPath() (string, error) {
+	if configFlags != nil {
+		if f := configFlags.Lookup("config"); f != nil && f.Value.String() != "" {
+			return f.Value.String(), nil
+		}
+	}
+	if path := os.Getenv(ConfigEnvPrefix + "_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	dir, err := AppDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ConfigFileName), nil
+}
+
+// LoadConfig reads the config from ConfigPath. Fields missing in the file
+// have their default value, and are overridden by the environment variables
+// and flags that are set, in that order. Files of older schema versions are
+// upgraded in place. A config.json in the working directory, where earlier
+// versions of the app read it from, is copied to ConfigPath on first use.
+//
+// LoadConfig returns ErrNoConfig if there is no config at all. If the config
+// is invalid, it is returned together with a ValidationError.
+func LoadConfig() (*Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := decodeConfig(path, data, true)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil && cfg.ClientID == "" {
+		return nil, ErrNoConfig
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// LoadConfigFile reads the config file at ConfigPath like LoadConfig, but
+// without the overrides of the environment and flags, e.g. to edit it. The
+// config is not validated, and is the default one if there is no file.
+func LoadConfigFile() (*Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConfig(path, data, false)
+}
+
+// UpdateConfig changes the config file at ConfigPath with update and returns
+// the config in effect afterwards, as LoadConfig would. Only the file is
+// changed: values set by the environment or flags are not written to it.
+// The change is not saved if it makes the config in effect invalid.
+func UpdateConfig(update func(cfg *Config)) (*Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := decodeConfig(path, data, false)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := decodeConfig(path, data, true)
+	if err != nil {
+		return nil, err
+	}
+	update(file)
+	update(cfg)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if data, err = file.marshal(); err != nil {
+		return nil, err
+	}
+	if err := writeConfigFile(path, data); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// decodeConfig returns the config of the file data read from path, nil if
+// there is none, over the defaults. The environment variables and flags that
+// are set override it if overrides is true.
+func decodeConfig(path string, data []byte, overrides bool) (*Config, error) {
+	v := viper.New()
+	v.SetConfigType("json")
+	defaults := DefaultConfig()
+	v.SetDefault(ConfigKeyVersion, defaults.Version)
+	v.SetDefault(ConfigKeyClientID, defaults.ClientID)
+	v.SetDefault(ConfigKeyClientSecret, defaults.ClientSecret)
+	v.SetDefault(ConfigKeyRedirectURL, defaults.RedirectURL)
+	v.SetDefault(ConfigKeyAuthURL, defaults.AuthURL)
+	v.SetDefault(ConfigKeyTokenURL, defaults.TokenURL)
+	v.SetDefault(ConfigKeyAPIURL, defaults.APIURL)
+	v.SetDefault(ConfigKeySyncInterval, defaults.SyncInterval)
+	v.SetDefault(ConfigKeyTheme, defaults.Theme)
+	v.SetDefault(ConfigKeyLanguage, defaults.Language)
+	v.SetDefault(ConfigKeyCurrency, defaults.Currency)
+	v.SetDefault(ConfigKeyWebhookURL, defaults.WebhookURL)
+	v.SetDefault(ConfigKeyWebhookAddr, defaults.WebhookAddr)
+	v.SetDefault(ConfigKeyBudgetWarning, defaults.BudgetWarning)
+	v.SetDefault(ConfigKeyAutoLock, defaults.AutoLock)
+	v.SetDefault(ConfigKeyHideBalances, defaults.HideBalances)
+
+	if overrides {
+		v.SetEnvPrefix(ConfigEnvPrefix)
+		v.AutomaticEnv()
+	}
+
+	if data != nil {
+		if err := v.ReadConfig(strings.NewReader(string(data))); err != nil {
+			return nil, fmt.Errorf("reading %s: %v", path, err)
+		}
+	}
+	if overrides && configFlags != nil {
+		configFlags.Visit(func(f *flag.Flag) {
+			if f.Name != "config" {
+				v.Set(strings.ReplaceAll(f.Name, "-", "_"), f.Value.String())
+			}
+		})
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+// readConfigFile returns the config file at path, upgraded to ConfigVersion,
+// or nil if there is none.
+func readConfigFile(path string) ([]byte, error) {
+	legacy := false
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = os.ReadFile(ConfigFileName)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		legacy = err == nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	upgraded, changed, err := upgradeConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	if changed || legacy {
+		if legacy {
+			logrus.Infof("copying %s in the working directory to %s", ConfigFileName, path)
+		}
+		if err := writeConfigFile(path, upgraded); err != nil {
+			return nil, err
+		}
+	}
+	return upgraded, nil
+}
+
+// upgradeConfig converts a config file to ConfigVersion and reports whether it
+// had to be changed.
+func upgradeConfig(data []byte) ([]byte, bool, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false, err
+	}
+
+	var version int
+	if raw, ok := fields[ConfigKeyVersion]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, false, fmt.Errorf("invalid version: %v", err)
+		}
+	}
+	switch {
+	case version == ConfigVersion:
+		return data, false, nil
+	case version > ConfigVersion:
+		return nil, false, fmt.Errorf("config version %d is newer than this app, which supports version %d", version, ConfigVersion)
+	}
+
+	// Version 0 was a serialized oauth2.Config plus the API URL.
+	var v0 struct {
+		ClientID     string
+		ClientSecret string
+		RedirectURL  string
+		Endpoint     struct{ AuthURL, TokenURL string }
+		APIURL       string `json:"apiurl"`
+	}
+	if err := json.Unmarshal(data, &v0); err != nil {
+		return nil, false, err
+	}
+	cfg := DefaultConfig()
+	cfg.ClientID = v0.ClientID
+	cfg.ClientSecret = v0.ClientSecret
+	if v0.RedirectURL != "" {
+		cfg.RedirectURL = v0.RedirectURL
+	}
+	if v0.Endpoint.AuthURL != "" {
+		cfg.AuthURL = v0.Endpoint.AuthURL
+	}
+	if v0.Endpoint.TokenURL != "" {
+		cfg.TokenURL = v0.Endpoint.TokenURL
+	}
+	if v0.APIURL != "" {
+		cfg.APIURL = v0.APIURL
+	}
+
+	upgraded, err := cfg.marshal()
+	return upgraded, true, err
+}
+
+func (c *Config) marshal() ([]byte, error) {
+	// Written as [] rather than null without budgets.
+	budgets := c.Budgets
+	if budgets == nil {
+		budgets = []BudgetConfig{}
+	}
+	return json.MarshalIndent(configFile{
+		Version:       ConfigVersion,
+		ClientID:      c.ClientID,
+		ClientSecret:  c.ClientSecret,
+		RedirectURL:   c.RedirectURL,
+		AuthURL:       c.AuthURL,
+		TokenURL:      c.TokenURL,
+		APIURL:        c.APIURL,
+		SyncInterval:  c.SyncInterval.String(),
+		Theme:         c.Theme,
+		Language:      c.Language,
+		Currency:      c.Currency,
+		WebhookURL:    c.WebhookURL,
+		WebhookAddr:   c.WebhookAddr,
+		Budgets:       budgets,
+		BudgetWarning: c.BudgetWarning,
+		AutoLock:      c.AutoLock.String(),
+		HideBalances:  c.HideBalances,
+	}, "", "  ")
+}
+
+// writeConfigFile replaces the config file at path. It holds the client
+// secret, so only the user may read it.
+func writeConfigFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// FieldError reports an invalid config field.
+type FieldError struct {
+	// Field is the config key, e.g. "client_id".
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists the invalid fields of a config.
+type ValidationError []*FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, fieldErr := range e {
+		msgs[i] = fieldErr.Error()
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+// Field returns the error of the field with the given key, or nil.
+func (e ValidationError) Field(key string) *FieldError {
+	for _, fieldErr := range e {
+		if fieldErr.Field == key {
+			return fieldErr
+		}
+	}
+	return nil
+}
+
+// Validate checks every field of the config and returns a ValidationError
+// listing those that are invalid.
+func (c *Config) Validate() error {
+	var errs ValidationError
+	invalid := func(field, format string, a ...interface{}) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if c.ClientID == "" {
+		invalid(ConfigKeyClientID, "missing, register an OAuth client at https://developers.monzo.com")
+	}
+	if c.ClientSecret == "" {
+		invalid(ConfigKeyClientSecret, "missing, register the OAuth client as confidential")
+	}
+	if err := checkURL(c.RedirectURL); err != nil {
+		invalid(ConfigKeyRedirectURL, "%v", err)
+	}
+	if err := checkURL(c.AuthURL); err != nil {
+		invalid(ConfigKeyAuthURL, "%v", err)
+	}
+	if err := checkURL(c.TokenURL); err != nil {
+		invalid(ConfigKeyTokenURL, "%v", err)
+	}
+	if err := checkURL(c.APIURL); err != nil {
+		invalid(ConfigKeyAPIURL, "%v", err)
+	}
+	if c.SyncInterval < MinSyncInterval {
+		invalid(ConfigKeySyncInterval, "must be at least %v", MinSyncInterval)
+	}
+	if c.Theme != ThemeLight && c.Theme != ThemeDark {
+		invalid(ConfigKeyTheme, "must be %q or %q", ThemeLight, ThemeDark)
+	}
+	if _, err := language.Parse(c.Language); err != nil {
+		invalid(ConfigKeyLanguage, "%q is not a language tag", c.Language)
+	}
+	if _, err := currency.ParseISO(c.Currency); err != nil {
+		invalid(ConfigKeyCurrency, "%q is not an ISO 4217 currency code", c.Currency)
+	}
+	if c.WebhookURL != "" {
+		if err := checkURL(c.WebhookURL); err != nil {
+			invalid(ConfigKeyWebhookURL, "%v", err)
+		}
+	}
+	if _, _, err := net.SplitHostPort(c.WebhookAddr); err != nil {
+		invalid(ConfigKeyWebhookAddr, "must be a host and port, e.g. %s", DefaultWebhookAddr)
+	}
+	if _, err := c.BudgetList(); err != nil {
+		invalid(ConfigKeyBudgets, "%v", err)
+	}
+	if c.BudgetWarning < 1 || c.BudgetWarning > 100 {
+		invalid(ConfigKeyBudgetWarning, "must be a percentage between 1 and 100")
+	}
+	if c.AutoLock < 0 {
+		invalid(ConfigKeyAutoLock, "must not be negative, use 0 to never lock")
+	}
+
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// checkURL returns an error if s is not an absolute HTTP(S) URL.
+func checkURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return errors.New("not a valid URL")
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// BudgetList returns the budgets of the config. Limits without a currency
+// are in the currency of the config.
+func (c *Config) BudgetList() ([]Budget, error) {
+	budgets := make([]Budget, 0, len(c.Budgets))
+	seen := make(map[string]bool)
+	for _, b := range c.Budgets {
+		if b.Category == "" {
+			return nil, errors.New("budget without a category")
+		}
+		if seen[b.Category] {
+			return nil, fmt.Errorf("more than one budget for %s", b.Category)
+		}
+		seen[b.Category] = true
+
+		currency := b.Currency
+		if currency == "" {
+			currency = c.Currency
+		}
+		limit, err := ParseMoney(b.Limit, currency)
+		if err != nil {
+			return nil, fmt.Errorf("budget for %s: %v", b.Category, err)
+		}
+		if limit.Amount <= 0 {
+			return nil, fmt.Errorf("budget for %s: limit must be positive", b.Category)
+		}
+		budgets = append(budgets, Budget{Category: b.Category, Limit: limit})
+	}
+	return budgets, nil
+}
+
+// OAuth2 returns the config of the OAuth client.
+func (c *Config) OAuth2() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     c.ClientID,
+		ClientSecret: c.ClientSecret,
+		RedirectURL:  c.RedirectURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  c.AuthURL,
+			TokenURL: c.TokenURL,
+		},
+	}
+}
+
+// Provider returns the ProviderFunc for the configured API.
+func (c *Config) Provider() ProviderFunc {
+	if c.APIURL == "" {
+		return MonzoProvider(MonzoBaseURL)
+	}
+	return MonzoProvider(c.APIURL)
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdateConfigKeepsOverridesOutOfTheFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	t.Setenv(ConfigEnvPrefix+"_CONFIG", path)
+	t.Setenv(ConfigEnvPrefix+"_CLIENT_SECRET", "env_secret")
+	t.Setenv(ConfigEnvPrefix+"_API_URL", "http://127.0.0.1:8080")
+
+	file := DefaultConfig()
+	file.ClientID = "file_client"
+	data, err := file.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := UpdateConfig(func(cfg *Config) { cfg.Theme = ThemeDark })
+	if err != nil {
+		t.Fatalf("UpdateConfig: %v", err)
+	}
+	if cfg.Theme != ThemeDark || cfg.ClientSecret != "env_secret" {
+		t.Errorf("UpdateConfig returned theme %q and secret %q, want the new theme and the secret of the environment",
+			cfg.Theme, cfg.ClientSecret)
+	}
+
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, override := range []string{"env_secret", "127.0.0.1:8080"} {
+		if strings.Contains(string(data), override) {
+			t.Errorf("the config file contains %q set by the environment:\n%s", override, data)
+		}
+	}
+
+	saved, err := LoadConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Theme != ThemeDark || saved.ClientID != "file_client" || saved.APIURL != MonzoBaseURL {
+		t.Errorf("the config file has theme %q, client %q and API URL %q, want %q, %q and %q",
+			saved.Theme, saved.ClientID, saved.APIURL, ThemeDark, "file_client", MonzoBaseURL)
+	}
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DeepLinkScheme is the URL scheme of links that open the app at a page,
+// e.g. monzo-wallet://account/acc_1/transactions?since=2022-06-01.
+const DeepLinkScheme = "monzo-wallet"
+
+// IsDeepLink reports whether s is a monzo-wallet:// link.
+func IsDeepLink(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), DeepLinkScheme+":")
+}
+
+// DeepLinkPath returns the path and query a monzo-wallet:// link leads to,
+// e.g. /account/acc_1/transactions?since=2022-06-01 for the link above.
+func DeepLinkPath(link string) (string, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(u.Scheme, DeepLinkScheme) {
+		return "", fmt.Errorf("%q is not a %s:// link", link, DeepLinkScheme)
+	}
+
+	// The first segment of the path is the host of the URL, unless the link
+	// has three slashes or none.
+	path := u.Opaque
+	if path == "" {
+		path = u.Host + u.EscapedPath()
+	}
+	path = "/" + strings.TrimLeft(path, "/")
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path, nil
+}
+
+// TakeDeepLink returns the monzo-wallet:// link that the last login
+// redirected back with, see ConnectWith, and forgets it. It returns an empty
+// string if there is none.
+func (w *Wallet) TakeDeepLink() string {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	link := w.deepLink
+	w.deepLink = ""
+	return link
+}
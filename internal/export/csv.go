@@ -0,0 +1,156 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go-monzo-wallet/internal"
+)
+
+// Column is a column of CSV exports.
+type Column string
+
+const (
+	ColumnID            Column = "id"
+	ColumnDate          Column = "date"    // creation date
+	ColumnTime          Column = "time"    // creation time of day
+	ColumnSettled       Column = "settled" // settlement date, empty while pending
+	ColumnTitle         Column = "title"   // merchant or counterparty name, see Transaction.Title
+	ColumnDescription   Column = "description"
+	ColumnCategory      Column = "category"
+	ColumnAmount        Column = "amount" // in the account currency, negative for payments
+	ColumnCurrency      Column = "currency"
+	ColumnLocalAmount   Column = "local_amount" // in the currency the payment was made in
+	ColumnLocalCurrency Column = "local_currency"
+	ColumnStatus        Column = "status"
+	ColumnNotes         Column = "notes"
+	ColumnCounterparty  Column = "counterparty" // sort code and account number of transfers
+)
+
+// Columns are all columns, in their default order.
+var Columns = []Column{ColumnID, ColumnDate, ColumnTime, ColumnSettled, ColumnTitle,
+	ColumnDescription, ColumnCategory, ColumnAmount, ColumnCurrency, ColumnLocalAmount,
+	ColumnLocalCurrency, ColumnStatus, ColumnNotes, ColumnCounterparty}
+
+// DefaultColumns are the columns of CSV exports unless others are requested.
+var DefaultColumns = []Column{ColumnDate, ColumnTitle, ColumnCategory, ColumnAmount,
+	ColumnCurrency, ColumnStatus, ColumnNotes}
+
+// ParseColumns parses a comma separated list of column names, e.g.
+// "date,title,amount".
+func ParseColumns(s string) ([]Column, error) {
+	var columns []Column
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		column, err := parseColumn(name)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns in %q", s)
+	}
+	return columns, nil
+}
+
+func parseColumn(name string) (Column, error) {
+	for _, column := range Columns {
+		if strings.EqualFold(name, string(column)) {
+			return column, nil
+		}
+	}
+	return "", fmt.Errorf("unknown column %q", name)
+}
+
+// WriteCSV writes the transactions as CSV with a header row, using the
+// columns of opts. Amounts are written as plain decimals in major units,
+// e.g. "-12.30", so that spreadsheets read them as numbers.
+func WriteCSV(w io.Writer, transactions []*internal.Transaction, opts Options) error {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = DefaultColumns
+	}
+
+	cw := csv.NewWriter(w)
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = string(column)
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+
+	location := opts.location()
+	for _, transaction := range transactions {
+		for i, column := range columns {
+			value, err := csvValue(transaction, column, location)
+			if err != nil {
+				return err
+			}
+			record[i] = value
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(transaction *internal.Transaction, column Column, location *time.Location) (string, error) {
+	switch column {
+	case ColumnID:
+		return transaction.ID, nil
+	case ColumnDate:
+		return transaction.Created.In(location).Format("2006-01-02"), nil
+	case ColumnTime:
+		return transaction.Created.In(location).Format("15:04:05"), nil
+	case ColumnSettled:
+		if transaction.Settled.IsZero() {
+			return "", nil
+		}
+		return transaction.Settled.In(location).Format("2006-01-02"), nil
+	case ColumnTitle:
+		return csvText(transaction.Title()), nil
+	case ColumnDescription:
+		return csvText(transaction.Description), nil
+	case ColumnCategory:
+		return transaction.Category, nil
+	case ColumnAmount:
+		return transaction.Amount.Decimal(), nil
+	case ColumnCurrency:
+		return transaction.Amount.Currency, nil
+	case ColumnLocalAmount:
+		return transaction.LocalAmount.Decimal(), nil
+	case ColumnLocalCurrency:
+		return transaction.LocalAmount.Currency, nil
+	case ColumnStatus:
+		return string(transaction.Status), nil
+	case ColumnNotes:
+		return csvText(transaction.Notes), nil
+	case ColumnCounterparty:
+		c := transaction.Counterparty
+		if c == nil || c.AccountNumber == "" {
+			return "", nil
+		}
+		return c.SortCode + " " + c.AccountNumber, nil
+	}
+	return "", fmt.Errorf("unknown column %q", column)
+}
+
+// csvText keeps spreadsheets from evaluating free text, such as notes, that
+// looks like a formula.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,131 @@
+// Package export writes the transactions of an account as statements for
+// spreadsheets and accounting software: CSV with configurable columns,
+// OFX 2.x and QIF.
+//
+// Declined transactions are never exported. CSV files include pending
+// transactions along with their status, while OFX and QIF statements only
+// contain settled ones, since importers identify transactions by ID and
+// would not pick up a later change of a pending amount.
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go-monzo-wallet/internal"
+)
+
+// Format is a statement file format.
+type Format string
+
+const (
+	CSV Format = "csv"
+	OFX Format = "ofx"
+	QIF Format = "qif"
+)
+
+// Formats are the supported formats.
+var Formats = []Format{CSV, OFX, QIF}
+
+// ParseFormat returns the format named s, ignoring case.
+func ParseFormat(s string) (Format, error) {
+	for _, format := range Formats {
+		if strings.EqualFold(s, string(format)) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown export format %q", s)
+}
+
+// Extension returns the file name extension of the format, including the dot.
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// Options select the transactions to export and how they are written.
+type Options struct {
+	// From and To limit the creation time of the transactions to
+	// [From, To). Zero values leave the range open.
+	From time.Time
+	To   time.Time
+	// Columns are the columns of CSV files, DefaultColumns if empty.
+	Columns []Column
+	// Location is the time zone dates are written in, time.Local if nil.
+	Location *time.Location
+	// Now is the time the statement is generated at, written to OFX files.
+	// time.Now() is used if it is zero.
+	Now time.Time
+}
+
+func (o Options) location() *time.Location {
+	if o.Location == nil {
+		return time.Local
+	}
+	return o.Location
+}
+
+func (o Options) now() time.Time {
+	if o.Now.IsZero() {
+		return time.Now()
+	}
+	return o.Now
+}
+
+// Write writes the transactions of account selected by opts to w in format.
+func Write(w io.Writer, format Format, account *internal.Account, opts Options) error {
+	switch format {
+	case CSV:
+		return WriteCSV(w, Transactions(account, opts, false), opts)
+	case OFX:
+		return WriteOFX(w, account, Transactions(account, opts, true), opts)
+	case QIF:
+		return WriteQIF(w, Transactions(account, opts, true), opts)
+	}
+	return fmt.Errorf("unknown export format %q", format)
+}
+
+// Transactions returns the transactions of the account created in the range
+// of opts, oldest first. Declined transactions are left out, and so are
+// pending ones if settledOnly is true.
+func Transactions(account *internal.Account, opts Options, settledOnly bool) []*internal.Transaction {
+	result := account.Query(internal.TransactionQuery{
+		From: opts.From,
+		To:   opts.To,
+		Sort: internal.OldestFirst,
+	})
+
+	transactions := make([]*internal.Transaction, 0, len(result.Transactions))
+	for _, transaction := range result.Transactions {
+		switch transaction.Status {
+		case internal.TransactionDeclined:
+			continue
+		case internal.TransactionPending:
+			if settledOnly {
+				continue
+			}
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions
+}
+
+// FileName returns a file name for a statement of the account, e.g.
+// "monzo-12345678-2022-08-01-2022-08-31.csv". The dates are left out if the
+// range of opts is open.
+func FileName(account *internal.Account, format Format, opts Options) string {
+	const dateFormat = "2006-01-02"
+	name := "monzo-" + account.AccountNumber
+	if name == "monzo-" {
+		name += account.ID
+	}
+	if !opts.From.IsZero() {
+		name += "-" + opts.From.In(opts.location()).Format(dateFormat)
+	}
+	if !opts.To.IsZero() {
+		// To is exclusive, so the statement ends the day before.
+		name += "-" + opts.To.In(opts.location()).Add(-time.Nanosecond).Format(dateFormat)
+	}
+	return name + format.Extension()
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-monzo-wallet/internal"
+)
+
+// update rewrites the golden files with the current output:
+//
+//	go test ./internal/export -update
+var update = flag.Bool("update", false, "update the golden files")
+
+// london is a fixed zone rather than Europe/London, so that the output does
+// not depend on the tz database of the machine running the tests.
+var london = time.FixedZone("BST", 60*60)
+
+func date(day, hour, min int) time.Time {
+	return time.Date(2022, time.August, day, hour, min, 0, 0, time.UTC)
+}
+
+// testAccount returns an account with a card payment, a payment abroad, an
+// incoming transfer, a pending and a declined payment.
+func testAccount() *internal.Account {
+	return &internal.Account{
+		ID:            "acc_00009237aqC8c5umZmrRdh",
+		AccountNumber: "12345678",
+		SortCode:      "040004",
+		Balance:       internal.NewMoney(123456, "GBP"),
+		Transactions: []*internal.Transaction{
+			{
+				ID:          "tx_0001",
+				Created:     date(1, 8, 15),
+				Settled:     date(2, 6, 0),
+				Status:      internal.TransactionSettled,
+				Amount:      internal.NewMoney(-450, "GBP"),
+				LocalAmount: internal.NewMoney(-450, "GBP"),
+				Description: "PRET A MANGER LONDON GBR",
+				Category:    "eating_out",
+				Notes:       "=SUM(A1:A9)",
+				Merchant:    &internal.Merchant{Name: "Pret A Manger"},
+			},
+			{
+				ID:          "tx_0002",
+				Created:     date(3, 23, 30),
+				Settled:     date(5, 6, 0),
+				Status:      internal.TransactionSettled,
+				Amount:      internal.NewMoney(-8734, "GBP"),
+				LocalAmount: internal.NewMoney(-10250, "EUR"),
+				Description: "HOTEL DU NORD PARIS FRA",
+				Category:    "holidays",
+				Notes:       "Two nights,\nbreakfast included",
+				Merchant:    &internal.Merchant{Name: "Hôtel du Nord"},
+			},
+			{
+				ID:          "tx_0003",
+				Created:     date(10, 9, 0),
+				Settled:     date(10, 9, 0),
+				Status:      internal.TransactionSettled,
+				Amount:      internal.NewMoney(250000, "GBP"),
+				LocalAmount: internal.NewMoney(250000, "GBP"),
+				Description: "SALARY AUG",
+				Category:    "income",
+				Counterparty: &internal.Counterparty{
+					Name:          "ACME Ltd",
+					SortCode:      "200000",
+					AccountNumber: "87654321",
+				},
+			},
+			{
+				ID:          "tx_0004",
+				Created:     date(12, 18, 45),
+				Status:      internal.TransactionPending,
+				Amount:      internal.NewMoney(-1299, "GBP"),
+				LocalAmount: internal.NewMoney(-1299, "GBP"),
+				Description: "AMAZON.CO.UK",
+				Category:    "shopping",
+				Merchant:    &internal.Merchant{Name: "Amazon"},
+			},
+			{
+				ID:            "tx_0005",
+				Created:       date(13, 12, 0),
+				Status:        internal.TransactionDeclined,
+				DeclineReason: "INSUFFICIENT_FUNDS",
+				Amount:        internal.NewMoney(-99999, "GBP"),
+				LocalAmount:   internal.NewMoney(-99999, "GBP"),
+				Description:   "APPLE STORE",
+				Category:      "shopping",
+			},
+		},
+	}
+}
+
+func TestWriteGolden(t *testing.T) {
+	opts := Options{
+		Location: london,
+		Now:      date(15, 12, 0),
+	}
+	custom := opts
+	custom.Columns = []Column{ColumnID, ColumnDate, ColumnTime, ColumnSettled, ColumnTitle,
+		ColumnAmount, ColumnLocalAmount, ColumnLocalCurrency, ColumnCounterparty}
+
+	tests := []struct {
+		golden string
+		format Format
+		opts   Options
+	}{
+		{"default.csv.golden", CSV, opts},
+		{"columns.csv.golden", CSV, custom},
+		{"statement.ofx.golden", OFX, opts},
+		{"statement.qif.golden", QIF, opts},
+	}
+
+	for _, test := range tests {
+		t.Run(test.golden, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, test.format, testAccount(), test.opts); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+
+			path := filepath.Join("testdata", test.golden)
+			if *update {
+				if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.Bytes(); !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
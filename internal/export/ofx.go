@@ -0,0 +1,172 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"go-monzo-wallet/internal"
+)
+
+const (
+	// ofxHeader is the processing instruction that marks an OFX 2.2 file.
+	ofxHeader = `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	// ofxTimeFormat is the OFX datetime format. Times are written in UTC.
+	ofxTimeFormat = "20060102150405.000[0:GMT]"
+	// ofxNameLength is the length limit of the NAME of a transaction.
+	ofxNameLength = 32
+	// ofxMemoLength is the length limit of the MEMO of a transaction.
+	ofxMemoLength = 255
+)
+
+type ofxDocument struct {
+	XMLName   xml.Name     `xml:"OFX"`
+	SignOn    ofxSignOn    `xml:"SIGNONMSGSRSV1>SONRS"`
+	Statement ofxStatement `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxSignOn struct {
+	Status   ofxStatus `xml:"STATUS"`
+	DTServer string    `xml:"DTSERVER"`
+	Language string    `xml:"LANGUAGE"`
+}
+
+type ofxStatement struct {
+	TrnUID        string           `xml:"TRNUID"`
+	Status        ofxStatus        `xml:"STATUS"`
+	CurDef        string           `xml:"STMTRS>CURDEF"`
+	BankID        string           `xml:"STMTRS>BANKACCTFROM>BANKID"`
+	AcctID        string           `xml:"STMTRS>BANKACCTFROM>ACCTID"`
+	AcctType      string           `xml:"STMTRS>BANKACCTFROM>ACCTTYPE"`
+	DTStart       string           `xml:"STMTRS>BANKTRANLIST>DTSTART"`
+	DTEnd         string           `xml:"STMTRS>BANKTRANLIST>DTEND"`
+	Transactions  []ofxTransaction `xml:"STMTRS>BANKTRANLIST>STMTTRN"`
+	LedgerBalance string           `xml:"STMTRS>LEDGERBAL>BALAMT"`
+	LedgerDate    string           `xml:"STMTRS>LEDGERBAL>DTASOF"`
+}
+
+type ofxTransaction struct {
+	TrnType  string `xml:"TRNTYPE"`
+	DTPosted string `xml:"DTPOSTED"`
+	DTUser   string `xml:"DTUSER"`
+	TrnAmt   string `xml:"TRNAMT"`
+	FitID    string `xml:"FITID"`
+	Name     string `xml:"NAME,omitempty"`
+	Memo     string `xml:"MEMO,omitempty"`
+}
+
+// WriteOFX writes the transactions of the account as an OFX 2.2 bank
+// statement. The statement covers the range of opts, or the period from the
+// first transaction until opts.Now if it is open. Its ledger balance is the
+// balance of the account at the end of that period.
+func WriteOFX(w io.Writer, account *internal.Account, transactions []*internal.Transaction, opts Options) error {
+	now := opts.now()
+	start, end := opts.From, opts.To
+	if start.IsZero() {
+		start = now
+		if len(transactions) > 0 {
+			start = transactions[0].Created
+		}
+	}
+	if end.IsZero() || end.After(now) {
+		end = now
+	}
+
+	balance, err := balanceAt(account, end)
+	if err != nil {
+		return err
+	}
+
+	statement := ofxStatement{
+		TrnUID:        "0",
+		Status:        ofxStatus{Code: 0, Severity: "INFO"},
+		CurDef:        account.Balance.Currency,
+		BankID:        account.SortCode,
+		AcctID:        account.AccountNumber,
+		AcctType:      "CHECKING",
+		DTStart:       ofxTime(start),
+		DTEnd:         ofxTime(end),
+		LedgerBalance: balance.Decimal(),
+		LedgerDate:    ofxTime(end),
+	}
+
+	for _, transaction := range transactions {
+		trnType := "DEBIT"
+		if transaction.Amount.Sign() > 0 {
+			trnType = "CREDIT"
+		}
+		posted := transaction.Settled
+		if posted.IsZero() {
+			posted = transaction.Created
+		}
+
+		memo := transaction.Notes
+		if memo == "" && transaction.Description != transaction.Title() {
+			memo = transaction.Description
+		}
+
+		statement.Transactions = append(statement.Transactions, ofxTransaction{
+			TrnType:  trnType,
+			DTPosted: ofxTime(posted),
+			DTUser:   ofxTime(transaction.Created),
+			TrnAmt:   transaction.Amount.Decimal(),
+			FitID:    transaction.ID,
+			Name:     truncate(transaction.Title(), ofxNameLength),
+			Memo:     truncate(memo, ofxMemoLength),
+		})
+	}
+
+	doc := ofxDocument{
+		SignOn: ofxSignOn{
+			Status:   ofxStatus{Code: 0, Severity: "INFO"},
+			DTServer: ofxTime(now),
+			Language: "ENG",
+		},
+		Statement: statement,
+	}
+
+	if _, err := io.WriteString(w, xml.Header+ofxHeader); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+// balanceAt returns the balance of the account at t, by taking the
+// transactions created since then off its current balance.
+func balanceAt(account *internal.Account, t time.Time) (internal.Money, error) {
+	balance := account.Balance
+	for _, transaction := range account.Transactions {
+		if transaction.Status == internal.TransactionDeclined || transaction.Created.Before(t) {
+			continue
+		}
+		var err error
+		if balance, err = balance.Sub(transaction.Amount); err != nil {
+			return internal.Money{}, err
+		}
+	}
+	return balance, nil
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format(ofxTimeFormat)
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
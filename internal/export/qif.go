@@ -0,0 +1,44 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"go-monzo-wallet/internal"
+)
+
+// qifDateFormat is the date format of QIF files. QIF does not define one;
+// day first is what UK banks and accounting software use.
+const qifDateFormat = "02/01/2006"
+
+// WriteQIF writes the transactions as a QIF bank statement. QIF has no notion
+// of currency, so amounts are in the currency of the account.
+func WriteQIF(w io.Writer, transactions []*internal.Transaction, opts Options) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("!Type:Bank\n")
+
+	location := opts.location()
+	for _, transaction := range transactions {
+		qifLine(bw, 'D', transaction.Created.In(location).Format(qifDateFormat))
+		qifLine(bw, 'T', transaction.Amount.Decimal())
+		qifLine(bw, 'P', transaction.Title())
+		if transaction.Notes != "" {
+			qifLine(bw, 'M', transaction.Notes)
+		}
+		if transaction.Category != "" {
+			qifLine(bw, 'L', transaction.Category)
+		}
+		bw.WriteString("^\n")
+	}
+
+	return bw.Flush()
+}
+
+// qifLine writes a field of a QIF record. Fields are line based, so line
+// breaks in value are replaced by spaces.
+func qifLine(w *bufio.Writer, code byte, value string) {
+	w.WriteByte(code)
+	w.WriteString(strings.Join(strings.Fields(value), " "))
+	w.WriteByte('\n')
+}
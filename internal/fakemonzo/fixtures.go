@@ -0,0 +1,61 @@
+package fakemonzo
+
+import (
+	"embed"
+	"encoding/json"
+	"os"
+)
+
+//go:embed fixtures
+var content embed.FS
+
+// Fixtures is the data served by a Server. Records are kept as raw API JSON so
+// that fixtures can be captured from real responses and replayed verbatim.
+type Fixtures struct {
+	// Accounts are the objects returned by GET /accounts.
+	Accounts []json.RawMessage `json:"accounts"`
+	// Balances maps an account ID to the object returned by GET /balance.
+	Balances map[string]json.RawMessage `json:"balances"`
+	// Transactions maps an account ID to the objects returned by
+	// GET /transactions, oldest first.
+	Transactions map[string][]json.RawMessage `json:"transactions"`
+	// Pots maps an account ID to the objects returned by GET /pots.
+	Pots map[string][]json.RawMessage `json:"pots"`
+	// DirectDebits and StandingOrders map an account ID to the objects
+	// returned by GET /direct_debits and GET /standing_orders.
+	DirectDebits   map[string][]json.RawMessage `json:"direct_debits"`
+	StandingOrders map[string][]json.RawMessage `json:"standing_orders"`
+}
+
+// DefaultFixtures returns the fixtures bundled with the package.
+func DefaultFixtures() (*Fixtures, error) {
+	data, err := content.ReadFile("fixtures/default.json")
+	if err != nil {
+		return nil, err
+	}
+	return ParseFixtures(data)
+}
+
+// WebhookTransactionCreated returns the transaction.created webhook event
+// bundled with the package, for the first account of the default fixtures.
+func WebhookTransactionCreated() ([]byte, error) {
+	return content.ReadFile("fixtures/webhook_transaction_created.json")
+}
+
+// LoadFixtures reads fixtures from the JSON file at path.
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFixtures(data)
+}
+
+// ParseFixtures decodes fixtures from JSON.
+func ParseFixtures(data []byte) (*Fixtures, error) {
+	f := &Fixtures{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
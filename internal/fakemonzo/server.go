@@ -0,0 +1,868 @@
+// Package fakemonzo implements a local stand-in for the Monzo API. It serves
+// the OAuth authorize and token endpoints plus the endpoints used by
+// internal.Wallet from JSON fixtures, which pot deposits and withdrawals and
+// mandate cancellations modify in memory. The transactions of pot transfers are posted as
+// transaction.created events to the webhooks registered for the account. It
+// can be told to fail or slow down requests to exercise error handling without
+// network access or real credentials.
+//
+// To run the app against it, start cmd/fakemonzo and point the config at it,
+// e.g. in config.json in the app data directory:
+//
+//	{
+//	  "version": 1,
+//	  "client_id": "fake_client",
+//	  "client_secret": "fake_secret",
+//	  "redirect_url": "http://127.0.0.1/callback",
+//	  "auth_url": "http://127.0.0.1:8081/oauth2/authorize",
+//	  "token_url": "http://127.0.0.1:8081/oauth2/token",
+//	  "api_url": "http://127.0.0.1:8081"
+//	}
+//
+// or with the MONZO_WALLET_CLIENT_ID, MONZO_WALLET_AUTH_URL etc. environment
+// variables.
+//
+// With "webhook_url" set to e.g. "http://127.0.0.1:8082", the app registers its
+// webhook receiver here and logs its local URL, to which an event can also be
+// posted by hand:
+//
+//	curl -d @internal/fakemonzo/fixtures/webhook_transaction_created.json <url>
+package fakemonzo
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	mrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// tokenLifetime is the expires_in reported for issued access tokens.
+	tokenLifetime = 6 * time.Hour
+	// maxPageSize is the largest number of transactions returned at once.
+	maxPageSize = 100
+)
+
+// Fault describes an error response the server returns instead of handling a
+// request.
+type Fault struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+// Server is an http.Handler that mimics the Monzo API. The zero value is not
+// usable, create one with New.
+type Server struct {
+	// dataMtx guards fixtures, which pot deposits and withdrawals and
+	// mandate cancellations modify, dedupeIDs and webhooks.
+	dataMtx   sync.RWMutex
+	fixtures  *Fixtures
+	dedupeIDs map[string]json.RawMessage // pots returned for each dedupe_id
+	webhooks  []*webhook
+
+	mtx           sync.Mutex
+	latency       time.Duration
+	failureRate   float64
+	faults        map[string][]Fault // pending faults keyed by request path
+	codes         map[string]string  // issued authorization codes and their PKCE challenges
+	accessTokens  map[string]bool
+	refreshTokens map[string]bool
+
+	mux *http.ServeMux
+}
+
+// New returns a Server that serves the given fixtures.
+func New(fixtures *Fixtures) *Server {
+	s := &Server{
+		fixtures:      fixtures,
+		dedupeIDs:     make(map[string]json.RawMessage),
+		faults:        make(map[string][]Fault),
+		codes:         make(map[string]string),
+		accessTokens:  make(map[string]bool),
+		refreshTokens: make(map[string]bool),
+		mux:           http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/oauth2/authorize", s.handleAuthorize)
+	s.mux.HandleFunc("/oauth2/token", s.handleToken)
+	s.mux.HandleFunc("/accounts", s.authenticated(s.handleAccounts))
+	s.mux.HandleFunc("/balance", s.authenticated(s.handleBalance))
+	s.mux.HandleFunc("/transactions", s.authenticated(s.handleTransactions))
+	s.mux.HandleFunc("/pots", s.authenticated(s.handlePots))
+	s.mux.HandleFunc("/pots/", s.authenticated(s.handlePotTransfer))
+	s.mux.HandleFunc("/direct_debits", s.authenticated(s.handleMandates("direct_debits", &fixtures.DirectDebits)))
+	s.mux.HandleFunc("/direct_debits/", s.authenticated(s.handleCancelMandate("direct_debits", &fixtures.DirectDebits)))
+	s.mux.HandleFunc("/standing_orders", s.authenticated(s.handleMandates("standing_orders", &fixtures.StandingOrders)))
+	s.mux.HandleFunc("/standing_orders/", s.authenticated(s.handleCancelMandate("standing_orders", &fixtures.StandingOrders)))
+	s.mux.HandleFunc("/webhooks", s.authenticated(s.handleWebhooks))
+	s.mux.HandleFunc("/webhooks/", s.authenticated(s.handleDeleteWebhook))
+	s.mux.HandleFunc("/_fake/fault", s.handleFault)
+
+	return s
+}
+
+// SetLatency delays every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mtx.Lock()
+	s.latency = d
+	s.mtx.Unlock()
+}
+
+// SetFailureRate makes the server answer a random fraction of requests, in
+// the range [0, 1], with a 500 internal error.
+func (s *Server) SetFailureRate(rate float64) {
+	s.mtx.Lock()
+	s.failureRate = math.Max(0, math.Min(1, rate))
+	s.mtx.Unlock()
+}
+
+// InjectFault makes the next n requests to path fail with fault.
+func (s *Server) InjectFault(path string, fault Fault, n int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for i := 0; i < n; i++ {
+		s.faults[path] = append(s.faults[path], fault)
+	}
+}
+
+// IssueToken returns a valid access token without going through the OAuth
+// flow, for callers that only want to exercise the data endpoints.
+func (s *Server) IssueToken() string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	token := randomString()
+	s.accessTokens[token] = true
+	return token
+}
+
+// ServeHTTP applies the configured latency and faults, then dispatches the
+// request.
+// Part of the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mtx.Lock()
+	latency := s.latency
+	fault, hasFault := s.nextFault(r.URL.Path)
+	if !hasFault && s.failureRate > 0 && mrand.Float64() < s.failureRate {
+		fault, hasFault = Fault{Status: http.StatusInternalServerError, Code: "internal_service", Message: "Injected failure"}, true
+	}
+	s.mtx.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if hasFault {
+		writeError(w, fault.Status, fault.Code, fault.Message)
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// nextFault pops the next pending fault for path. s.mtx must be held.
+func (s *Server) nextFault(path string) (Fault, bool) {
+	pending := s.faults[path]
+	if len(pending) == 0 {
+		return Fault{}, false
+	}
+	s.faults[path] = pending[1:]
+	return pending[0], true
+}
+
+// handleAuthorize approves every authorization request and redirects straight
+// back to the client with a fresh code.
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		writeError(w, http.StatusBadRequest, "bad_request.bad_param.redirect_uri", "Invalid redirect_uri")
+		return
+	}
+	if query.Get("response_type") != "code" {
+		writeError(w, http.StatusBadRequest, "bad_request.bad_param.response_type", "Unsupported response_type")
+		return
+	}
+
+	challenge := query.Get("code_challenge")
+	if challenge != "" && query.Get("code_challenge_method") != "S256" {
+		writeError(w, http.StatusBadRequest, "bad_request.bad_param.code_challenge_method", "Only S256 is supported")
+		return
+	}
+
+	code := randomString()
+	s.mtx.Lock()
+	s.codes[code] = challenge
+	s.mtx.Unlock()
+
+	callback := redirectURI.Query()
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	redirectURI.RawQuery = callback.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "bad_request.method_not_allowed", "Use POST")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		code := r.PostForm.Get("code")
+		challenge, ok := s.codes[code]
+		if !ok {
+			writeTokenError(w, http.StatusUnauthorized, "invalid_grant", "unauthorized.bad_authorization_code", "Authorization code has been used or has expired")
+			return
+		}
+		delete(s.codes, code)
+		if challenge != "" && pkceChallenge(r.PostForm.Get("code_verifier")) != challenge {
+			writeTokenError(w, http.StatusUnauthorized, "invalid_grant", "unauthorized.bad_code_verifier", "Code verifier does not match the code challenge")
+			return
+		}
+
+	case "refresh_token":
+		refreshToken := r.PostForm.Get("refresh_token")
+		if !s.refreshTokens[refreshToken] {
+			writeTokenError(w, http.StatusUnauthorized, "invalid_grant", "unauthorized.bad_refresh_token", "Refresh token has been used or has been revoked")
+			return
+		}
+		delete(s.refreshTokens, refreshToken)
+
+	default:
+		writeTokenError(w, http.StatusBadRequest, "unsupported_grant_type", "bad_request.bad_param.grant_type", "Unsupported grant_type")
+		return
+	}
+
+	accessToken, refreshToken := randomString(), randomString()
+	s.accessTokens[accessToken] = true
+	s.refreshTokens[refreshToken] = true
+
+	writeJSON(w, map[string]interface{}{
+		"access_token":  accessToken,
+		"client_id":     r.PostForm.Get("client_id"),
+		"expires_in":    int64(tokenLifetime / time.Second),
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"user_id":       "user_00009237aqC8c5umZmrRdh",
+	})
+}
+
+// authenticated rejects requests that do not carry an access token issued by
+// this server.
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "Bearer "
+		header := r.Header.Get("Authorization")
+		token := ""
+		if len(header) > len(prefix) && header[:len(prefix)] == prefix {
+			token = header[len(prefix):]
+		}
+
+		s.mtx.Lock()
+		valid := s.accessTokens[token]
+		s.mtx.Unlock()
+
+		if !valid {
+			writeError(w, http.StatusUnauthorized, "unauthorized.bad_access_token", "Access token is invalid or has expired")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleAccounts(w http.ResponseWriter, r *http.Request) {
+	accountType := r.URL.Query().Get("account_type")
+
+	s.dataMtx.RLock()
+	defer s.dataMtx.RUnlock()
+
+	accounts := make([]json.RawMessage, 0, len(s.fixtures.Accounts))
+	for _, account := range s.fixtures.Accounts {
+		var fields struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(account, &fields); err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_service", err.Error())
+			return
+		}
+		if accountType == "" || fields.Type == accountType {
+			accounts = append(accounts, account)
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{"accounts": accounts})
+}
+
+func (s *Server) handleBalance(w http.ResponseWriter, r *http.Request) {
+	accountID := r.URL.Query().Get("account_id")
+	s.dataMtx.RLock()
+	balance, ok := s.fixtures.Balances[accountID]
+	s.dataMtx.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found.account", "Account not found")
+		return
+	}
+	writeJSON(w, balance)
+}
+
+func (s *Server) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	accountID := r.URL.Query().Get("account_id")
+	s.dataMtx.RLock()
+	transactions, ok := s.fixtures.Transactions[accountID]
+	s.dataMtx.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found.account", "Account not found")
+		return
+	}
+
+	transactions, err := paginate(transactions, r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request.bad_param", err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("expand[]") != "merchant" {
+		collapsed := make([]json.RawMessage, 0, len(transactions))
+		for _, transaction := range transactions {
+			transaction, err := collapseMerchant(transaction)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "internal_service", err.Error())
+				return
+			}
+			collapsed = append(collapsed, transaction)
+		}
+		transactions = collapsed
+	}
+
+	writeJSON(w, map[string]interface{}{"transactions": transactions})
+}
+
+// paginate applies the since, before and limit parameters of the
+// transactions endpoint. since is either an RFC 3339 timestamp or the ID of
+// the last transaction already seen, before is an RFC 3339 timestamp and
+// limit caps the number of results at maxPageSize. The fixtures are ordered
+// oldest first, as are the results.
+func paginate(transactions []json.RawMessage, query url.Values) ([]json.RawMessage, error) {
+	limit := maxPageSize
+	if l := query.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit %q", l)
+		}
+		if n < limit {
+			limit = n
+		}
+	}
+
+	var since, before time.Time
+	sinceID := ""
+	if s := query.Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			sinceID = s
+		}
+		since = t
+	}
+	if b := query.Get("before"); b != "" {
+		t, err := time.Parse(time.RFC3339, b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid before %q", b)
+		}
+		before = t
+	}
+
+	page := make([]json.RawMessage, 0, len(transactions))
+	seen := sinceID == ""
+	for _, transaction := range transactions {
+		var fields struct {
+			ID      string    `json:"id"`
+			Created time.Time `json:"created"`
+		}
+		if err := json.Unmarshal(transaction, &fields); err != nil {
+			return nil, err
+		}
+
+		if !seen {
+			seen = fields.ID == sinceID
+			continue
+		}
+		if !since.IsZero() && fields.Created.Before(since) {
+			continue
+		}
+		if !before.IsZero() && !fields.Created.Before(before) {
+			continue
+		}
+		if len(page) == limit {
+			break
+		}
+		page = append(page, transaction)
+	}
+	return page, nil
+}
+
+// collapseMerchant replaces an expanded merchant object with its ID, which is
+// what the API returns unless expand[]=merchant is requested.
+func collapseMerchant(transaction json.RawMessage) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(transaction, &fields); err != nil {
+		return nil, err
+	}
+
+	var merchant struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(fields["merchant"], &merchant); err != nil {
+		// Not an object, so it is either null or already collapsed.
+		return transaction, nil
+	}
+	if merchant.ID == "" {
+		return transaction, nil
+	}
+
+	id, err := json.Marshal(merchant.ID)
+	if err != nil {
+		return nil, err
+	}
+	fields["merchant"] = id
+	return json.Marshal(fields)
+}
+
+func (s *Server) handlePots(w http.ResponseWriter, r *http.Request) {
+	accountID := r.URL.Query().Get("current_account_id")
+	s.dataMtx.RLock()
+	pots := s.fixtures.Pots[accountID]
+	s.dataMtx.RUnlock()
+	if pots == nil {
+		pots = []json.RawMessage{}
+	}
+	writeJSON(w, map[string]interface{}{"pots": pots})
+}
+
+// handlePotTransfer serves PUT /pots/{id}/deposit and PUT /pots/{id}/withdraw.
+// The pot and account balances are updated and a savings transaction is added
+// to the account. A request with a dedupe_id that was seen before is answered
+// with the pot as it was returned then, without moving the money again.
+func (s *Server) handlePotTransfer(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/pots/"), "/")
+	if len(parts) != 2 || (parts[1] != "deposit" && parts[1] != "withdraw") {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return
+	}
+	potID, withdraw := parts[0], parts[1] == "withdraw"
+
+	if r.Method != http.MethodPut {
+		writeError(w, http.StatusMethodNotAllowed, "bad_request.method_not_allowed", "Use PUT")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	accountParam := "source_account_id"
+	if withdraw {
+		accountParam = "destination_account_id"
+	}
+	accountID := r.PostForm.Get(accountParam)
+	amount, err := strconv.ParseInt(r.PostForm.Get("amount"), 10, 64)
+	if err != nil || amount <= 0 {
+		writeError(w, http.StatusBadRequest, "bad_request.bad_param.amount", "amount must be a positive number")
+		return
+	}
+	dedupeID := r.PostForm.Get("dedupe_id")
+	if dedupeID == "" {
+		writeError(w, http.StatusBadRequest, "bad_request.missing_param.dedupe_id", "Missing dedupe_id")
+		return
+	}
+
+	s.dataMtx.Lock()
+	defer s.dataMtx.Unlock()
+
+	if pot, ok := s.dedupeIDs[dedupeID]; ok {
+		writeJSON(w, pot)
+		return
+	}
+
+	index := -1
+	var pot map[string]interface{}
+	for i, raw := range s.fixtures.Pots[accountID] {
+		if err := unmarshalNumbers(raw, &pot); err != nil {
+			writeError(w, http.StatusInternalServerError, "internal_service", err.Error())
+			return
+		}
+		if pot["id"] == potID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		writeError(w, http.StatusNotFound, "not_found.pot", "Pot not found")
+		return
+	}
+	if withdraw && pot["locked"] == true {
+		writeError(w, http.StatusForbidden, "forbidden.pot_locked", "Pot is locked")
+		return
+	}
+
+	rawBalance, ok := s.fixtures.Balances[accountID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found.account", "Account not found")
+		return
+	}
+	var balance map[string]interface{}
+	if err := unmarshalNumbers(rawBalance, &balance); err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_service", err.Error())
+		return
+	}
+	potBalance, ok := intField(pot, "balance")
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "bad_request.invalid_fixture", "Pot has no balance")
+		return
+	}
+	accountBalance, ok := intField(balance, "balance")
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "bad_request.invalid_fixture", "Account has no balance")
+		return
+	}
+
+	delta := amount
+	if withdraw {
+		delta = -amount
+	}
+	if potBalance+delta < 0 || accountBalance-delta < 0 {
+		writeError(w, http.StatusBadRequest, "bad_request.insufficient_funds", "Insufficient funds")
+		return
+	}
+	pot["balance"] = potBalance + delta
+	pot["updated"] = time.Now().UTC().Format(time.RFC3339Nano)
+	balance["balance"] = accountBalance - delta
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	transaction, err := json.Marshal(map[string]interface{}{
+		"id":             "tx_" + randomString(),
+		"created":        now,
+		"account_id":     accountID,
+		"amount":         -delta,
+		"currency":       pot["currency"],
+		"local_amount":   -delta,
+		"local_currency": pot["currency"],
+		"description":    potID,
+		"category":       "savings",
+		"scheme":         "uk_retail_pot",
+		"settled":        now,
+		"notes":          "",
+		"merchant":       nil,
+		"counterparty":   map[string]interface{}{},
+		"attachments":    []interface{}{},
+		"metadata":       map[string]string{"pot_id": potID},
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_service", err.Error())
+		return
+	}
+	potJSON, err := json.Marshal(pot)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_service", err.Error())
+		return
+	}
+	balanceJSON, err := json.Marshal(balance)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_service", err.Error())
+		return
+	}
+
+	// The fixture slices may be shared with the caller, so they are copied
+	// rather than modified.
+	pots := append([]json.RawMessage(nil), s.fixtures.Pots[accountID]...)
+	pots[index] = potJSON
+	s.fixtures.Pots[accountID] = pots
+	s.fixtures.Balances[accountID] = balanceJSON
+	if s.fixtures.Transactions == nil {
+		s.fixtures.Transactions = make(map[string][]json.RawMessage)
+	}
+	s.fixtures.Transactions[accountID] = append(append([]json.RawMessage(nil), s.fixtures.Transactions[accountID]...), transaction)
+	s.dedupeIDs[dedupeID] = potJSON
+	s.deliverTransaction(accountID, transaction)
+
+	writeJSON(w, json.RawMessage(potJSON))
+}
+
+// webhook is a webhook registered with POST /webhooks.
+type webhook struct {
+	ID        string `json:"id"`
+	AccountID string `json:"account_id"`
+	URL       string `json:"url"`
+}
+
+// handleMandates returns a handler that lists the direct debits or standing
+// orders of an account from mandates under key.
+func (s *Server) handleMandates(key string, mandates *map[string][]json.RawMessage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountID := r.URL.Query().Get("account_id")
+		if accountID == "" {
+			writeError(w, http.StatusBadRequest, "bad_request.missing_param.account_id", "account_id is required")
+			return
+		}
+
+		s.dataMtx.RLock()
+		list := (*mandates)[accountID]
+		s.dataMtx.RUnlock()
+		if list == nil {
+			list = []json.RawMessage{}
+		}
+		writeJSON(w, map[string]interface{}{key: list})
+	}
+}
+
+// handleCancelMandate returns a handler for DELETE /{key}/{id}, which sets
+// the status of the direct debit or standing order to cancelled.
+func (s *Server) handleCancelMandate(key string, mandates *map[string][]json.RawMessage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			writeError(w, http.StatusMethodNotAllowed, "bad_request.method_not_allowed", "Use DELETE")
+			return
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/"+key+"/")
+
+		s.dataMtx.Lock()
+		defer s.dataMtx.Unlock()
+		for accountID, list := range *mandates {
+			for i, raw := range list {
+				var fields map[string]json.RawMessage
+				if err := unmarshalNumbers(raw, &fields); err != nil {
+					writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+					return
+				}
+				var mandateID string
+				if err := json.Unmarshal(fields["id"], &mandateID); err != nil || mandateID != id {
+					continue
+				}
+
+				fields["status"] = json.RawMessage(`"cancelled"`)
+				cancelled, err := json.Marshal(fields)
+				if err != nil {
+					writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+					return
+				}
+				updated := append([]json.RawMessage(nil), list...)
+				updated[i] = cancelled
+				(*mandates)[accountID] = updated
+				writeJSON(w, map[string]interface{}{})
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "not_found.mandate", "Mandate not found")
+	}
+}
+
+// handleWebhooks lists the webhooks of an account on GET and registers one on
+// POST.
+func (s *Server) handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		accountID := r.URL.Query().Get("account_id")
+		if accountID == "" {
+			writeError(w, http.StatusBadRequest, "bad_request.missing_param.account_id", "account_id is required")
+			return
+		}
+
+		s.dataMtx.RLock()
+		webhooks := []*webhook{}
+		for _, hook := range s.webhooks {
+			if hook.AccountID == accountID {
+				webhooks = append(webhooks, hook)
+			}
+		}
+		s.dataMtx.RUnlock()
+		writeJSON(w, map[string]interface{}{"webhooks": webhooks})
+
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+			return
+		}
+		hook := &webhook{
+			ID:        "webhook_" + randomString(),
+			AccountID: r.PostForm.Get("account_id"),
+			URL:       r.PostForm.Get("url"),
+		}
+		if hook.AccountID == "" {
+			writeError(w, http.StatusBadRequest, "bad_request.missing_param.account_id", "account_id is required")
+			return
+		}
+		if u, err := url.Parse(hook.URL); err != nil || u.Host == "" {
+			writeError(w, http.StatusBadRequest, "bad_request.bad_param.url", "url must be an absolute URL")
+			return
+		}
+
+		s.dataMtx.Lock()
+		s.webhooks = append(s.webhooks, hook)
+		s.dataMtx.Unlock()
+		writeJSON(w, map[string]interface{}{"webhook": hook})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "bad_request.method_not_allowed", "Use GET or POST")
+	}
+}
+
+// handleDeleteWebhook handles DELETE /webhooks/{id}.
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "bad_request.method_not_allowed", "Use DELETE")
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/webhooks/")
+
+	s.dataMtx.Lock()
+	defer s.dataMtx.Unlock()
+	for i, hook := range s.webhooks {
+		if hook.ID == id {
+			s.webhooks = append(s.webhooks[:i:i], s.webhooks[i+1:]...)
+			writeJSON(w, map[string]interface{}{})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "not_found.webhook", "Webhook not found")
+}
+
+// deliverTransaction posts a transaction.created event for the transaction to
+// the webhooks of the account in the background. Delivery is best effort, like
+// the real API. s.dataMtx must be held.
+func (s *Server) deliverTransaction(accountID string, transaction json.RawMessage) {
+	event, err := json.Marshal(map[string]interface{}{
+		"type": "transaction.created",
+		"data": transaction,
+	})
+	if err != nil {
+		return
+	}
+
+	for _, hook := range s.webhooks {
+		if hook.AccountID != accountID {
+			continue
+		}
+		go func(url string) {
+			rsp, err := http.Post(url, "application/json", bytes.NewReader(event))
+			if err != nil {
+				logrus.Infof("delivering webhook to %s: %v", url, err)
+				return
+			}
+			rsp.Body.Close()
+		}(hook.URL)
+	}
+}
+
+// unmarshalNumbers decodes data into v, keeping numbers as json.Number so
+// that amounts survive being encoded again unchanged.
+func unmarshalNumbers(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// intField returns the integer at key of an object decoded with
+// unmarshalNumbers, and whether there is one.
+func intField(object map[string]interface{}, key string) (int64, bool) {
+	number, ok := object[key].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	n, err := number.Int64()
+	return n, err == nil
+}
+
+// handleFault lets a running server be told to fail requests, e.g.
+// POST /_fake/fault?path=/accounts&status=500&times=3
+func (s *Server) handleFault(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "bad_request.method_not_allowed", "Use POST")
+		return
+	}
+
+	query := r.URL.Query()
+	status, err := strconv.Atoi(query.Get("status"))
+	if err != nil || status < 400 {
+		writeError(w, http.StatusBadRequest, "bad_request.bad_param.status", "status must be an HTTP error code")
+		return
+	}
+	times := 1
+	if t := query.Get("times"); t != "" {
+		if times, err = strconv.Atoi(t); err != nil || times < 1 {
+			writeError(w, http.StatusBadRequest, "bad_request.bad_param.times", "times must be a positive number")
+			return
+		}
+	}
+
+	s.InjectFault(query.Get("path"), Fault{
+		Status:  status,
+		Code:    "injected",
+		Message: "Injected fault",
+	}, times)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"code":    code,
+		"message": message,
+	})
+}
+
+// writeTokenError writes an error of the token endpoint, which carries the
+// OAuth error, e.g. invalid_grant, in addition to the Monzo error code.
+func writeTokenError(w http.ResponseWriter, status int, oauthError, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":             oauthError,
+		"error_description": message,
+		"code":              code,
+		"message":           message,
+	})
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString() string {
+	randomBytes := make([]byte, 16)
+	if _, err := rand.Read(randomBytes); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(randomBytes)
+}
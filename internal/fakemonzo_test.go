@@ -0,0 +1,299 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tjvr/go-monzo"
+	"go-monzo-wallet/internal/fakemonzo"
+	"golang.org/x/oauth2"
+)
+
+// startFakeMonzo serves the default fixtures and returns the server and the
+// OAuth config of a client registered with it.
+func startFakeMonzo(t *testing.T) (*fakemonzo.Server, *httptest.Server, *oauth2.Config) {
+	t.Helper()
+	fixtures, err := fakemonzo.DefaultFixtures()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := fakemonzo.New(fixtures)
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	conf := &oauth2.Config{
+		ClientID:     "fake_client",
+		ClientSecret: "fake_secret",
+		RedirectURL:  "http://127.0.0.1/callback",
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  srv.URL + "/oauth2/authorize",
+			TokenURL: srv.URL + "/oauth2/token",
+		},
+	}
+	return fake, srv, conf
+}
+
+// connect logs in to the fake server, following the authorization redirect
+// instead of opening a browser.
+func connect(t *testing.T, w *Wallet, conf *oauth2.Config) *oauth2.Token {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	token, err := w.ConnectWith(ctx, conf, func(authURL string) error {
+		go func() {
+			rsp, err := http.Get(authURL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			rsp.Body.Close()
+		}()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	return token
+}
+
+func TestConnectAndFetchAccounts(t *testing.T) {
+	_, srv, conf := startFakeMonzo(t)
+
+	w := NewWallet(MonzoProvider(srv.URL))
+	token := connect(t, w, conf)
+	w.Authenticate(conf.TokenSource(context.Background(), token))
+
+	if err := w.FetchAccounts(); err != nil {
+		t.Fatalf("FetchAccounts: %v", err)
+	}
+
+	accounts := w.AccountsList()
+	if len(accounts) != 2 {
+		t.Fatalf("got %d accounts, want 2", len(accounts))
+	}
+	retail := accounts[0]
+	if retail.ID != "acc_00009237aqC8c5umZmrRdh" || retail.Type != AccountTypeRetail {
+		t.Errorf("first account is %s of type %s, want the retail account", retail.ID, retail.Type)
+	}
+	if want := NewMoney(123456, "GBP"); retail.Balance != want {
+		t.Errorf("balance is %v, want %v", retail.Balance, want)
+	}
+	if len(retail.Transactions) == 0 {
+		t.Error("no transactions were loaded")
+	}
+	if len(retail.Pots) == 0 {
+		t.Error("no pots were loaded")
+	}
+	if accounts[1].Type != AccountTypeJoint {
+		t.Errorf("second account is of type %s, want %s", accounts[1].Type, AccountTypeJoint)
+	}
+}
+
+func TestFetchAccountsFaults(t *testing.T) {
+	fake, srv, _ := startFakeMonzo(t)
+	w := NewWallet(MonzoProvider(srv.URL))
+	w.Authenticate(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: fake.IssueToken()}))
+
+	fake.InjectFault("/balance", fakemonzo.Fault{
+		Status:  http.StatusInternalServerError,
+		Code:    "internal_service",
+		Message: "Injected failure",
+	}, 1)
+	if err := w.FetchAccounts(); err == nil {
+		t.Fatal("FetchAccounts succeeded despite a failing balance request")
+	}
+	if w.LoadedWallet() {
+		t.Error("the wallet is loaded after a failed fetch")
+	}
+
+	// The fault is used up, so the next fetch succeeds, however slowly.
+	const latency = 20 * time.Millisecond
+	fake.SetLatency(latency)
+	start := time.Now()
+	if err := w.FetchAccounts(); err != nil {
+		t.Fatalf("FetchAccounts: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < latency {
+		t.Errorf("FetchAccounts took %v, less than the latency of %v", elapsed, latency)
+	}
+	if !w.LoadedWallet() {
+		t.Error("the wallet is not loaded")
+	}
+}
+
+func TestFetchAccountsRevokedToken(t *testing.T) {
+	_, srv, _ := startFakeMonzo(t)
+	w := NewWallet(MonzoProvider(srv.URL))
+	w.Authenticate(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "not_issued"}))
+
+	err := w.FetchAccounts()
+	if !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("FetchAccounts returned %v, want ErrTokenRevoked", err)
+	}
+	var profileErr *ProfileError
+	if !errors.As(err, &profileErr) || profileErr.Profile.ID != DefaultProfileID {
+		t.Errorf("FetchAccounts returned %v, want a *ProfileError of the default profile", err)
+	}
+}
+
+func TestFetchAccountsProfileFailure(t *testing.T) {
+	fake, srv, _ := startFakeMonzo(t)
+	w := NewWallet(MonzoProvider(srv.URL))
+
+	personal := DefaultProfile()
+	personal.AccountTypes = []string{AccountTypeRetail}
+	joint, err := NewProfile("Joint", []string{AccountTypeJoint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetProfiles([]*Profile{personal, joint})
+	w.Authenticate(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: fake.IssueToken()}))
+	w.AuthenticateProfile(joint.ID, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: fake.IssueToken()}))
+	if err := w.FetchAccounts(); err != nil {
+		t.Fatalf("FetchAccounts: %v", err)
+	}
+
+	// The joint profile fails, which does not stop the personal one.
+	w.AuthenticateProfile(joint.ID, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "not_issued"}))
+	err = w.FetchAccounts()
+	var profileErrs ProfileErrors
+	if !errors.As(err, &profileErrs) || len(profileErrs) != 1 || profileErrs.Failed(joint.ID) == nil {
+		t.Fatalf("FetchAccounts returned %v, want ProfileErrors for the joint profile only", err)
+	}
+	if !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("FetchAccounts returned %v, want it to wrap ErrTokenRevoked", err)
+	}
+	if revoked := profileErrs.Revoked(); len(revoked) != 1 || revoked[0] != joint {
+		t.Errorf("Revoked returned %v, want the joint profile", revoked)
+	}
+
+	accounts := w.AccountsList()
+	if len(accounts) != 2 {
+		t.Fatalf("got %d accounts, want the personal account and the joint one loaded before", len(accounts))
+	}
+	if accounts[0].ProfileID != personal.ID || accounts[1].ProfileID != joint.ID {
+		t.Errorf("accounts belong to profiles %s and %s, want %s and %s",
+			accounts[0].ProfileID, accounts[1].ProfileID, personal.ID, joint.ID)
+	}
+}
+
+func TestSyncerSkipsRevokedProfile(t *testing.T) {
+	fake, srv, _ := startFakeMonzo(t)
+	w := NewWallet(MonzoProvider(srv.URL))
+
+	personal := DefaultProfile()
+	personal.AccountTypes = []string{AccountTypeRetail}
+	joint, err := NewProfile("Joint", []string{AccountTypeJoint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetProfiles([]*Profile{personal, joint})
+	w.Authenticate(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: fake.IssueToken()}))
+	w.AuthenticateProfile(joint.ID, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "not_issued"}))
+
+	syncer := w.Syncer()
+	events, unsubscribe := syncer.Subscribe()
+	defer unsubscribe()
+	syncer.Start()
+	defer syncer.Stop()
+
+	// next returns the next LoginRequired or SyncFinished event.
+	next := func() SyncEvent {
+		t.Helper()
+		timeout := time.After(10 * time.Second)
+		for {
+			select {
+			case event := <-events:
+				switch event.(type) {
+				case LoginRequired, SyncFinished:
+					return event
+				}
+			case <-timeout:
+				t.Fatal("timed out waiting for the sync")
+			}
+		}
+	}
+
+	loginRequired, ok := next().(LoginRequired)
+	if !ok || loginRequired.Profile != joint {
+		t.Fatalf("got %#v, want LoginRequired for the joint profile", loginRequired)
+	}
+	if !syncer.Running() {
+		t.Fatal("the background sync stopped")
+	}
+
+	// The joint profile is skipped until it logs in again.
+	syncer.SyncNow()
+	if event, ok := next().(SyncFinished); !ok {
+		t.Fatalf("got %#v, want SyncFinished", event)
+	}
+	accounts := w.AccountsList()
+	if len(accounts) != 1 || accounts[0].ProfileID != personal.ID {
+		t.Errorf("got %d accounts, want the personal account", len(accounts))
+	}
+}
+
+func TestFetchAccountsTimeout(t *testing.T) {
+	fake, srv, _ := startFakeMonzo(t)
+	fake.SetLatency(time.Second)
+	w := NewWallet(MonzoProviderWithClient(srv.URL, &http.Client{Timeout: 50 * time.Millisecond}))
+	w.Authenticate(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: fake.IssueToken()}))
+
+	start := time.Now()
+	err := w.FetchAccounts()
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("FetchAccounts returned %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("FetchAccounts took %v, as long as the latency of the API", elapsed)
+	}
+}
+
+func TestDepositInvalidFixtures(t *testing.T) {
+	tests := []struct {
+		name       string
+		fixtures   string
+		wantStatus int
+	}{
+		{
+			name: "pot without a balance",
+			fixtures: `{
+				"balances": {"acc_1": {"balance": 1000, "currency": "GBP", "spend_today": 0}},
+				"pots": {"acc_1": [{"id": "pot_1", "name": "Savings", "currency": "GBP"}]}
+			}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "account without a balance",
+			fixtures: `{
+				"pots": {"acc_1": [{"id": "pot_1", "name": "Savings", "balance": 500, "currency": "GBP"}]}
+			}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fixtures, err := fakemonzo.ParseFixtures([]byte(test.fixtures))
+			if err != nil {
+				t.Fatal(err)
+			}
+			fake := fakemonzo.New(fixtures)
+			srv := httptest.NewServer(fake)
+			defer srv.Close()
+
+			_, err = NewMonzo(srv.URL, fake.IssueToken()).Deposit("pot_1", "acc_1", NewMoney(100, "GBP"), "dedupe_1")
+			var apiErr *monzo.APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != test.wantStatus {
+				t.Errorf("Deposit returned %v, want an API error with status %d", err, test.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/scrypt"
+)
+
+// LockPINFileName is the name of the file in AppDataDir that keeps the key
+// derived from the unlock PIN.
+const LockPINFileName = "pin.json"
+
+// UnlockAttemptsFileName is the name of the file in AppDataDir that keeps the
+// failed unlock attempts, so that restarting the app does not reset their
+// delay.
+const UnlockAttemptsFileName = "unlock_attempts.json"
+
+const (
+	// MinLockPINLength is the minimum number of digits of an unlock PIN.
+	MinLockPINLength = 4
+
+	// freeUnlockAttempts is the number of failed unlock attempts after which
+	// further attempts are delayed.
+	freeUnlockAttempts = 3
+	// maxUnlockDelay is the longest delay between failed unlock attempts.
+	maxUnlockDelay = 5 * time.Minute
+)
+
+// ErrInvalidLockPIN is returned by Locker.SetPIN for PINs that are not at
+// least MinLockPINLength digits.
+var ErrInvalidLockPIN = fmt.Errorf("the PIN must be at least %d digits", MinLockPINLength)
+
+// UnlockThrottledError is returned by Locker.Unlock while attempts are
+// delayed after too many failed ones.
+type UnlockThrottledError struct {
+	// RetryAt is when the next attempt is allowed.
+	RetryAt time.Time
+}
+
+func (e *UnlockThrottledError) Error() string {
+	return fmt.Sprintf("too many failed attempts, try again at %s", e.RetryAt.Local().Format("15:04:05"))
+}
+
+// lockSecret is a key derived from a passphrase or PIN with scrypt, which
+// lets the passphrase be checked without keeping it.
+type lockSecret struct {
+	Salt []byte `json:"salt"`
+	Key  []byte `json:"key"`
+}
+
+func newLockSecret(secret string) (*lockSecret, error) {
+	s := &lockSecret{Salt: make([]byte, saltSize)}
+	if _, err := rand.Read(s.Salt); err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key([]byte(secret), s.Salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	s.Key = key
+	return s, nil
+}
+
+// unlockAttempts are the failed unlock attempts as kept in
+// UnlockAttemptsFileName.
+type unlockAttempts struct {
+	Failures int       `json:"failures"`
+	RetryAt  time.Time `json:"retry_at"`
+}
+
+// matches reports whether secret is the one the key was derived from.
+func (s *lockSecret) matches(secret string) bool {
+	key, err := scrypt.Key([]byte(secret), s.Salt, scryptN, scryptR, scryptP, keySize)
+	return err == nil && subtle.ConstantTimeCompare(key, s.Key) == 1
+}
+
+// Locker locks the app after it has been idle for a while and unlocks it with
+// the startup passphrase or, if one is set, an unlock PIN. Neither is kept,
+// only keys derived from them. Failed attempts beyond the first few are
+// delayed, doubling the delay with every failure.
+type Locker struct {
+	mtx          sync.Mutex
+	timeout      time.Duration
+	lastActivity time.Time
+	locked       bool
+
+	passphrase *lockSecret
+	pin        *lockSecret
+
+	failures int
+	retryAt  time.Time
+	// dir is where the PIN and the failed attempts are kept, see LoadPIN.
+	dir string
+}
+
+// NewLocker returns an unlocked Locker that locks after timeout without
+// activity, or never if timeout is zero. It cannot lock until SetPassphrase
+// is called.
+func NewLocker(timeout time.Duration) *Locker {
+	return &Locker{
+		timeout:      timeout,
+		lastActivity: time.Now(),
+	}
+}
+
+// SetTimeout changes how long the app may be idle before it locks, zero to
+// never lock.
+func (l *Locker) SetTimeout(timeout time.Duration) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.timeout = timeout
+}
+
+// SetPassphrase makes passphrase unlock the app, e.g. the startup
+// passphrase once it unlocked the token store.
+func (l *Locker) SetPassphrase(passphrase string) error {
+	secret, err := newLockSecret(passphrase)
+	if err != nil {
+		return err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.passphrase = secret
+	return nil
+}
+
+// Enabled reports whether the app can be locked, which requires a
+// passphrase to unlock it.
+func (l *Locker) Enabled() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.passphrase != nil
+}
+
+// Touch records user activity at now, which postpones locking.
+func (l *Locker) Touch(now time.Time) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if now.After(l.lastActivity) {
+		l.lastActivity = now
+	}
+}
+
+// LockIfIdle locks the app if there was no activity for the timeout before
+// now, and reports whether it did.
+func (l *Locker) LockIfIdle(now time.Time) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.locked || l.passphrase == nil || l.timeout <= 0 || now.Sub(l.lastActivity) < l.timeout {
+		return false
+	}
+	l.locked = true
+	return true
+}
+
+// Lock locks the app right away if it can be locked.
+func (l *Locker) Lock() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.passphrase != nil {
+		l.locked = true
+	}
+}
+
+// Locked reports whether the app is locked.
+func (l *Locker) Locked() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.locked
+}
+
+// Unlock unlocks the app if secret is the passphrase or the PIN. It returns
+// ErrWrongPassphrase if it is neither, or an *UnlockThrottledError if the
+// attempt was made too soon after failed ones, in which case secret is not
+// checked.
+func (l *Locker) Unlock(secret string, now time.Time) error {
+	l.mtx.Lock()
+	if now.Before(l.retryAt) {
+		l.mtx.Unlock()
+		return &UnlockThrottledError{RetryAt: l.retryAt}
+	}
+	passphrase, pin := l.passphrase, l.pin
+	l.mtx.Unlock()
+
+	// The keys are derived without holding the lock, as that is slow by
+	// design.
+	ok := passphrase != nil && passphrase.matches(secret) || pin != nil && pin.matches(secret)
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if !ok {
+		l.failures++
+		if n := l.failures - freeUnlockAttempts; n > 0 {
+			delay := maxUnlockDelay
+			if n <= 16 {
+				if d := time.Second << (n - 1); d < maxUnlockDelay {
+					delay = d
+				}
+			}
+			l.retryAt = now.Add(delay)
+		}
+		l.saveAttempts()
+		return ErrWrongPassphrase
+	}
+
+	if l.failures > 0 {
+		l.failures = 0
+		l.retryAt = time.Time{}
+		l.saveAttempts()
+	}
+	l.locked = false
+	l.lastActivity = now
+	return nil
+}
+
+// saveAttempts writes the failed unlock attempts to UnlockAttemptsFileName
+// in l.dir, or removes the file once there are none. The delay still holds
+// while the app runs if they cannot be written. l.mtx must be held.
+func (l *Locker) saveAttempts() {
+	if l.dir == "" {
+		return
+	}
+	path := filepath.Join(l.dir, UnlockAttemptsFileName)
+	if l.failures == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logrus.Info("removing unlock attempts:", err)
+		}
+		return
+	}
+
+	data, err := json.Marshal(unlockAttempts{Failures: l.failures, RetryAt: l.retryAt})
+	if err == nil {
+		tmp := path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0600); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		logrus.Info("saving unlock attempts:", err)
+	}
+}
+
+// HasPIN reports whether an unlock PIN is set.
+func (l *Locker) HasPIN() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.pin != nil
+}
+
+// LoadPIN reads the unlock PIN from LockPINFileName in dir, if one was set,
+// and the failed unlock attempts from UnlockAttemptsFileName, to which later
+// failed attempts are written. The PIN is loaded even if the attempts
+// cannot be read.
+func (l *Locker) LoadPIN(dir string) error {
+	attemptsErr := l.loadAttempts(dir)
+
+	data, err := os.ReadFile(filepath.Join(dir, LockPINFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return attemptsErr
+	}
+	if err != nil {
+		return err
+	}
+
+	var pin lockSecret
+	if err := json.Unmarshal(data, &pin); err != nil {
+		return fmt.Errorf("reading %s: %v", LockPINFileName, err)
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.pin = &pin
+	return attemptsErr
+}
+
+// loadAttempts reads the failed unlock attempts from UnlockAttemptsFileName
+// in dir and keeps later ones there.
+func (l *Locker) loadAttempts(dir string) error {
+	l.mtx.Lock()
+	l.dir = dir
+	l.mtx.Unlock()
+
+	data, err := os.ReadFile(filepath.Join(dir, UnlockAttemptsFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var attempts unlockAttempts
+	if err := json.Unmarshal(data, &attempts); err != nil {
+		return fmt.Errorf("reading %s: %v", UnlockAttemptsFileName, err)
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.failures, l.retryAt = attempts.Failures, attempts.RetryAt
+	return nil
+}
+
+// SetPIN makes pin unlock the app in addition to the passphrase and writes
+// the key derived from it to LockPINFileName in dir. The startup passphrase
+// is still needed to start the app.
+func (l *Locker) SetPIN(dir, pin string) error {
+	if len(pin) < MinLockPINLength {
+		return ErrInvalidLockPIN
+	}
+	for _, r := range pin {
+		if r < '0' || r > '9' {
+			return ErrInvalidLockPIN
+		}
+	}
+
+	secret, err := newLockSecret(pin)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(secret)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, LockPINFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.pin = secret
+	return nil
+}
+
+// RemovePIN removes the unlock PIN, so that only the passphrase unlocks the
+// app.
+func (l *Locker) RemovePIN(dir string) error {
+	if err := os.Remove(filepath.Join(dir, LockPINFileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.pin = nil
+	return nil
+}
@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetPIN(t *testing.T) {
+	tests := []struct {
+		name    string
+		pin     string
+		wantErr error
+	}{
+		{"four digits", "1234", nil},
+		{"eight digits", "00000000", nil},
+		{"too short", "123", ErrInvalidLockPIN},
+		{"letter", "12a4", ErrInvalidLockPIN},
+		{"full-width digits", "１２３４", ErrInvalidLockPIN},
+		{"empty", "", ErrInvalidLockPIN},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			l := NewLocker(time.Minute)
+			if err := l.SetPIN(dir, test.pin); !errors.Is(err, test.wantErr) {
+				t.Fatalf("SetPIN returned %v, want %v", err, test.wantErr)
+			}
+			if l.HasPIN() != (test.wantErr == nil) {
+				t.Errorf("HasPIN returned %t after SetPIN returned %v", l.HasPIN(), test.wantErr)
+			}
+			if test.wantErr != nil {
+				if _, err := os.Stat(filepath.Join(dir, LockPINFileName)); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("the rejected PIN was written: %v", err)
+				}
+				return
+			}
+
+			// The PIN unlocks a Locker that loads it, as on the next launch.
+			loaded := NewLocker(time.Minute)
+			if err := loaded.LoadPIN(dir); err != nil {
+				t.Fatalf("LoadPIN: %v", err)
+			}
+			if err := loaded.SetPassphrase("passphrase"); err != nil {
+				t.Fatal(err)
+			}
+			loaded.Lock()
+			if err := loaded.Unlock(test.pin, time.Now()); err != nil || loaded.Locked() {
+				t.Errorf("Unlock with the PIN returned %v, locked: %t", err, loaded.Locked())
+			}
+
+			if err := loaded.RemovePIN(dir); err != nil {
+				t.Fatalf("RemovePIN: %v", err)
+			}
+			loaded.Lock()
+			if err := loaded.Unlock(test.pin, time.Now()); !errors.Is(err, ErrWrongPassphrase) {
+				t.Errorf("Unlock with the removed PIN returned %v, want ErrWrongPassphrase", err)
+			}
+		})
+	}
+}
+
+func TestUnlockThrottling(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2022, time.August, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds float64) time.Time { return start.Add(time.Duration(seconds * float64(time.Second))) }
+
+	// The steps run in order against the same Locker, unless restart
+	// replaces it with one that loads the state from dir.
+	tests := []struct {
+		name    string
+		restart bool
+		secret  string
+		now     time.Time
+		wantErr error
+		// wantRetryAt is the time of the *UnlockThrottledError.
+		wantRetryAt time.Time
+	}{
+		{name: "first failure", secret: "wrong", now: at(0), wantErr: ErrWrongPassphrase},
+		{name: "second failure", secret: "wrong", now: at(0), wantErr: ErrWrongPassphrase},
+		{name: "last free failure", secret: "wrong", now: at(0), wantErr: ErrWrongPassphrase},
+		{name: "first delayed failure", secret: "wrong", now: at(0), wantErr: ErrWrongPassphrase},
+		{name: "right PIN during the delay", secret: "1234", now: at(0.5), wantRetryAt: at(1)},
+		{name: "failure after the delay", secret: "wrong", now: at(1), wantErr: ErrWrongPassphrase},
+		{name: "delay doubles", secret: "passphrase", now: at(2.5), wantRetryAt: at(3)},
+		{name: "delay kept after a restart", restart: true, secret: "1234", now: at(2.5), wantRetryAt: at(3)},
+		{name: "right PIN after the delay", secret: "1234", now: at(3)},
+		{name: "failures reset by unlocking", restart: true, secret: "wrong", now: at(3), wantErr: ErrWrongPassphrase},
+		{name: "right passphrase", secret: "passphrase", now: at(3)},
+	}
+
+	newLocker := func() *Locker {
+		t.Helper()
+		l := NewLocker(time.Minute)
+		if err := l.LoadPIN(dir); err != nil {
+			t.Fatalf("LoadPIN: %v", err)
+		}
+		if err := l.SetPassphrase("passphrase"); err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+	l := newLocker()
+	if err := l.SetPIN(dir, "1234"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		if test.restart {
+			l = newLocker()
+		}
+		l.Lock()
+		err := l.Unlock(test.secret, test.now)
+
+		var throttled *UnlockThrottledError
+		switch {
+		case !test.wantRetryAt.IsZero():
+			if !errors.As(err, &throttled) || !throttled.RetryAt.Equal(test.wantRetryAt) {
+				t.Errorf("%s: Unlock returned %v, want to retry at %v", test.name, err, test.wantRetryAt)
+			}
+		case !errors.Is(err, test.wantErr):
+			t.Errorf("%s: Unlock returned %v, want %v", test.name, err, test.wantErr)
+		}
+		if locked := err != nil; l.Locked() != locked {
+			t.Errorf("%s: locked: %t, want %t", test.name, l.Locked(), locked)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, UnlockAttemptsFileName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the failed attempts were kept after unlocking: %v", err)
+	}
+}
+
+func TestLockIfIdle(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		passphrase bool
+		idle       time.Duration
+		want       bool
+	}{
+		{"idle for the timeout", 5 * time.Minute, true, 5 * time.Minute, true},
+		{"idle for longer", 5 * time.Minute, true, time.Hour, true},
+		{"active", 5 * time.Minute, true, 5*time.Minute - time.Second, false},
+		{"never locks", 0, true, time.Hour, false},
+		{"no passphrase", 5 * time.Minute, false, time.Hour, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l := NewLocker(test.timeout)
+			if test.passphrase {
+				if err := l.SetPassphrase("passphrase"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Touch ignores times before the last activity, which is
+			// when the Locker was created.
+			start := time.Now()
+			l.Touch(start)
+
+			if got := l.LockIfIdle(start.Add(test.idle)); got != test.want {
+				t.Errorf("LockIfIdle returned %t, want %t", got, test.want)
+			}
+			if l.Locked() != test.want {
+				t.Errorf("locked: %t, want %t", l.Locked(), test.want)
+			}
+			if test.want && l.LockIfIdle(start.Add(test.idle)) {
+				t.Error("LockIfIdle locked the app again")
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Mandate types.
+const (
+	MandateDirectDebit   = "direct_debit"
+	MandateStandingOrder = "standing_order"
+)
+
+// ErrMandatesUnsupported is returned by providers whose API does not list
+// direct debits and standing orders.
+var ErrMandatesUnsupported = errors.New("direct debits and standing orders are not supported")
+
+// Mandate is a direct debit mandate, which lets a payee collect money from an
+// account, or a standing order, which pays a payee on a schedule.
+type Mandate struct {
+	ID        string
+	AccountID string
+	// Type is MandateDirectDebit or MandateStandingOrder.
+	Type      string
+	Payee     string
+	Reference string
+	// LastCollection is when money was last paid, the zero time if it never
+	// was, and LastAmount how much.
+	LastCollection time.Time
+	LastAmount     Money
+	// NextCollection is when money is paid next, the zero time if it is not
+	// known. Amount is the amount of the next payment, zero if it is not known
+	// in advance as is usual for direct debits.
+	NextCollection time.Time
+	Amount         Money
+}
+
+// CancelMandate cancels a direct debit mandate or standing order of the
+// account, so that no more payments are made. The mandate is removed from
+// the account.
+func (w *Wallet) CancelMandate(accountID, mandateID string) error {
+	w.mtx.RLock()
+	account, i := w.findMandate(accountID, mandateID)
+	var mandate *Mandate
+	if account != nil {
+		mandate = account.Mandates[i]
+	}
+	w.mtx.RUnlock()
+	if mandate == nil {
+		return fmt.Errorf("mandate %s not found", mandateID)
+	}
+
+	provider, err := w.providerForAccount(account)
+	if err != nil {
+		return err
+	}
+	if err := provider.CancelMandate(mandate.Type, mandate.ID); err != nil {
+		return err
+	}
+
+	// The mandates are replaced rather than modified, since the UI may be
+	// reading them.
+	w.mtx.Lock()
+	if account, i := w.findMandate(accountID, mandateID); account != nil {
+		mandates := append([]*Mandate(nil), account.Mandates[:i]...)
+		account.Mandates = append(mandates, account.Mandates[i+1:]...)
+	}
+	w.mtx.Unlock()
+	return nil
+}
+
+// findMandate returns the account with the given ID and the index of the
+// mandate in its Mandates, or nil if either is not found. w.mtx must be held.
+func (w *Wallet) findMandate(accountID, mandateID string) (*Account, int) {
+	for _, account := range w.accounts {
+		if account.ID != accountID {
+			continue
+		}
+		for i, mandate := range account.Mandates {
+			if mandate.ID == mandateID {
+				return account, i
+			}
+		}
+	}
+	return nil, -1
+}
@@ -0,0 +1,215 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+var (
+	// ErrCurrencyMismatch is returned by arithmetic on amounts in different
+	// currencies.
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrAmountOverflow is returned if the result of arithmetic does not fit
+	// in an int64 of minor units.
+	ErrAmountOverflow = errors.New("amount overflow")
+)
+
+// defaultScale is the number of minor unit digits assumed for currencies
+// that are not known to golang.org/x/text/currency.
+const defaultScale = 2
+
+// Money is an amount in the minor units of a currency, e.g. pence for GBP,
+// as returned by the Monzo API.
+type Money struct {
+	Amount   int64  // in minor units
+	Currency string // ISO 4217 code, e.g. "GBP"
+}
+
+// NewMoney returns amount minor units of currency.
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// ParseMoney parses an amount in major units as returned by Decimal, e.g.
+// "-12.30", into Money in currency. Grouping and currency symbols are not
+// accepted, and the amount may not have more fractional digits than the
+// currency has minor units.
+func ParseMoney(s, currency string) (Money, error) {
+	m := NewMoney(0, currency)
+	digits := strings.TrimSpace(s)
+
+	sign := ""
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	whole, frac := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		whole, frac = digits[:i], digits[i+1:]
+	}
+
+	scale := m.Scale()
+	if whole == "" && frac == "" || len(frac) > scale || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	frac += strings.Repeat("0", scale-len(frac))
+
+	amount, err := strconv.ParseInt(sign+whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, ErrAmountOverflow
+	}
+	m.Amount = amount
+	return m, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Add returns m + o. Both amounts must be in the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Sub returns m - o. Both amounts must be in the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	neg, err := o.Neg()
+	if err != nil {
+		return Money{}, err
+	}
+	return m.Add(neg)
+}
+
+// Neg returns -m. The smallest int64 amount cannot be negated and returns
+// ErrAmountOverflow.
+func (m Money) Neg() (Money, error) {
+	if m.Amount == math.MinInt64 {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: -m.Amount, Currency: m.Currency}, nil
+}
+
+// Abs returns the absolute value of m, or ErrAmountOverflow like Neg.
+func (m Money) Abs() (Money, error) {
+	if m.Amount < 0 {
+		return m.Neg()
+	}
+	return m, nil
+}
+
+// Sign returns -1, 0 or 1 depending on whether m is negative, zero or
+// positive.
+func (m Money) Sign() int {
+	switch {
+	case m.Amount < 0:
+		return -1
+	case m.Amount > 0:
+		return 1
+	}
+	return 0
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Scale returns the number of minor unit digits of the currency, e.g. 2 for
+// GBP and 0 for JPY.
+func (m Money) Scale() int {
+	unit, err := currency.ParseISO(m.Currency)
+	if err != nil {
+		return defaultScale
+	}
+	scale, _ := currency.Standard.Rounding(unit)
+	return scale
+}
+
+// Decimal returns the amount in major units without grouping or currency,
+// e.g. "-12.30". It is exact and meant for machine readable output.
+func (m Money) Decimal() string {
+	digits := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+
+	scale := m.Scale()
+	if scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// String returns the amount followed by its currency code, e.g. "-12.30 GBP".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// Format returns the amount for display in the language of p, with the
+// currency symbol and digit grouping, e.g. "-£1,234.50".
+func (m Money) Format(p *message.Printer) string {
+	// The amount is formatted from its integer parts, as not every int64
+	// amount is exact as a float64.
+	sign, minor := "", uint64(m.Amount)
+	if m.Amount < 0 {
+		// Two's complement also negates the smallest int64 correctly.
+		sign, minor = "-", -minor
+	}
+
+	scale := m.Scale()
+	unit := uint64(1)
+	for i := 0; i < scale; i++ {
+		unit *= 10
+	}
+	amount := p.Sprint(number.Decimal(minor / unit))
+	if scale > 0 {
+		amount += decimalSeparator(p) +
+			p.Sprint(number.Decimal(minor%unit, number.MinIntegerDigits(scale), number.NoSeparator()))
+	}
+
+	cur, err := currency.ParseISO(m.Currency)
+	if err != nil {
+		return sign + amount + " " + m.Currency
+	}
+	return sign + p.Sprint(currency.NarrowSymbol(cur)) + amount
+}
+
+// decimalSeparator returns the decimal separator of the language of p, e.g.
+// "," for German.
+func decimalSeparator(p *message.Printer) string {
+	// Every language formats zero with one fractional digit as the same
+	// digit twice around the separator.
+	s := p.Sprint(number.Decimal(0, number.Scale(1)))
+	_, first := utf8.DecodeRuneInString(s)
+	_, last := utf8.DecodeLastRuneInString(s)
+	if first+last >= len(s) {
+		return "."
+	}
+	return s[first : len(s)-last]
+}
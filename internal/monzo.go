@@ -0,0 +1,560 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tjvr/go-monzo"
+)
+
+// MonzoBaseURL is the base URL of the production Monzo API.
+const MonzoBaseURL = "https://api.monzo.com"
+
+// MonzoTimeout is how long a request to the Monzo API may take, including
+// reading the response, so that an API that stalls does not hold up a sync.
+const MonzoTimeout = 30 * time.Second
+
+// monzoHTTPClient is the client of the Monzo providers that are not given
+// one.
+var monzoHTTPClient = &http.Client{Timeout: MonzoTimeout}
+
+// Monzo is a Provider backed by the Monzo API.
+type Monzo struct {
+	cl     *monzo.Client
+	client *http.Client
+}
+
+// NewMonzo returns a Monzo provider that talks to the API at baseURL using
+// accessToken. The production API is used if baseURL is empty. Requests time
+// out after MonzoTimeout.
+func NewMonzo(baseURL, accessToken string) *Monzo {
+	return NewMonzoWithClient(baseURL, accessToken, nil)
+}
+
+// NewMonzoWithClient is NewMonzo, making the requests with client, e.g. one
+// with a shorter timeout in tests. A nil client times out after
+// MonzoTimeout.
+func NewMonzoWithClient(baseURL, accessToken string, client *http.Client) *Monzo {
+	if baseURL == "" {
+		baseURL = MonzoBaseURL
+	}
+	if client == nil {
+		client = monzoHTTPClient
+	}
+	return &Monzo{
+		cl: &monzo.Client{
+			BaseURL:     baseURL,
+			AccessToken: accessToken,
+		},
+		client: client,
+	}
+}
+
+// MonzoProvider returns a ProviderFunc that creates Monzo providers for the
+// API at baseURL.
+func MonzoProvider(baseURL string) ProviderFunc {
+	return MonzoProviderWithClient(baseURL, nil)
+}
+
+// MonzoProviderWithClient is MonzoProvider, creating the providers with
+// NewMonzoWithClient.
+func MonzoProviderWithClient(baseURL string, client *http.Client) ProviderFunc {
+	return func(accessToken string) Provider {
+		return NewMonzoWithClient(baseURL, accessToken, client)
+	}
+}
+
+// Accounts returns the open accounts of the given type.
+// Part of the Provider interface.
+func (m *Monzo) Accounts(accountType string) (Accounts, error) {
+	args := url.Values{}
+	if accountType != "" {
+		args.Set("account_type", accountType)
+	}
+	rsp := &struct {
+		Accounts []*monzo.Account `json:"accounts"`
+	}{}
+	if err := m.get("/accounts", args, rsp); err != nil {
+		return nil, apiError(err)
+	}
+
+	accounts := make(Accounts, 0, len(rsp.Accounts))
+	for _, account := range rsp.Accounts {
+		if account.Closed {
+			continue
+		}
+		accounts = append(accounts, &Account{
+			ID:            account.ID,
+			Created:       account.Created,
+			AccountNumber: account.AccountNumber,
+			SortCode:      account.SortCode,
+		})
+	}
+	return accounts, nil
+}
+
+// Balance returns the current balance of the account.
+// Part of the Provider interface.
+func (m *Monzo) Balance(accountID string) (*Balance, error) {
+	balance := &monzo.Balance{}
+	if err := m.get("/balance", url.Values{"account_id": {accountID}}, balance); err != nil {
+		return nil, apiError(err)
+	}
+
+	return &Balance{
+		Balance:    NewMoney(balance.Balance, balance.Currency),
+		SpendToday: NewMoney(balance.SpendToday, balance.Currency),
+	}, nil
+}
+
+// transactionsPageSize is the largest page the transactions endpoint returns.
+const transactionsPageSize = 100
+
+// Transactions returns the transactions of the account created after the
+// transaction with ID since, oldest first, or the full history if since is
+// empty. go-monzo cannot page through the history, so the requests are made
+// directly.
+// Part of the Provider interface.
+func (m *Monzo) Transactions(accountID, since string) ([]*Transaction, error) {
+	// Fix the end of the range so that transactions created while paging
+	// do not shift the pages; they are picked up by the next sync.
+	before := time.Now().UTC().Format(time.RFC3339)
+
+	var transactions []*Transaction
+	for {
+		args := url.Values{
+			"account_id": {accountID},
+			"expand[]":   {"merchant"},
+			"before":     {before},
+			"limit":      {strconv.Itoa(transactionsPageSize)},
+		}
+		if since != "" {
+			args.Set("since", since)
+		}
+
+		rsp := &struct {
+			Transactions []*monzoTransaction `json:"transactions"`
+		}{}
+		if err := m.get("/transactions", args, rsp); err != nil {
+			return nil, apiError(err)
+		}
+
+		for _, transaction := range rsp.Transactions {
+			transactions = append(transactions, transaction.toTransaction())
+		}
+
+		if len(rsp.Transactions) < transactionsPageSize {
+			return transactions, nil
+		}
+		since = rsp.Transactions[len(rsp.Transactions)-1].ID
+	}
+}
+
+// monzoTransaction is a transaction as returned by the API. go-monzo does not
+// decode counterparties and attachments, and fails on merchants that are not
+// expanded, so transactions are decoded here.
+type monzoTransaction struct {
+	ID            string          `json:"id"`
+	Created       string          `json:"created"`
+	Settled       string          `json:"settled"`
+	DeclineReason string          `json:"decline_reason"`
+	Amount        int64           `json:"amount"`
+	Currency      string          `json:"currency"`
+	LocalAmount   int64           `json:"local_amount"`
+	LocalCurrency string          `json:"local_currency"`
+	Description   string          `json:"description"`
+	Category      string          `json:"category"`
+	Notes         string          `json:"notes"`
+	Merchant      json.RawMessage `json:"merchant"`
+	Counterparty  *struct {
+		Name          string `json:"name"`
+		PreferredName string `json:"preferred_name"`
+		UserID        string `json:"user_id"`
+		SortCode      string `json:"sort_code"`
+		AccountNumber string `json:"account_number"`
+	} `json:"counterparty"`
+	Attachments []*struct {
+		ID       string `json:"id"`
+		Created  string `json:"created"`
+		FileType string `json:"file_type"`
+		FileURL  string `json:"file_url"`
+	} `json:"attachments"`
+}
+
+// monzoMerchant is an expanded merchant as returned by the API.
+type monzoMerchant struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Logo     string `json:"logo"`
+	Online   bool   `json:"online"`
+	Address  *struct {
+		Address   string  `json:"address"`
+		City      string  `json:"city"`
+		Region    string  `json:"region"`
+		Country   string  `json:"country"`
+		Postcode  string  `json:"postcode"`
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	} `json:"address"`
+}
+
+// toTransaction maps the API transaction to a Transaction. Missing or
+// malformed optional fields are left empty rather than failing the sync.
+func (t *monzoTransaction) toTransaction() *Transaction {
+	transaction := &Transaction{
+		ID:            t.ID,
+		Created:       parseTime(t.Created),
+		Settled:       parseTime(t.Settled),
+		DeclineReason: t.DeclineReason,
+		Amount:        NewMoney(t.Amount, t.Currency),
+		LocalAmount:   NewMoney(t.LocalAmount, t.LocalCurrency),
+		Description:   t.Description,
+		Category:      t.Category,
+		Notes:         t.Notes,
+	}
+
+	switch {
+	case t.DeclineReason != "":
+		transaction.Status = TransactionDeclined
+		transaction.Settled = time.Time{}
+	case transaction.Settled.IsZero():
+		transaction.Status = TransactionPending
+	default:
+		transaction.Status = TransactionSettled
+	}
+
+	if t.LocalCurrency == "" {
+		transaction.LocalAmount = transaction.Amount
+	}
+
+	// The merchant is an object when expanded, otherwise its ID or null.
+	var merchant monzoMerchant
+	if err := json.Unmarshal(t.Merchant, &merchant); err == nil && merchant.ID != "" {
+		transaction.Merchant = &Merchant{
+			ID:       merchant.ID,
+			Name:     merchant.Name,
+			Category: merchant.Category,
+			LogoURL:  merchant.Logo,
+			Online:   merchant.Online,
+		}
+		if a := merchant.Address; a != nil {
+			transaction.Merchant.Address = &Address{
+				Address:   a.Address,
+				City:      a.City,
+				Region:    a.Region,
+				Country:   a.Country,
+				Postcode:  a.Postcode,
+				Latitude:  a.Latitude,
+				Longitude: a.Longitude,
+			}
+		}
+	} else if id := ""; json.Unmarshal(t.Merchant, &id) == nil && id != "" {
+		transaction.Merchant = &Merchant{ID: id}
+	}
+
+	// The API returns an empty object rather than null for card payments.
+	if c := t.Counterparty; c != nil && (c.Name != "" || c.UserID != "" || c.AccountNumber != "") {
+		name := c.PreferredName
+		if name == "" {
+			name = c.Name
+		}
+		transaction.Counterparty = &Counterparty{
+			Name:          name,
+			UserID:        c.UserID,
+			SortCode:      c.SortCode,
+			AccountNumber: c.AccountNumber,
+		}
+	}
+
+	for _, a := range t.Attachments {
+		if a == nil {
+			continue
+		}
+		transaction.Attachments = append(transaction.Attachments, &Attachment{
+			ID:       a.ID,
+			Created:  parseTime(a.Created),
+			FileType: a.FileType,
+			URL:      a.FileURL,
+		})
+	}
+
+	return transaction
+}
+
+// parseTime parses an RFC 3339 time from the API, returning the zero time if
+// s is empty or malformed.
+func parseTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Pots returns the pots that belong to the account. The pots endpoint of
+// go-monzo predates the current_account_id parameter and does not decode
+// goals, so the request is made directly.
+// Part of the Provider interface.
+func (m *Monzo) Pots(accountID string) ([]*Pot, error) {
+	rsp := &struct {
+		Pots []*monzoPot `json:"pots"`
+	}{}
+	if err := m.get("/pots", url.Values{"current_account_id": {accountID}}, rsp); err != nil {
+		return nil, apiError(err)
+	}
+
+	pots := make([]*Pot, 0, len(rsp.Pots))
+	for _, pot := range rsp.Pots {
+		if pot.Deleted {
+			continue
+		}
+		pots = append(pots, pot.toPot())
+	}
+	return pots, nil
+}
+
+// Deposit moves amount from the account into the pot.
+// Part of the Provider interface.
+func (m *Monzo) Deposit(potID, accountID string, amount Money, dedupeID string) (*Pot, error) {
+	return m.transfer("/pots/"+url.PathEscape(potID)+"/deposit", url.Values{
+		"source_account_id": {accountID},
+		"amount":            {strconv.FormatInt(amount.Amount, 10)},
+		"dedupe_id":         {dedupeID},
+	})
+}
+
+// Withdraw moves amount from the pot into the account.
+// Part of the Provider interface.
+func (m *Monzo) Withdraw(potID, accountID string, amount Money, dedupeID string) (*Pot, error) {
+	return m.transfer("/pots/"+url.PathEscape(potID)+"/withdraw", url.Values{
+		"destination_account_id": {accountID},
+		"amount":                 {strconv.FormatInt(amount.Amount, 10)},
+		"dedupe_id":              {dedupeID},
+	})
+}
+
+// transfer makes a pot deposit or withdrawal request and returns the updated
+// pot.
+func (m *Monzo) transfer(path string, args url.Values) (*Pot, error) {
+	pot := &monzoPot{}
+	if err := m.request(http.MethodPut, path, args, pot); err != nil {
+		return nil, apiError(err)
+	}
+	return pot.toPot(), nil
+}
+
+// monzoPot is a pot as returned by the API.
+type monzoPot struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Style      string `json:"style"`
+	Balance    int64  `json:"balance"`
+	Currency   string `json:"currency"`
+	GoalAmount int64  `json:"goal_amount"`
+	Locked     bool   `json:"locked"`
+	Deleted    bool   `json:"deleted"`
+}
+
+func (p *monzoPot) toPot() *Pot {
+	return &Pot{
+		ID:      p.ID,
+		Name:    p.Name,
+		Style:   p.Style,
+		Balance: NewMoney(p.Balance, p.Currency),
+		Goal:    NewMoney(p.GoalAmount, p.Currency),
+		Locked:  p.Locked,
+	}
+}
+
+// Mandates returns the direct debit mandates and standing orders of the
+// account. They are listed by separate endpoints, which are not available to
+// every client; a 403 or 404 response is returned as
+// ErrMandatesUnsupported.
+// Part of the Provider interface.
+func (m *Monzo) Mandates(accountID string) ([]*Mandate, error) {
+	args := url.Values{"account_id": {accountID}}
+	directDebits := &struct {
+		DirectDebits []*monzoMandate `json:"direct_debits"`
+	}{}
+	if err := m.get("/direct_debits", args, directDebits); err != nil {
+		return nil, mandatesError(err)
+	}
+	standingOrders := &struct {
+		StandingOrders []*monzoMandate `json:"standing_orders"`
+	}{}
+	if err := m.get("/standing_orders", args, standingOrders); err != nil {
+		return nil, mandatesError(err)
+	}
+
+	mandates := make([]*Mandate, 0, len(directDebits.DirectDebits)+len(standingOrders.StandingOrders))
+	for _, mandate := range directDebits.DirectDebits {
+		if mandate.Status == "active" {
+			mandates = append(mandates, mandate.toMandate(MandateDirectDebit))
+		}
+	}
+	for _, mandate := range standingOrders.StandingOrders {
+		if mandate.Status == "active" {
+			mandates = append(mandates, mandate.toMandate(MandateStandingOrder))
+		}
+	}
+	return mandates, nil
+}
+
+// CancelMandate cancels a direct debit mandate or standing order.
+// Part of the Provider interface.
+func (m *Monzo) CancelMandate(mandateType, mandateID string) error {
+	path := "/direct_debits/"
+	if mandateType == MandateStandingOrder {
+		path = "/standing_orders/"
+	}
+	var rsp struct{}
+	if err := m.request(http.MethodDelete, path+url.PathEscape(mandateID), nil, &rsp); err != nil {
+		return apiError(err)
+	}
+	return nil
+}
+
+// monzoMandate is a direct debit mandate or standing order as returned by
+// the API.
+type monzoMandate struct {
+	ID             string `json:"id"`
+	AccountID      string `json:"account_id"`
+	Status         string `json:"status"`
+	PayeeName      string `json:"payee_name"`
+	Reference      string `json:"reference"`
+	Currency       string `json:"currency"`
+	LastCollected  string `json:"last_collected"`
+	LastAmount     int64  `json:"last_amount"`
+	NextCollection string `json:"next_collection"`
+	NextAmount     int64  `json:"next_amount"`
+}
+
+func (m *monzoMandate) toMandate(mandateType string) *Mandate {
+	return &Mandate{
+		ID:             m.ID,
+		AccountID:      m.AccountID,
+		Type:           mandateType,
+		Payee:          m.PayeeName,
+		Reference:      m.Reference,
+		LastCollection: parseTime(m.LastCollected),
+		LastAmount:     NewMoney(m.LastAmount, m.Currency),
+		NextCollection: parseTime(m.NextCollection),
+		Amount:         NewMoney(m.NextAmount, m.Currency),
+	}
+}
+
+// mandatesError returns ErrMandatesUnsupported for responses that show the
+// mandate endpoints are not available.
+func mandatesError(err error) error {
+	var apiErr *monzo.APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusForbidden || apiErr.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("%w: %v", ErrMandatesUnsupported, err)
+	}
+	return apiError(err)
+}
+
+// Webhooks returns the webhooks registered for the account.
+// Part of the Provider interface.
+func (m *Monzo) Webhooks(accountID string) ([]*Webhook, error) {
+	rsp := &struct {
+		Webhooks []*Webhook `json:"webhooks"`
+	}{}
+	if err := m.get("/webhooks", url.Values{"account_id": {accountID}}, rsp); err != nil {
+		return nil, apiError(err)
+	}
+	return rsp.Webhooks, nil
+}
+
+// RegisterWebhook registers a webhook for the account.
+// Part of the Provider interface.
+func (m *Monzo) RegisterWebhook(accountID, webhookURL string) (*Webhook, error) {
+	rsp := &struct {
+		Webhook *Webhook `json:"webhook"`
+	}{}
+	args := url.Values{"account_id": {accountID}, "url": {webhookURL}}
+	if err := m.request(http.MethodPost, "/webhooks", args, rsp); err != nil {
+		return nil, apiError(err)
+	}
+	if rsp.Webhook == nil {
+		return nil, errors.New("webhook missing in response")
+	}
+	return rsp.Webhook, nil
+}
+
+// DeleteWebhook removes the webhook.
+// Part of the Provider interface.
+func (m *Monzo) DeleteWebhook(webhookID string) error {
+	var rsp struct{}
+	if err := m.request(http.MethodDelete, "/webhooks/"+url.PathEscape(webhookID), nil, &rsp); err != nil {
+		return apiError(err)
+	}
+	return nil
+}
+
+// get performs an authenticated GET request against the API and decodes the
+// JSON response into out.
+func (m *Monzo) get(path string, args url.Values, out interface{}) error {
+	return m.request(http.MethodGet, path, args, out)
+}
+
+// request performs an authenticated request against the API and decodes the
+// JSON response into out. args are sent in the query string of GET requests
+// and as a form otherwise. Non-200 responses are returned as *monzo.APIError
+// to match the errors returned by go-monzo, also if their body is not an API
+// error.
+func (m *Monzo) request(method, path string, args url.Values, out interface{}) error {
+	var body io.Reader
+	if method != http.MethodGet {
+		body = strings.NewReader(args.Encode())
+	}
+	req, err := http.NewRequest(method, m.cl.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if method == http.MethodGet {
+		req.URL.RawQuery = args.Encode()
+	} else {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", m.cl.AccessToken))
+
+	rsp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	data, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		return err
+	}
+
+	if rsp.StatusCode != http.StatusOK {
+		apiErr := &monzo.APIError{StatusCode: rsp.StatusCode}
+		if err := json.Unmarshal(data, apiErr); err != nil {
+			apiErr.Code = "unexpected_response"
+			apiErr.Message = fmt.Sprintf("%d %s", rsp.StatusCode, http.StatusText(rsp.StatusCode))
+		}
+		return apiErr
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// apiError marks errors caused by a rejected access token with
+// ErrTokenRevoked so callers can tell them apart from transient failures.
+func apiError(err error) error {
+	var apiErr *monzo.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("%w: %v", ErrTokenRevoked, err)
+	}
+	return err
+}
@@ -0,0 +1,259 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// LoginTimeout is how long Connect waits for the browser to redirect back
+// before giving up.
+const LoginTimeout = 5 * time.Minute
+
+// defaultCallbackPath is used for the loopback redirect URL if the OAuth
+// config does not specify a path.
+const defaultCallbackPath = "/callback"
+
+var (
+	// ErrLoginTimeout is returned by Connect if the browser did not redirect
+	// back within LoginTimeout, e.g. because the user closed it.
+	ErrLoginTimeout = errors.New("login timed out")
+	// ErrLoginCancelled is returned by Connect if its context was cancelled.
+	ErrLoginCancelled = errors.New("login cancelled")
+	// ErrStateMismatch is returned by Connect if the redirect does not carry
+	// the state sent with the authorization request.
+	ErrStateMismatch = errors.New("login response does not match the login request")
+)
+
+// LoginError is returned by Connect if the authorization server redirected
+// back with an error, e.g. because the user denied access.
+type LoginError struct {
+	Code        string
+	Description string
+}
+
+func (e *LoginError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("login failed: %s: %s", e.Code, e.Description)
+	}
+	return fmt.Sprintf("login failed: %s", e.Code)
+}
+
+type callbackResult struct {
+	code string
+	// link is the monzo-wallet:// link passed in the link parameter of the
+	// redirect URL, if any.
+	link string
+	err  error
+}
+
+var callbackPage = template.Must(template.New("callback").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Monzo Wallet</title></head>
+<body style="font-family: sans-serif; text-align: center; margin-top: 15%;">
+<h2>{{.Title}}</h2>
+<p>{{.Message}}</p>
+</body>
+</html>
+`))
+
+// Connect logs in through the user's browser using the authorization code
+// flow with PKCE. The redirect is received by a listener bound to a random
+// free port on the loopback interface, so the OAuth client must accept
+// loopback redirect URLs on any port. Connect returns ErrLoginTimeout,
+// ErrLoginCancelled, ErrStateMismatch or a *LoginError if no code could be
+// obtained.
+//
+// The redirect URL may have a link parameter with a monzo-wallet:// link,
+// e.g. http://127.0.0.1/callback?link=monzo-wallet%3A%2F%2Fbudgets, which
+// is kept for TakeDeepLink once the user is logged in.
+func (w *Wallet) Connect(ctx context.Context, conf *oauth2.Config) (*oauth2.Token, error) {
+	return w.ConnectWith(ctx, conf, openbrowser)
+}
+
+// ConnectWith is Connect, calling open with the authorization URL instead of
+// opening it in the default browser. It lets the login be completed on
+// machines without a display, e.g. by showing the URL to a user connected
+// over SSH with the callback port forwarded.
+func (w *Wallet) ConnectWith(ctx context.Context, conf *oauth2.Config, open func(authURL string) error) (*oauth2.Token, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	callbackPath, callbackQuery := defaultCallbackPath, ""
+	if redirectURL, err := url.Parse(conf.RedirectURL); err == nil {
+		if redirectURL.Path != "" && redirectURL.Path != "/" {
+			callbackPath = redirectURL.Path
+		}
+		if redirectURL.RawQuery != "" {
+			callbackQuery = "?" + redirectURL.RawQuery
+		}
+	}
+
+	loopbackConf := *conf
+	loopbackConf.RedirectURL = fmt.Sprintf("http://%s%s%s", listener.Addr(), callbackPath, callbackQuery)
+
+	state := generateRandomState()
+	verifier, challenge := generatePKCE()
+
+	// Buffered so the first callback never blocks; later ones are dropped.
+	resultCh := make(chan callbackResult, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, func(w http.ResponseWriter, r *http.Request) {
+		result := handleCallback(r, state)
+		select {
+		case resultCh <- result:
+		default:
+		}
+		writeCallbackPage(w, result.err)
+	})
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logrus.Info(err)
+		}
+	}()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logrus.Info(err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, LoginTimeout)
+	defer cancel()
+
+	authURL := loopbackConf.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("code_challenge", challenge),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+	if err := open(authURL); err != nil {
+		return nil, err
+	}
+
+	var result callbackResult
+	select {
+	case result = <-resultCh:
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, ErrLoginTimeout
+		}
+		return nil, ErrLoginCancelled
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+
+	token, err := loopbackConf.Exchange(ctx, result.code, oauth2.SetAuthURLParam("code_verifier", verifier))
+	if err != nil {
+		return nil, err
+	}
+	if result.link != "" {
+		w.mtx.Lock()
+		w.deepLink = result.link
+		w.mtx.Unlock()
+	}
+	return token, nil
+}
+
+// handleCallback validates the redirect from the authorization server and
+// extracts the authorization code.
+func handleCallback(r *http.Request, state string) callbackResult {
+	query := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 {
+		return callbackResult{err: ErrStateMismatch}
+	}
+	if code := query.Get("error"); code != "" {
+		return callbackResult{err: &LoginError{
+			Code:        code,
+			Description: query.Get("error_description"),
+		}}
+	}
+	code := query.Get("code")
+	if code == "" {
+		return callbackResult{err: &LoginError{Code: "missing_code"}}
+	}
+	result := callbackResult{code: code}
+	if link := query.Get("link"); IsDeepLink(link) {
+		result.link = link
+	}
+	return result
+}
+
+func writeCallbackPage(w http.ResponseWriter, err error) {
+	data := struct{ Title, Message string }{
+		Title:   "Logged in",
+		Message: "You can close this window and return to Monzo Wallet.",
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err != nil {
+		data.Title = "Login failed"
+		data.Message = err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	if err := callbackPage.Execute(w, data); err != nil {
+		logrus.Info(err)
+	}
+}
+
+func openbrowser(url string) error {
+	var err error
+
+	switch runtime.GOOS {
+	case "linux":
+		err = exec.Command("xdg-open", url).Start()
+	case "windows":
+		err = exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	case "darwin":
+		err = exec.Command("open", url).Start()
+	default:
+		err = fmt.Errorf("unsupported platform")
+	}
+
+	return err
+}
+
+func generateRandomState() string {
+	randomBytes := make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(randomBytes)
+}
+
+// generatePKCE returns a PKCE code verifier and its S256 code challenge.
+func generatePKCE() (verifier, challenge string) {
+	randomBytes := make([]byte, 32)
+	if _, err := rand.Read(randomBytes); err != nil {
+		panic(err)
+	}
+
+	verifier = base64.RawURLEncoding.EncodeToString(randomBytes)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// ProfilesFileName is the name of the file in AppDataDir that lists the
+// profiles.
+const ProfilesFileName = "profiles.json"
+
+// DefaultProfileID is the ID of the profile of the first login. Its token is
+// kept in TokenFileName.
+const DefaultProfileID = "default"
+
+// Account types that can be requested from the provider.
+const (
+	AccountTypeRetail   = "uk_retail"
+	AccountTypeJoint    = "uk_retail_joint"
+	AccountTypeBusiness = "uk_business"
+)
+
+// AccountTypes lists the account types in the order they are shown.
+var AccountTypes = []string{AccountTypeRetail, AccountTypeJoint, AccountTypeBusiness}
+
+// Profile is a Monzo login, e.g. a personal one and a company's. Each profile
+// has its own token and loads the accounts of its AccountTypes.
+type Profile struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	AccountTypes []string `json:"account_types"`
+}
+
+// DefaultProfile returns the profile used until others are added.
+func DefaultProfile() *Profile {
+	return &Profile{
+		ID:           DefaultProfileID,
+		Name:         "Personal",
+		AccountTypes: []string{AccountTypeRetail, AccountTypeJoint},
+	}
+}
+
+// NewProfile returns a profile with a new random ID.
+func NewProfile(name string, accountTypes []string) (*Profile, error) {
+	if name == "" {
+		return nil, errors.New("missing profile name")
+	}
+	if len(accountTypes) == 0 {
+		return nil, errors.New("no account types selected")
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &Profile{
+		ID:           hex.EncodeToString(b),
+		Name:         name,
+		AccountTypes: accountTypes,
+	}, nil
+}
+
+// TokenFileName returns the name of the token store of the profile in
+// AppDataDir.
+func (p *Profile) TokenFileName() string {
+	if p.ID == DefaultProfileID {
+		return TokenFileName
+	}
+	return "token-" + p.ID + ".enc"
+}
+
+// ProfileError is the error of a profile whose accounts cannot be loaded,
+// e.g. because its login expired, see ProfileErrors.
+type ProfileError struct {
+	Profile *Profile
+	Err     error
+}
+
+func (e *ProfileError) Error() string {
+	return fmt.Sprintf("profile %s: %v", e.Profile.Name, e.Err)
+}
+
+func (e *ProfileError) Unwrap() error {
+	return e.Err
+}
+
+// ProfileErrors is returned by FetchAccounts when the accounts of some
+// profiles cannot be loaded, with an error for each of them. errors.Is and
+// errors.As match any of the errors.
+type ProfileErrors []*ProfileError
+
+func (e ProfileErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e ProfileErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e ProfileErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Revoked returns the profiles that need a new login, as their token was
+// revoked or has expired.
+func (e ProfileErrors) Revoked() []*Profile {
+	var profiles []*Profile
+	for _, err := range e {
+		if errors.Is(err, ErrTokenRevoked) {
+			profiles = append(profiles, err.Profile)
+		}
+	}
+	return profiles
+}
+
+// Failed returns the error of the profile with the given ID, or nil if its
+// accounts were loaded.
+func (e ProfileErrors) Failed(profileID string) *ProfileError {
+	for _, err := range e {
+		if err.Profile.ID == profileID {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadProfiles reads the profiles from ProfilesFileName in dir. Only the
+// default profile is returned if the file does not exist yet.
+func LoadProfiles(dir string) ([]*Profile, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ProfilesFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Profile{DefaultProfile()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []*Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", ProfilesFileName, err)
+	}
+	if len(profiles) == 0 {
+		return []*Profile{DefaultProfile()}, nil
+	}
+	return profiles, nil
+}
+
+// SaveProfiles writes profiles to ProfilesFileName in dir.
+func SaveProfiles(dir string, profiles []*Profile) error {
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Written to a temporary file first so that a crash cannot leave a
+	// truncated list behind.
+	path := filepath.Join(dir, ProfilesFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// OpenProfileTokenStore unlocks the token store of the profile in dir with
+// passphrase, see OpenTokenStore.
+func OpenProfileTokenStore(dir string, profile *Profile, passphrase string) (*TokenStore, error) {
+	return OpenTokenStore(filepath.Join(dir, profile.TokenFileName()), passphrase)
+}
+
+// RemoveProfileToken deletes the token store of the profile in dir.
+func RemoveProfileToken(dir string, profile *Profile) error {
+	err := os.Remove(filepath.Join(dir, profile.TokenFileName()))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// UnlockProfiles authenticates the wallet for each of its profiles other than
+// the default one, with the tokens of their token stores in dir. The stores
+// are opened with the passphrase of the default one. Profiles without a
+// stored token are skipped and returned, so that the caller can log in again.
+func (w *Wallet) UnlockProfiles(ctx context.Context, dir string, conf *oauth2.Config, passphrase string) ([]*Profile, error) {
+	var missing []*Profile
+	for _, profile := range w.Profiles() {
+		if profile.ID == DefaultProfileID {
+			continue
+		}
+
+		store, err := OpenProfileTokenStore(dir, profile, passphrase)
+		if err != nil {
+			return nil, &ProfileError{Profile: profile, Err: err}
+		}
+		token, err := store.Load()
+		if errors.Is(err, ErrNoToken) {
+			missing = append(missing, profile)
+			continue
+		}
+		if err != nil {
+			return nil, &ProfileError{Profile: profile, Err: err}
+		}
+		w.AuthenticateProfile(profile.ID, store.TokenSource(ctx, conf, token))
+	}
+	return missing, nil
+}
@@ -0,0 +1,43 @@
+package internal
+
+// Provider is implemented by the banking backends a Wallet loads its data
+// from. Implementations are expected to be authenticated already; see
+// ProviderFunc.
+type Provider interface {
+	// Accounts returns the open accounts of the given type, e.g. "uk_retail".
+	Accounts(accountType string) (Accounts, error)
+	// Balance returns the current balance of the account with the given ID.
+	Balance(accountID string) (*Balance, error)
+	// Transactions returns the transactions of the account with the given
+	// ID that were created after the transaction with ID since, oldest
+	// first. The full history is returned if since is empty.
+	Transactions(accountID, since string) ([]*Transaction, error)
+	// Pots returns the pots that belong to the account with the given ID.
+	Pots(accountID string) ([]*Pot, error)
+	// Deposit moves amount from the account with the given ID into the pot
+	// and returns the updated pot. Requests repeated with the same dedupeID
+	// are only carried out once.
+	Deposit(potID, accountID string, amount Money, dedupeID string) (*Pot, error)
+	// Withdraw moves amount from the pot into the account with the given ID
+	// and returns the updated pot. Requests repeated with the same dedupeID
+	// are only carried out once.
+	Withdraw(potID, accountID string, amount Money, dedupeID string) (*Pot, error)
+	// Mandates returns the direct debit mandates and standing orders of the
+	// account with the given ID, or ErrMandatesUnsupported.
+	Mandates(accountID string) ([]*Mandate, error)
+	// CancelMandate cancels the direct debit mandate or standing order with
+	// the given type and ID.
+	CancelMandate(mandateType, mandateID string) error
+	// Webhooks returns the webhooks registered for the account with the
+	// given ID.
+	Webhooks(accountID string) ([]*Webhook, error)
+	// RegisterWebhook makes the API post the events of the account with the
+	// given ID to url.
+	RegisterWebhook(accountID, url string) (*Webhook, error)
+	// DeleteWebhook removes the webhook with the given ID.
+	DeleteWebhook(webhookID string) error
+}
+
+// ProviderFunc creates a Provider that is authenticated with the given OAuth
+// access token.
+type ProviderFunc func(accessToken string) Provider
@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Direction selects transactions by the sign of their amount.
+type Direction int
+
+const (
+	AnyDirection Direction = iota
+	Incoming               // positive amounts, e.g. salary or refunds
+	Outgoing               // negative amounts, e.g. card payments
+)
+
+// SortOrder is the order of the transactions returned by a query.
+type SortOrder int
+
+const (
+	NewestFirst SortOrder = iota
+	OldestFirst
+)
+
+// TransactionQuery selects, orders and pages transactions. The zero value
+// matches all transactions, newest first.
+type TransactionQuery struct {
+	// Text matches transactions whose merchant, counterparty, description
+	// or notes contain it, ignoring case.
+	Text string
+	// From and To limit the creation time to [From, To). Zero values leave
+	// the range open.
+	From time.Time
+	To   time.Time
+	// MinAmount and MaxAmount limit the absolute amount in minor units,
+	// inclusive, if they are not nil.
+	MinAmount *int64
+	MaxAmount *int64
+	// Category matches transactions of the category, e.g. "groceries", if
+	// it is not empty.
+	Category  string
+	Direction Direction
+	Sort      SortOrder
+	// Offset skips the first matches and Limit caps the number of
+	// transactions returned if it is greater than zero.
+	Offset int
+	Limit  int
+}
+
+// QueryResult is a page of transactions matching a TransactionQuery.
+type QueryResult struct {
+	Transactions []*Transaction
+	// Total is the number of matching transactions on all pages.
+	Total int
+}
+
+// Matches reports whether the transaction satisfies the filters of q.
+func (q *TransactionQuery) Matches(transaction *Transaction) bool {
+	if !q.From.IsZero() && transaction.Created.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !transaction.Created.Before(q.To) {
+		return false
+	}
+
+	switch q.Direction {
+	case Incoming:
+		if transaction.Amount.Sign() <= 0 {
+			return false
+		}
+	case Outgoing:
+		if transaction.Amount.Sign() >= 0 {
+			return false
+		}
+	}
+
+	abs, err := transaction.Amount.Abs()
+	if err != nil {
+		return false
+	}
+	if q.MinAmount != nil && abs.Amount < *q.MinAmount {
+		return false
+	}
+	if q.MaxAmount != nil && abs.Amount > *q.MaxAmount {
+		return false
+	}
+
+	if q.Category != "" && transaction.Category != q.Category {
+		return false
+	}
+
+	return q.Text == "" || matchesText(transaction, strings.ToLower(strings.TrimSpace(q.Text)))
+}
+
+func matchesText(transaction *Transaction, text string) bool {
+	fields := []string{transaction.Description, transaction.Notes}
+	if transaction.Merchant != nil {
+		fields = append(fields, transaction.Merchant.Name)
+	}
+	if transaction.Counterparty != nil {
+		fields = append(fields, transaction.Counterparty.Name)
+	}
+
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Query returns the transactions of the account that match q.
+func (a *Account) Query(q TransactionQuery) QueryResult {
+	return QueryTransactions(a.Transactions, q)
+}
+
+// QueryTransactions returns the transactions that match q, ordered and paged
+// as q requests. transactions is not modified.
+func QueryTransactions(transactions []*Transaction, q TransactionQuery) QueryResult {
+	var matches []*Transaction
+	for _, transaction := range transactions {
+		if q.Matches(transaction) {
+			matches = append(matches, transaction)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if q.Sort == OldestFirst {
+			return matches[i].Created.Before(matches[j].Created)
+		}
+		return matches[i].Created.After(matches[j].Created)
+	})
+
+	result := QueryResult{Total: len(matches)}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Offset >= len(matches) {
+		return result
+	}
+	matches = matches[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(matches) {
+		matches = matches[:q.Limit]
+	}
+	result.Transactions = matches
+	return result
+}
+
+// Categories returns the distinct categories of the transactions, sorted.
+func Categories(transactions []*Transaction) []string {
+	seen := make(map[string]bool)
+	var categories []string
+	for _, transaction := range transactions {
+		if transaction.Category != "" && !seen[transaction.Category] {
+			seen[transaction.Category] = true
+			categories = append(categories, transaction.Category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
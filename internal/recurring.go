@@ -0,0 +1,332 @@
+package internal
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recurringAmountTolerance is how much a payment may differ from the
+// previous payment of a series, as a fraction, e.g. for a bill that varies
+// from month to month.
+const recurringAmountTolerance = 0.2
+
+// Cadence is how often a recurring payment is taken.
+type Cadence int
+
+const (
+	CadenceWeekly Cadence = iota
+	CadenceFortnightly
+	CadenceMonthly
+	CadenceQuarterly
+	CadenceYearly
+)
+
+// cadences are the cadences that payments are matched against, with the
+// average number of days between payments and by how many days a payment
+// may be early or late, e.g. as it falls on a weekend.
+var cadences = []struct {
+	cadence   Cadence
+	days      float64
+	tolerance float64
+	// min is the number of payments needed to detect the cadence.
+	min int
+}{
+	{CadenceWeekly, 7, 1.5, 3},
+	{CadenceFortnightly, 14, 2, 3},
+	{CadenceMonthly, 30.44, 4, 3},
+	{CadenceQuarterly, 91.31, 7, 3},
+	{CadenceYearly, 365.25, 10, 2},
+}
+
+func (c Cadence) String() string {
+	switch c {
+	case CadenceWeekly:
+		return "weekly"
+	case CadenceFortnightly:
+		return "fortnightly"
+	case CadenceMonthly:
+		return "monthly"
+	case CadenceQuarterly:
+		return "quarterly"
+	case CadenceYearly:
+		return "yearly"
+	}
+	return "unknown"
+}
+
+// after returns when the payment after one at t is due.
+func (c Cadence) after(t time.Time) time.Time {
+	switch c {
+	case CadenceWeekly:
+		return t.AddDate(0, 0, 7)
+	case CadenceFortnightly:
+		return t.AddDate(0, 0, 14)
+	case CadenceQuarterly:
+		return t.AddDate(0, 3, 0)
+	case CadenceYearly:
+		return t.AddDate(1, 0, 0)
+	}
+	return t.AddDate(0, 1, 0)
+}
+
+// RecurringStatus is whether a recurring payment is still being taken.
+type RecurringStatus int
+
+const (
+	RecurringActive  RecurringStatus = iota
+	RecurringMissing                 // the next payment is overdue
+	RecurringEnded                   // a whole period has passed without a payment
+)
+
+// RecurringPayment is a series of outgoing payments to the same merchant or
+// payee at a regular cadence, e.g. a subscription, rent or a direct debit.
+type RecurringPayment struct {
+	AccountID string
+	// Merchant is the title of the payments, see Transaction.Title.
+	Merchant string
+	Category string
+	Cadence  Cadence
+	// Transactions are the payments of the series, oldest first.
+	Transactions []*Transaction
+	// Amount is the positive amount of the last payment and PreviousAmount
+	// that of the one before it.
+	Amount         Money
+	PreviousAmount Money
+	// NextDue is when the next payment is expected. It is expected to be
+	// Amount, as prices change rather than drift.
+	NextDue time.Time
+	Status  RecurringStatus
+}
+
+// Key identifies the series across detections.
+func (p *RecurringPayment) Key() string {
+	return p.AccountID + "/" + strings.ToLower(p.Merchant) + "/" + p.Cadence.String()
+}
+
+// PriceIncrease returns by how much the last payment was more than the one
+// before it, or zero.
+func (p *RecurringPayment) PriceIncrease() Money {
+	increase := p.Amount.Amount - p.PreviousAmount.Amount
+	if increase < 0 {
+		increase = 0
+	}
+	return NewMoney(increase, p.Amount.Currency)
+}
+
+// DetectRecurring returns the recurring payments in the outgoing
+// transactions of accounts, soonest due first and ended ones last. Payments
+// are grouped by account and title, then into series whose amounts are
+// within recurringAmountTolerance of each other, and a series is recurring
+// if the time between all of its payments matches a cadence. A series that
+// starts one period after another one ended continues it at a new price. The
+// status of each is relative to now.
+func DetectRecurring(accounts Accounts, now time.Time) []RecurringPayment {
+	var payments []RecurringPayment
+	for _, account := range accounts {
+		byTitle := make(map[string][]*Transaction)
+		var titles []string
+		for _, transaction := range account.Transactions {
+			if transaction.Status == TransactionDeclined || transaction.Amount.Amount >= 0 {
+				continue
+			}
+			title := strings.ToLower(transaction.Title())
+			if _, ok := byTitle[title]; !ok {
+				titles = append(titles, title)
+			}
+			byTitle[title] = append(byTitle[title], transaction)
+		}
+
+		for _, title := range titles {
+			for _, series := range joinPriceChanges(amountSeries(byTitle[title])) {
+				if payment, ok := recurringPayment(account.ID, series, now); ok {
+					payments = append(payments, payment)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(payments, func(i, j int) bool {
+		if ended := payments[i].Status == RecurringEnded; ended != (payments[j].Status == RecurringEnded) {
+			return !ended
+		}
+		return payments[i].NextDue.Before(payments[j].NextDue)
+	})
+	return payments
+}
+
+// amountSeries splits transactions into series, oldest first, in which every
+// amount is within recurringAmountTolerance of the previous one.
+func amountSeries(transactions []*Transaction) [][]*Transaction {
+	sorted := append([]*Transaction(nil), transactions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Created.Before(sorted[j].Created)
+	})
+
+	var series [][]*Transaction
+next:
+	for _, transaction := range sorted {
+		for i, s := range series {
+			last := s[len(s)-1]
+			if last.Amount.Currency == transaction.Amount.Currency &&
+				math.Abs(float64(transaction.Amount.Amount-last.Amount.Amount)) <= recurringAmountTolerance*math.Abs(float64(last.Amount.Amount)) {
+				series[i] = append(s, transaction)
+				continue next
+			}
+		}
+		series = append(series, []*Transaction{transaction})
+	}
+	return series
+}
+
+// joinPriceChanges joins the series, as returned by amountSeries, in which
+// the price changed by more than recurringAmountTolerance: a series that
+// starts after the last payment of a recurring series, at its cadence,
+// continues it.
+func joinPriceChanges(series [][]*Transaction) [][]*Transaction {
+	joined := make([][]*Transaction, 0, len(series))
+	used := make([]bool, len(series))
+	for i, s := range series {
+		if used[i] {
+			continue
+		}
+		for j := i + 1; j < len(series); j++ {
+			if !used[j] && continues(s, series[j]) {
+				s = append(s[:len(s):len(s)], series[j]...)
+				used[j] = true
+			}
+		}
+		joined = append(joined, s)
+	}
+	return joined
+}
+
+// continues reports whether next continues the recurring series s at
+// another price.
+func continues(s, next []*Transaction) bool {
+	last, first := s[len(s)-1], next[0]
+	if first.Amount.Currency != last.Amount.Currency || !first.Created.After(last.Created) {
+		return false
+	}
+	c, ok := matchCadence(s)
+	if !ok {
+		return false
+	}
+	joined, ok := matchCadence(append(s[:len(s):len(s)], next...))
+	return ok && joined == c
+}
+
+// matchCadence returns the index in cadences of the cadence that the time
+// between all payments of the series matches.
+func matchCadence(series []*Transaction) (int, bool) {
+	if len(series) < 2 {
+		return 0, false
+	}
+
+	intervals := make([]float64, len(series)-1)
+	for i := range intervals {
+		intervals[i] = series[i+1].Created.Sub(series[i].Created).Hours() / 24
+	}
+	for i, c := range cadences {
+		if len(series) >= c.min && allWithin(intervals, c.days, c.tolerance) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// recurringPayment returns the series as a recurring payment if the time
+// between its payments matches a cadence.
+func recurringPayment(accountID string, series []*Transaction, now time.Time) (RecurringPayment, bool) {
+	i, ok := matchCadence(series)
+	if !ok {
+		return RecurringPayment{}, false
+	}
+	c := cadences[i]
+
+	last := series[len(series)-1]
+	amount, err := last.Amount.Abs()
+	if err != nil {
+		return RecurringPayment{}, false
+	}
+	previous, err := series[len(series)-2].Amount.Abs()
+	if err != nil {
+		return RecurringPayment{}, false
+	}
+	payment := RecurringPayment{
+		AccountID:      accountID,
+		Merchant:       last.Title(),
+		Category:       last.Category,
+		Cadence:        c.cadence,
+		Transactions:   series,
+		Amount:         amount,
+		PreviousAmount: previous,
+		NextDue:        c.cadence.after(last.Created),
+	}
+	grace := time.Duration(c.tolerance * float64(24*time.Hour))
+	switch {
+	case now.After(c.cadence.after(payment.NextDue).Add(grace)):
+		payment.Status = RecurringEnded
+	case now.After(payment.NextDue.Add(grace)):
+		payment.Status = RecurringMissing
+	}
+	return payment, true
+}
+
+func allWithin(intervals []float64, days, tolerance float64) bool {
+	for _, interval := range intervals {
+		if math.Abs(interval-days) > tolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// RecurringAlert is a recurring payment that went missing or up in price.
+type RecurringAlert struct {
+	Payment RecurringPayment
+	// Missing is set if the payment is overdue, otherwise its last payment
+	// was more than the one before.
+	Missing bool
+}
+
+// RecurringMonitor detects the recurring payments of a wallet and reports
+// each missing payment and price increase once, also across runs of the app
+// if the alerts are recorded in an AlertLog.
+type RecurringMonitor struct {
+	mtx    sync.Mutex
+	alerts *alertRecord
+}
+
+// NewRecurringMonitor returns a monitor that records the alerts it reports
+// in log. Without a log, they are only kept in memory.
+func NewRecurringMonitor(log AlertLog) *RecurringMonitor {
+	return &RecurringMonitor{alerts: newAlertRecord(log)}
+}
+
+// Check detects the recurring payments of accounts and returns the alerts
+// that were not returned before. Ended payments are not reported.
+func (m *RecurringMonitor) Check(accounts Accounts, now time.Time) []RecurringAlert {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var alerts []RecurringAlert
+	for _, payment := range DetectRecurring(accounts, now) {
+		if payment.Status == RecurringEnded {
+			continue
+		}
+
+		last := payment.Transactions[len(payment.Transactions)-1]
+		if payment.PriceIncrease().Amount > 0 && m.alerts.raise(last.ID, now) {
+			alerts = append(alerts, RecurringAlert{Payment: payment})
+		}
+
+		missing := payment.Key() + "@" + payment.NextDue.Format(time.RFC3339)
+		if payment.Status == RecurringMissing && m.alerts.raise(missing, now) {
+			alerts = append(alerts, RecurringAlert{Payment: payment, Missing: true})
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// subscription returns monthly payments to Netflix of each amount, on the
+// first of the month from June 2022.
+func subscription(amounts ...int64) []*Transaction {
+	var transactions []*Transaction
+	for i, amount := range amounts {
+		transaction := spending(time.Date(2022, time.June+time.Month(i), 1, 9, 0, 0, 0, time.UTC), amount, "entertainment", "GBP")
+		transaction.Merchant = &Merchant{Name: "Netflix"}
+		transactions = append(transactions, transaction)
+	}
+	return transactions
+}
+
+func TestDetectRecurringPriceIncrease(t *testing.T) {
+	tests := []struct {
+		name    string
+		amounts []int64
+	}{
+		{"within the tolerance", []int64{599, 599, 599, 699}},
+		{"over the tolerance", []int64{599, 599, 599, 799}},
+		{"doubled", []int64{599, 599, 599, 1199, 1199}},
+	}
+
+	now := time.Date(2022, time.October, 2, 12, 0, 0, 0, time.UTC)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			accounts := Accounts{{ID: "acc_1", Transactions: subscription(test.amounts...)}}
+			payments := DetectRecurring(accounts, now)
+			if len(payments) != 1 {
+				t.Fatalf("got %d recurring payments, want 1", len(payments))
+			}
+			payment := payments[0]
+
+			if len(payment.Transactions) != len(test.amounts) {
+				t.Errorf("series has %d payments, want %d", len(payment.Transactions), len(test.amounts))
+			}
+			if payment.Cadence != CadenceMonthly {
+				t.Errorf("cadence %v, want monthly", payment.Cadence)
+			}
+			increase := test.amounts[len(test.amounts)-1] - test.amounts[len(test.amounts)-2]
+			if want := NewMoney(increase, "GBP"); payment.PriceIncrease() != want {
+				t.Errorf("price increase %v, want %v", payment.PriceIncrease(), want)
+			}
+		})
+	}
+}
+
+func TestRecurringMonitorAlertsOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	accounts := Accounts{{ID: "acc_1", Transactions: subscription(599, 599, 599, 799)}}
+	increased := time.Date(2022, time.September, 2, 12, 0, 0, 0, time.UTC)
+	overdue := time.Date(2022, time.October, 10, 12, 0, 0, 0, time.UTC)
+
+	// check runs a new monitor, as on a launch of the app, and returns how
+	// many of its alerts were for missing payments and price increases.
+	check := func(now time.Time) (missing, increases int) {
+		t.Helper()
+		store, err := OpenStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+
+		for _, alert := range NewRecurringMonitor(store).Check(accounts, now) {
+			if alert.Missing {
+				missing++
+			} else {
+				increases++
+			}
+		}
+		return missing, increases
+	}
+
+	if missing, increases := check(increased); missing != 0 || increases != 1 {
+		t.Errorf("first check raised %d missing and %d price alerts, want 0 and 1", missing, increases)
+	}
+	if missing, increases := check(increased); missing != 0 || increases != 0 {
+		t.Errorf("check after a restart raised %d missing and %d price alerts, want none", missing, increases)
+	}
+	if missing, increases := check(overdue); missing != 1 || increases != 0 {
+		t.Errorf("check of the overdue payment raised %d missing and %d price alerts, want 1 and 0", missing, increases)
+	}
+	if missing, increases := check(overdue); missing != 0 || increases != 0 {
+		t.Errorf("check of the overdue payment after a restart raised %d missing and %d price alerts, want none", missing, increases)
+	}
+
+	monitor := NewRecurringMonitor(nil)
+	if alerts := monitor.Check(accounts, increased); len(alerts) != 1 {
+		t.Errorf("monitor without a log raised %d alerts, want 1", len(alerts))
+	}
+	if alerts := monitor.Check(accounts, increased); len(alerts) != 0 {
+		t.Errorf("monitor without a log raised %d alerts again, want none", len(alerts))
+	}
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// stop marks an error that Retry must not retry.
+type stop struct {
+	error
+}
+
+// StopRetry wraps err so that Retry returns it right away instead of trying
+// again, e.g. because a new login is required.
+func StopRetry(err error) error {
+	return stop{err}
+}
+
+// Retry calls f until it succeeds, returns an error wrapped with StopRetry or
+// has been called attempts times. The delay between attempts starts at sleep
+// and doubles after every attempt. Retry gives up early with ctx.Err() if ctx
+// is done while waiting.
+func Retry(ctx context.Context, attempts int, sleep time.Duration, f func() error) error {
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		if s, ok := err.(stop); ok {
+			// Return the original error for later checking
+			return s.error
+		}
+
+		if attempts--; attempts <= 0 {
+			return err
+		}
+
+		// Add some randomness to prevent creating a Thundering Herd
+		jitter := time.Duration(rand.Int63n(int64(sleep)))
+		sleep = sleep + jitter/2
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sleep):
+		}
+		sleep *= 2
+	}
+}
@@ -0,0 +1,294 @@
+package internal
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// StoreFileName is the name of the offline cache database in AppDataDir.
+const StoreFileName = "wallet.db"
+
+// storeVersion is the version of the cache format. Caches written in another
+// format are discarded when they are opened and rebuilt by the next sync.
+const storeVersion = "4"
+
+var (
+	metaBucket         = []byte("meta")
+	accountsBucket     = []byte("accounts")
+	transactionsBucket = []byte("transactions")
+	// syncedBucket holds the ID of the newest transaction fetched by a sync
+	// for each account.
+	syncedBucket = []byte("synced")
+	// alertsBucket holds the keys of the alerts raised, see
+	// Wallet.SetAlerted. It is kept when the cache format changes, so that
+	// alerts are not raised again.
+	alertsBucket = []byte("alerts")
+
+	versionKey = []byte("version")
+)
+
+// Store is an embedded on-disk cache of accounts and their transactions
+// that lets the wallet open without network access.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens the cache database at path, creating it if necessary.
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		if string(meta.Get(versionKey)) != storeVersion {
+			for _, bucket := range [][]byte{accountsBucket, transactionsBucket, syncedBucket} {
+				if err := tx.DeleteBucket(bucket); err != nil && err != bolt.ErrBucketNotFound {
+					return err
+				}
+			}
+			if err := meta.Put(versionKey, []byte(storeVersion)); err != nil {
+				return err
+			}
+		}
+
+		for _, bucket := range [][]byte{accountsBucket, transactionsBucket, syncedBucket, alertsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SaveAccounts replaces the cached account details and balances with
+// accounts. Transactions are stored separately with AddTransactions; those of
+// accounts that are no longer listed are removed.
+func (s *Store) SaveAccounts(accounts Accounts) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(accountsBucket); err != nil {
+			return err
+		}
+		bucket, err := tx.CreateBucket(accountsBucket)
+		if err != nil {
+			return err
+		}
+
+		listed := make(map[string]bool, len(accounts))
+		for _, account := range accounts {
+			details := *account
+			details.Transactions = nil
+			if err := putJSON(bucket, []byte(account.ID), &details); err != nil {
+				return err
+			}
+			listed[account.ID] = true
+		}
+
+		var removed [][]byte
+		transactions := tx.Bucket(transactionsBucket)
+		err = transactions.ForEach(func(id, _ []byte) error {
+			if !listed[string(id)] {
+				removed = append(removed, append([]byte(nil), id...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, id := range removed {
+			if err := transactions.DeleteBucket(id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Accounts returns the cached accounts with their transactions.
+func (s *Store) Accounts() (Accounts, error) {
+	var accounts Accounts
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(accountsBucket).ForEach(func(_, data []byte) error {
+			account := &Account{}
+			if err := json.Unmarshal(data, account); err != nil {
+				return err
+			}
+
+			transactions, err := readTransactions(tx, account.ID)
+			if err != nil {
+				return err
+			}
+			account.Transactions = transactions
+
+			accounts = append(accounts, account)
+			return nil
+		})
+	})
+	return accounts, err
+}
+
+// AddTransactions inserts transactions into the account's history, replacing
+// previously cached copies of the same transactions.
+func (s *Store) AddTransactions(accountID string, transactions []*Transaction) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(transactionsBucket).CreateBucketIfNotExists([]byte(accountID))
+		if err != nil {
+			return err
+		}
+		for _, transaction := range transactions {
+			if err := putJSON(bucket, transactionKey(transaction), transaction); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Transactions returns the cached transactions of the account, oldest first.
+func (s *Store) Transactions(accountID string) ([]*Transaction, error) {
+	var transactions []*Transaction
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		transactions, err = readTransactions(tx, accountID)
+		return err
+	})
+	return transactions, err
+}
+
+// LastTransaction returns the newest cached transaction of the account or
+// nil if none is cached.
+func (s *Store) LastTransaction(accountID string) (*Transaction, error) {
+	var transaction *Transaction
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(transactionsBucket).Bucket([]byte(accountID))
+		if bucket == nil {
+			return nil
+		}
+		_, data := bucket.Cursor().Last()
+		if data == nil {
+			return nil
+		}
+		transaction = &Transaction{}
+		return json.Unmarshal(data, transaction)
+	})
+	return transaction, err
+}
+
+// SetSyncedUntil records the ID of the newest transaction of the account
+// fetched from the provider. Transactions added otherwise, e.g. received
+// through a webhook, may be newer; the next sync must not skip the ones in
+// between.
+func (s *Store) SetSyncedUntil(accountID, transactionID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(syncedBucket).Put([]byte(accountID), []byte(transactionID))
+	})
+}
+
+// SyncedUntil returns the ID recorded with SetSyncedUntil for the account, or
+// an empty string if there is none.
+func (s *Store) SyncedUntil(accountID string) (string, error) {
+	var id string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		id = string(tx.Bucket(syncedBucket).Get([]byte(accountID)))
+		return nil
+	})
+	return id, err
+}
+
+// Alerted reports whether the alert with key was recorded with SetAlerted.
+func (s *Store) Alerted(key string) (bool, error) {
+	var alerted bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		alerted = tx.Bucket(alertsBucket).Get([]byte(key)) != nil
+		return nil
+	})
+	return alerted, err
+}
+
+// SetAlerted records that the alert with key was raised at the given time.
+func (s *Store) SetAlerted(key string, at time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(alertsBucket).Put([]byte(key), []byte(at.UTC().Format(time.RFC3339)))
+	})
+}
+
+// OldestPending returns the oldest cached transaction of the account created
+// after since that is still pending, or nil if there is none.
+func (s *Store) OldestPending(accountID string, since time.Time) (*Transaction, error) {
+	var pending *Transaction
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(transactionsBucket).Bucket([]byte(accountID))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		start := []byte(since.UTC().Format(transactionKeyLayout))
+		for k, data := c.Seek(start); k != nil; k, data = c.Next() {
+			transaction := &Transaction{}
+			if err := json.Unmarshal(data, transaction); err != nil {
+				return err
+			}
+			if transaction.Status == TransactionPending {
+				pending = transaction
+				return nil
+			}
+		}
+		return nil
+	})
+	return pending, err
+}
+
+func readTransactions(tx *bolt.Tx, accountID string) ([]*Transaction, error) {
+	bucket := tx.Bucket(transactionsBucket).Bucket([]byte(accountID))
+	if bucket == nil {
+		return nil, nil
+	}
+
+	transactions := make([]*Transaction, 0, bucket.Stats().KeyN)
+	err := bucket.ForEach(func(_, data []byte) error {
+		transaction := &Transaction{}
+		if err := json.Unmarshal(data, transaction); err != nil {
+			return err
+		}
+		transactions = append(transactions, transaction)
+		return nil
+	})
+	return transactions, err
+}
+
+// transactionKeyLayout formats creation times with a fixed width so that
+// keys sort chronologically.
+const transactionKeyLayout = "2006-01-02T15:04:05.000000000Z"
+
+// transactionKey orders transactions by creation time. The ID is appended so
+// that transactions created at the same instant do not overwrite each other.
+func transactionKey(transaction *Transaction) []byte {
+	return []byte(transaction.Created.UTC().Format(transactionKeyLayout) + "\x00" + transaction.ID)
+}
+
+func putJSON(bucket *bolt.Bucket, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, data)
+}
@@ -0,0 +1,361 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSyncInterval is how often the Syncer refreshes the wallet when
+	// no other interval is set.
+	DefaultSyncInterval = 5 * time.Minute
+
+	// syncAttempts and syncRetryDelay configure the retries of a single sync.
+	syncAttempts   = 3
+	syncRetryDelay = 3 * time.Second
+	// syncBackoff is the delay before the next sync after a failed one. It
+	// doubles with every consecutive failure up to the sync interval.
+	syncBackoff = 30 * time.Second
+)
+
+// SyncEvent is published by the Syncer whenever the sync state changes. It
+// is one of SyncStarted, SyncProgress, SyncFinished, SyncFailed or
+// SyncOffline, or TransactionReceived or LoginRequired, which do not change
+// the state.
+type SyncEvent interface {
+	syncEvent()
+}
+
+// SyncStarted is published when a sync begins.
+type SyncStarted struct {
+	Time time.Time
+}
+
+// SyncProgress is published after each account has been synced.
+type SyncProgress struct {
+	AccountID string
+	Done      int
+	Total     int
+}
+
+// SyncFinished is published when all accounts have been synced.
+type SyncFinished struct {
+	Time time.Time
+}
+
+// SyncFailed is published when a sync fails for a reason other than the
+// network being unavailable. Err wraps ErrTokenRevoked if a new login is
+// required.
+type SyncFailed struct {
+	Err error
+}
+
+// SyncOffline is published when a sync fails because the API could not be
+// reached.
+type SyncOffline struct {
+	Err error
+}
+
+// TransactionReceived is published when a new transaction is received between
+// syncs, see Wallet.ReceiveTransaction.
+type TransactionReceived struct {
+	AccountID   string
+	Transaction *Transaction
+}
+
+// LoginRequired is published once when the background sync finds that the
+// token of a profile was revoked or has expired. The profile is skipped, and
+// keeps its accounts, until the app logs it in again with
+// Wallet.AuthenticateProfile. The other profiles are synced as usual.
+type LoginRequired struct {
+	Profile *Profile
+	Err     error
+}
+
+func (SyncStarted) syncEvent()         {}
+func (SyncProgress) syncEvent()        {}
+func (SyncFinished) syncEvent()        {}
+func (SyncFailed) syncEvent()          {}
+func (SyncOffline) syncEvent()         {}
+func (TransactionReceived) syncEvent() {}
+func (LoginRequired) syncEvent()       {}
+
+// Syncer keeps a Wallet up to date by fetching its accounts periodically and
+// publishes the progress as SyncEvents. Every Wallet owns one, see
+// Wallet.Syncer.
+type Syncer struct {
+	wallet *Wallet
+
+	syncMtx sync.Mutex // held while a sync runs
+
+	mtx         sync.Mutex
+	interval    time.Duration
+	status      SyncEvent
+	lastSynced  time.Time
+	subscribers map[chan SyncEvent]struct{}
+	trigger     chan struct{}
+	cancel      context.CancelFunc
+}
+
+func newSyncer(wallet *Wallet) *Syncer {
+	return &Syncer{
+		wallet:      wallet,
+		interval:    DefaultSyncInterval,
+		subscribers: make(map[chan SyncEvent]struct{}),
+		trigger:     make(chan struct{}, 1),
+	}
+}
+
+// SetInterval changes how often the wallet is synced in the background. It
+// takes effect after the next sync.
+func (s *Syncer) SetInterval(interval time.Duration) {
+	s.mtx.Lock()
+	s.interval = interval
+	s.mtx.Unlock()
+}
+
+// Interval returns how often the wallet is synced in the background.
+func (s *Syncer) Interval() time.Duration {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.interval
+}
+
+// Status returns the last published event, or nil if the wallet has not been
+// synced yet.
+func (s *Syncer) Status() SyncEvent {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.status
+}
+
+// LastSynced returns the time the last successful sync finished.
+func (s *Syncer) LastSynced() time.Time {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.lastSynced
+}
+
+// Syncing reports whether a sync is in progress.
+func (s *Syncer) Syncing() bool {
+	switch s.Status().(type) {
+	case SyncStarted, SyncProgress:
+		return true
+	}
+	return false
+}
+
+// Subscribe returns a channel that receives the events published from now
+// on and a function that cancels the subscription and closes the channel.
+// Events are dropped for subscribers that do not keep up; use Status to get
+// the current state.
+func (s *Syncer) Subscribe() (<-chan SyncEvent, func()) {
+	ch := make(chan SyncEvent, 16)
+
+	s.mtx.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mtx.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mtx.Lock()
+			delete(s.subscribers, ch)
+			s.mtx.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (s *Syncer) publish(event SyncEvent) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.status = event
+	if finished, ok := event.(SyncFinished); ok {
+		s.lastSynced = finished.Time
+	}
+	s.broadcast(event)
+}
+
+// notify sends event to the subscribers without changing the status.
+func (s *Syncer) notify(event SyncEvent) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.broadcast(event)
+}
+
+// broadcast sends event to the subscribers. s.mtx must be held.
+func (s *Syncer) broadcast(event SyncEvent) {
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// restore publishes the status before an abandoned sync again.
+func (s *Syncer) restore(prev SyncEvent) {
+	if prev != nil {
+		s.publish(prev)
+		return
+	}
+	s.mtx.Lock()
+	s.status = nil
+	s.mtx.Unlock()
+}
+
+// Start syncs the wallet in the background until Stop is called, every
+// interval after the last successful sync. Failed syncs are retried sooner
+// with an increasing delay. Calling Start while the Syncer is running has no
+// effect.
+func (s *Syncer) Start() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.run(ctx)
+}
+
+// Stop stops the background sync started by Start. A sync in progress is
+// abandoned between retries.
+func (s *Syncer) Stop() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+}
+
+// Running reports whether the background sync is started.
+func (s *Syncer) Running() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.cancel != nil
+}
+
+// SyncNow makes the background sync run right away instead of waiting for
+// the interval to pass. If the Syncer is not running, a single sync is
+// started instead.
+func (s *Syncer) SyncNow() {
+	if !s.Running() {
+		go s.Sync(context.Background())
+		return
+	}
+
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+		// A sync is already pending.
+	}
+}
+
+func (s *Syncer) run(ctx context.Context) {
+	// A negative delay syncs right away if the wallet was never synced.
+	delay := time.Until(s.LastSynced().Add(s.Interval()))
+	failures := 0
+	for {
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-s.trigger:
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+
+		delay = s.Interval()
+		if !s.wallet.authenticated() {
+			// Every profile waits for a new login.
+			continue
+		}
+
+		err := s.Sync(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		var profileErrs ProfileErrors
+		if errors.As(err, &profileErrs) && len(profileErrs.Revoked()) > 0 {
+			// Retrying will not help these profiles until the user logs
+			// in again, so they are skipped in the meantime.
+			for _, profile := range profileErrs.Revoked() {
+				s.wallet.dropTokenSource(profile.ID)
+				s.notify(LoginRequired{Profile: profile, Err: profileErrs.Failed(profile.ID)})
+			}
+			if len(profileErrs.Revoked()) == len(profileErrs) {
+				err = nil
+			}
+		}
+		switch {
+		case err != nil:
+			failures++
+		default:
+			failures = 0
+		}
+
+		if failures > 0 {
+			delay = backoff(failures, delay)
+		}
+	}
+}
+
+// backoff returns the delay before the next sync after the given number of
+// consecutive failures.
+func backoff(failures int, interval time.Duration) time.Duration {
+	delay := syncBackoff
+	for i := 1; i < failures && delay < interval; i++ {
+		delay *= 2
+	}
+	if delay > interval {
+		delay = interval
+	}
+	return delay
+}
+
+// Sync fetches the accounts of the wallet once, retrying transient failures.
+// It returns an error wrapping ErrTokenRevoked if a new login is required.
+func (s *Syncer) Sync(ctx context.Context) error {
+	s.syncMtx.Lock()
+	defer s.syncMtx.Unlock()
+
+	prev := s.Status()
+	s.publish(SyncStarted{Time: time.Now()})
+	err := Retry(ctx, syncAttempts, syncRetryDelay, func() error {
+		err := s.wallet.fetchAccounts(func(account *Account, done, total int) {
+			s.publish(SyncProgress{AccountID: account.ID, Done: done, Total: total})
+		})
+		if errors.Is(err, ErrTokenRevoked) {
+			// Retrying will not help, a new login is required.
+			return StopRetry(err)
+		}
+		return err
+	})
+	if err == nil {
+		s.publish(SyncFinished{Time: time.Now()})
+		return nil
+	}
+
+	var netErr net.Error
+	switch {
+	case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+		// The sync was abandoned rather than failed.
+		s.restore(prev)
+	case errors.As(err, &netErr):
+		s.publish(SyncOffline{Err: err})
+	default:
+		s.publish(SyncFailed{Err: err})
+	}
+	return err
+}
@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/oauth2"
+)
+
+// TokenFileName is the name of the encrypted token file in AppDataDir.
+const TokenFileName = "token.enc"
+
+const (
+	tokenStoreVersion = 1
+
+	// scrypt parameters recommended for interactive logins.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	saltSize  = 32
+	keySize   = 32
+	nonceSize = 24
+)
+
+var (
+	// ErrWrongPassphrase is returned when a token store cannot be decrypted
+	// with the given passphrase.
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	// ErrNoToken is returned by TokenStore.Load when no token was saved yet.
+	ErrNoToken = errors.New("no token stored")
+	// ErrTokenRevoked is returned when the stored refresh token was revoked
+	// or has expired. A new login is required.
+	ErrTokenRevoked = errors.New("token revoked or expired, log in again")
+)
+
+// tokenFile is the on-disk format of a TokenStore.
+type tokenFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// TokenStore keeps an OAuth token on disk, encrypted with a key derived from
+// a passphrase.
+type TokenStore struct {
+	path string
+	salt []byte
+	key  [keySize]byte
+
+	mtx   sync.Mutex
+	token *oauth2.Token
+}
+
+// OpenTokenStore unlocks the token store at path with passphrase. A new
+// store protected by passphrase is created if none exists at path yet.
+func OpenTokenStore(path, passphrase string) (*TokenStore, error) {
+	s := &TokenStore{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.salt = make([]byte, saltSize)
+		if _, err := rand.Read(s.salt); err != nil {
+			return nil, err
+		}
+		if err := s.deriveKey(passphrase); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f tokenFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("reading token store: %v", err)
+	}
+	if f.Version != tokenStoreVersion || len(f.Nonce) != nonceSize {
+		return nil, fmt.Errorf("unsupported token store version %d", f.Version)
+	}
+
+	s.salt = f.Salt
+	if err := s.deriveKey(passphrase); err != nil {
+		return nil, err
+	}
+
+	var nonce [nonceSize]byte
+	copy(nonce[:], f.Nonce)
+	plaintext, ok := secretbox.Open(nil, f.Data, &nonce, &s.key)
+	if !ok {
+		return nil, ErrWrongPassphrase
+	}
+
+	if len(plaintext) > 0 {
+		s.token = &oauth2.Token{}
+		if err := json.Unmarshal(plaintext, s.token); err != nil {
+			return nil, fmt.Errorf("reading token store: %v", err)
+		}
+	}
+	return s, nil
+}
+
+func (s *TokenStore) deriveKey(passphrase string) error {
+	key, err := scrypt.Key([]byte(passphrase), s.salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return err
+	}
+	copy(s.key[:], key)
+	return nil
+}
+
+// Load returns the stored token or ErrNoToken.
+func (s *TokenStore) Load() (*oauth2.Token, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.token == nil {
+		return nil, ErrNoToken
+	}
+	return s.token, nil
+}
+
+// Save encrypts token and writes it to disk, replacing any stored token.
+func (s *TokenStore) Save(token *oauth2.Token) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := s.write(plaintext); err != nil {
+		return err
+	}
+	s.token = token
+	return nil
+}
+
+// Clear removes the stored token but keeps the store protected by the same
+// passphrase.
+func (s *TokenStore) Clear() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if err := s.write(nil); err != nil {
+		return err
+	}
+	s.token = nil
+	return nil
+}
+
+// write encrypts plaintext and atomically replaces the store file with it.
+// s.mtx must be held.
+func (s *TokenStore) write(plaintext []byte) error {
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(tokenFile{
+		Version: tokenStoreVersion,
+		Salt:    s.salt,
+		Nonce:   nonce[:],
+		Data:    secretbox.Seal(nil, plaintext, &nonce, &s.key),
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// TokenSource returns a TokenSource that starts from token, refreshes it
+// through conf shortly before it expires and saves every refreshed token to
+// the store. Monzo rotates refresh tokens on every refresh, so a refreshed
+// token must be persisted before the old one is thrown away.
+// If the token endpoint rejects the refresh token with invalid_grant, because
+// it was revoked or has expired, the stored token is cleared and Token()
+// returns an error wrapping ErrTokenRevoked. Other failures, e.g. a
+// misconfigured client ID, keep the stored token and may be retried.
+func (s *TokenStore) TokenSource(ctx context.Context, conf *oauth2.Config, token *oauth2.Token) oauth2.TokenSource {
+	return &persistingTokenSource{
+		store:  s,
+		source: oauth2.ReuseTokenSource(token, conf.TokenSource(ctx, token)),
+		last:   token,
+	}
+}
+
+type persistingTokenSource struct {
+	store  *TokenStore
+	source oauth2.TokenSource
+
+	mtx  sync.Mutex
+	last *oauth2.Token
+}
+
+// Token returns a valid token, refreshing and saving it if necessary.
+// Part of the oauth2.TokenSource interface.
+func (ts *persistingTokenSource) Token() (*oauth2.Token, error) {
+	ts.mtx.Lock()
+	defer ts.mtx.Unlock()
+
+	token, err := ts.source.Token()
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && isInvalidGrant(retrieveErr) {
+			if clearErr := ts.store.Clear(); clearErr != nil {
+				return nil, clearErr
+			}
+			return nil, fmt.Errorf("%w: %v", ErrTokenRevoked, err)
+		}
+		return nil, err
+	}
+
+	if ts.last == nil || token.AccessToken != ts.last.AccessToken {
+		if err := ts.store.Save(token); err != nil {
+			return nil, err
+		}
+		ts.last = token
+	}
+	return token, nil
+}
+
+// isInvalidGrant reports whether the token endpoint rejected the refresh
+// token itself, which is the only failure that requires a new login.
+func isInvalidGrant(err *oauth2.RetrieveError) bool {
+	var body struct {
+		Error string `json:"error"`
+	}
+	return json.Unmarshal(err.Body, &body) == nil && body.Error == "invalid_grant"
+}
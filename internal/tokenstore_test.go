@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestTokenSourceRefreshFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantRevoked bool
+	}{
+		{
+			name:        "invalid grant",
+			status:      http.StatusBadRequest,
+			body:        `{"error":"invalid_grant","error_description":"Refresh token revoked"}`,
+			wantRevoked: true,
+		},
+		{
+			name:   "invalid client",
+			status: http.StatusUnauthorized,
+			body:   `{"error":"invalid_client","error_description":"Unknown client"}`,
+		},
+		{
+			name:   "server error",
+			status: http.StatusServiceUnavailable,
+			body:   "Service Unavailable",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer srv.Close()
+
+			store, err := OpenTokenStore(filepath.Join(t.TempDir(), TokenFileName), "passphrase")
+			if err != nil {
+				t.Fatal(err)
+			}
+			expired := &oauth2.Token{
+				AccessToken:  "access",
+				RefreshToken: "refresh",
+				Expiry:       time.Now().Add(-time.Hour),
+			}
+			if err := store.Save(expired); err != nil {
+				t.Fatal(err)
+			}
+
+			conf := &oauth2.Config{
+				ClientID: "client",
+				Endpoint: oauth2.Endpoint{TokenURL: srv.URL, AuthStyle: oauth2.AuthStyleInParams},
+			}
+			_, err = store.TokenSource(context.Background(), conf, expired).Token()
+			if err == nil {
+				t.Fatal("Token succeeded despite the failing refresh")
+			}
+			if revoked := errors.Is(err, ErrTokenRevoked); revoked != test.wantRevoked {
+				t.Errorf("Token returned %v, revoked: %t, want %t", err, revoked, test.wantRevoked)
+			}
+
+			_, err = store.Load()
+			if cleared := errors.Is(err, ErrNoToken); cleared != test.wantRevoked {
+				t.Errorf("stored token cleared: %t, want %t", cleared, test.wantRevoked)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"strings"
+	"time"
+)
+
+// TransactionStatus is the state of a transaction.
+type TransactionStatus string
+
+const (
+	// TransactionPending transactions are authorised but not settled yet.
+	// Their amount may still change.
+	TransactionPending TransactionStatus = "pending"
+	// TransactionSettled transactions are final.
+	TransactionSettled TransactionStatus = "settled"
+	// TransactionDeclined transactions were refused, see
+	// Transaction.DeclineReason.
+	TransactionDeclined TransactionStatus = "declined"
+)
+
+type Transaction struct {
+	ID      string
+	Created time.Time
+	// Settled is the zero time unless Status is TransactionSettled.
+	Settled time.Time
+	Status  TransactionStatus
+	// DeclineReason is set if Status is TransactionDeclined, e.g.
+	// "INSUFFICIENT_FUNDS".
+	DeclineReason string
+
+	// Amount is in the currency of the account. LocalAmount is the amount in
+	// the currency the payment was made in, which differs for payments
+	// abroad.
+	Amount      Money
+	LocalAmount Money
+
+	// Description is the raw description from the bank statement, e.g.
+	// "PRET A MANGER".
+	Description string
+	Category    string
+	Notes       string
+
+	// Merchant is set for card payments and Counterparty for transfers to
+	// and from other people and bank accounts. Either may be nil.
+	Merchant     *Merchant
+	Counterparty *Counterparty
+	Attachments  []*Attachment
+}
+
+// Title returns the name shown for the transaction: the merchant or
+// counterparty name if there is one, otherwise the description.
+func (t *Transaction) Title() string {
+	switch {
+	case t.Merchant != nil && t.Merchant.Name != "":
+		return t.Merchant.Name
+	case t.Counterparty != nil && t.Counterparty.Name != "":
+		return t.Counterparty.Name
+	case t.Description != "":
+		return t.Description
+	}
+	return t.ID
+}
+
+type Merchant struct {
+	ID       string
+	Name     string
+	Category string
+	LogoURL  string
+	Online   bool
+	Address  *Address // nil for online merchants
+}
+
+type Address struct {
+	Address   string
+	City      string
+	Region    string
+	Country   string
+	Postcode  string
+	Latitude  float64
+	Longitude float64
+}
+
+// String returns the non-empty parts of the address separated by commas.
+func (a *Address) String() string {
+	var parts []string
+	for _, part := range []string{a.Address, a.City, a.Postcode, a.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Counterparty is the other side of a bank transfer or a payment to or from
+// another Monzo user.
+type Counterparty struct {
+	Name          string
+	UserID        string
+	SortCode      string
+	AccountNumber string
+}
+
+// Attachment is a file attached to a transaction, e.g. a receipt.
+type Attachment struct {
+	ID       string
+	Created  time.Time
+	FileType string
+	URL      string
+}
@@ -1,159 +1,666 @@
 package internal
 
 import (
-	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"github.com/sirupsen/logrus"
-	"github.com/tjvr/go-monzo"
+	"sync"
+	"time"
+
 	"golang.org/x/oauth2"
-	"net/http"
-	"os/exec"
-	"runtime"
 )
 
+// pendingWindow is how long after their creation pending transactions are
+// fetched again on every sync to pick up their settlement. Older ones are
+// assumed to stay pending.
+const pendingWindow = 14 * 24 * time.Hour
+
+// ErrPotLocked is returned by Wallet.Withdraw for pots that are locked.
+var ErrPotLocked = errors.New("pot is locked")
+
 type Account struct {
-	ID            string
+	ID string
+	// ProfileID is the ID of the profile the account was loaded with.
+	ProfileID string
+	// Type is the account type, e.g. AccountTypeRetail.
+	Type          string
 	Created       string
 	AccountNumber string
-	Balance       float64
+	SortCode      string
+	Balance       Money
+	SpendToday    Money
 	Transactions  []*Transaction
+	Pots          []*Pot
+	// Mandates are the direct debits and standing orders of the account.
+	Mandates []*Mandate
 }
 
-type Transaction struct {
-	ID       string
-	Amount   float64
-	Created  string
-	Merchant string
+type Balance struct {
+	Balance    Money
+	SpendToday Money
+}
+
+type Pot struct {
+	ID      string
+	Name    string
+	Style   string
+	Balance Money
+	// Goal is the amount being saved towards, zero if the pot has no goal.
+	Goal Money
+	// Locked pots cannot be withdrawn from until they unlock.
+	Locked bool
+}
+
+// Progress returns how far the pot is towards its goal in the range [0, 1],
+// or 0 if it has no goal.
+func (p *Pot) Progress() float64 {
+	if p.Goal.Amount <= 0 || p.Balance.Amount <= 0 {
+		return 0
+	}
+	if p.Balance.Amount >= p.Goal.Amount {
+		return 1
+	}
+	return float64(p.Balance.Amount) / float64(p.Goal.Amount)
 }
 
 type Accounts []*Account
 
 type Wallet struct {
-	cl              *monzo.Client
+	newProvider ProviderFunc
+	store       *Store
+	syncer      *Syncer
+
+	mtx      sync.RWMutex
+	profiles []*Profile
+	// tokenSources holds the token sources of the authenticated profiles
+	// by profile ID.
+	tokenSources    map[string]oauth2.TokenSource
 	accounts        Accounts
 	SelectedAccount *Account
+	webhookReceiver *WebhookReceiver
+	// deepLink is the link received with the last login, see TakeDeepLink.
+	deepLink string
 }
 
-func (w *Wallet) LoadedWallet() bool {
-	return w.cl != nil && w.accounts != nil
+// NewWallet returns a Wallet that loads its data through the providers
+// created by newProvider. It has the default profile until SetProfiles is
+// called.
+func NewWallet(newProvider ProviderFunc) *Wallet {
+	w := &Wallet{
+		newProvider:  newProvider,
+		profiles:     []*Profile{DefaultProfile()},
+		tokenSources: make(map[string]oauth2.TokenSource),
+	}
+	w.syncer = newSyncer(w)
+	return w
 }
 
-func (w *Wallet) Shutdown() {
-	w.cl = nil
-	w.accounts = nil
+// Syncer returns the Syncer that keeps the wallet up to date.
+func (w *Wallet) Syncer() *Syncer {
+	return w.syncer
 }
 
-func (w *Wallet) Connect(conf *oauth2.Config) (*oauth2.Token, error) {
+// UseProvider changes the ProviderFunc used by the next call to
+// FetchAccounts, e.g. to point the wallet at a different API base URL.
+func (w *Wallet) UseProvider(newProvider ProviderFunc) {
+	w.newProvider = newProvider
+}
 
-	respCh := make(chan string)
+// Authenticate makes the wallet request the access tokens of the default
+// profile from ts, see AuthenticateProfile.
+func (w *Wallet) Authenticate(ts oauth2.TokenSource) {
+	w.AuthenticateProfile(DefaultProfileID, ts)
+}
 
-	srv := http.Server{Addr: ":8080"}
-	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		code := r.URL.Query().Get("code")
-		if code != "" {
-			respCh <- code
-		}
-		w.WriteHeader(200)
-	})
+// AuthenticateProfile makes the wallet request the access tokens of the
+// profile with the given ID from ts. A token is requested on every
+// FetchAccounts, so ts may refresh it in the meantime.
+func (w *Wallet) AuthenticateProfile(profileID string, ts oauth2.TokenSource) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.tokenSources[profileID] = ts
+}
 
-	go func() {
-		err := srv.ListenAndServe()
-		if err != nil {
-			logrus.Info(err)
+// dropTokenSource forgets the token source of the profile with the given ID,
+// e.g. as its token was revoked, so that FetchAccounts skips the profile and
+// keeps its accounts until AuthenticateProfile is called again.
+func (w *Wallet) dropTokenSource(profileID string) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	delete(w.tokenSources, profileID)
+}
+
+// authenticated reports whether any profile of the wallet has a token
+// source.
+func (w *Wallet) authenticated() bool {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+	for _, profile := range w.profiles {
+		if w.tokenSources[profile.ID] != nil {
+			return true
 		}
-	}()
-	defer func(srv *http.Server, ctx context.Context) {
-		err := srv.Shutdown(ctx)
-		if err != nil {
-			logrus.Info(err)
+	}
+	return false
+}
+
+// SetProfiles replaces the profiles of the wallet, e.g. with those returned
+// by LoadProfiles.
+func (w *Wallet) SetProfiles(profiles []*Profile) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	w.profiles = profiles
+}
+
+// Profiles returns the profiles of the wallet in the order their accounts
+// are listed. The slice must not be modified.
+func (w *Wallet) Profiles() []*Profile {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+	return w.profiles
+}
+
+// Profile returns the profile with the given ID, or nil if there is none.
+// Accounts cached before profiles were introduced have no profile ID and
+// belong to the default profile.
+func (w *Wallet) Profile(id string) *Profile {
+	if id == "" {
+		id = DefaultProfileID
+	}
+	for _, profile := range w.Profiles() {
+		if profile.ID == id {
+			return profile
 		}
-	}(&srv, context.Background())
+	}
+	return nil
+}
 
-	url := conf.AuthCodeURL(generateRandomState())
-	if err := openbrowser(url); err != nil {
-		return nil, err
+// AddProfile adds the profile, authenticated with ts, and starts a sync to
+// load its accounts. The caller should save the profiles with SaveProfiles.
+func (w *Wallet) AddProfile(profile *Profile, ts oauth2.TokenSource) {
+	w.mtx.Lock()
+	w.profiles = append(w.profiles[:len(w.profiles):len(w.profiles)], profile)
+	w.tokenSources[profile.ID] = ts
+	w.mtx.Unlock()
+
+	w.syncer.SyncNow()
+}
+
+// RemoveProfile removes the profile with the given ID and its accounts,
+// from the offline cache as well. The last profile cannot be removed. The
+// caller should save the profiles with SaveProfiles and delete the token
+// store of the profile.
+func (w *Wallet) RemoveProfile(id string) error {
+	// Wait for a sync in progress, which could save the accounts of the
+	// profile again.
+	w.syncer.syncMtx.Lock()
+	defer w.syncer.syncMtx.Unlock()
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	var profiles []*Profile
+	for _, profile := range w.profiles {
+		if profile.ID != id {
+			profiles = append(profiles, profile)
+		}
+	}
+	if len(profiles) == len(w.profiles) {
+		return fmt.Errorf("profile %s not found", id)
+	}
+	if len(profiles) == 0 {
+		return errors.New("the last profile cannot be removed")
 	}
 
-	return conf.Exchange(context.Background(), <-respCh)
+	var accounts Accounts
+	for _, account := range w.accounts {
+		if account.ProfileID != id {
+			accounts = append(accounts, account)
+		}
+	}
+	if w.store != nil {
+		if err := w.store.SaveAccounts(accounts); err != nil {
+			return err
+		}
+	}
 
+	w.profiles = profiles
+	delete(w.tokenSources, id)
+	w.accounts = accounts
+	if w.SelectedAccount != nil && w.SelectedAccount.ProfileID == id {
+		w.SelectedAccount = nil
+	}
+	return nil
 }
 
-func (w *Wallet) FetchAccounts(token string) error {
-	w.cl = &monzo.Client{
-		BaseURL:     "https://api.monzo.com",
-		AccessToken: token,
+// OpenCache opens the offline cache at path and loads the accounts stored in
+// it, so they are available before the first FetchAccounts completes. Later
+// fetches only download transactions that are not cached yet.
+func (w *Wallet) OpenCache(path string) error {
+	// The database is locked while it is open, so a previously opened cache
+	// must be closed first in case it is the same file.
+	if w.store != nil {
+		w.store.Close()
+		w.store = nil
 	}
-	accounts, err := w.cl.Accounts("uk_retail")
+
+	store, err := OpenStore(path)
 	if err != nil {
 		return err
 	}
 
-	w.accounts = make([]*Account, 0, len(accounts))
+	accounts, err := store.Accounts()
+	if err != nil {
+		store.Close()
+		return err
+	}
+	w.store = store
+
+	w.mtx.Lock()
+	if len(accounts) > 0 {
+		w.accounts = accounts
+	}
+	w.mtx.Unlock()
+	return nil
+}
+
+// Alerted reports whether the alert with key was recorded in the cache with
+// SetAlerted. It is always false while no cache is open.
+func (w *Wallet) Alerted(key string) (bool, error) {
+	w.mtx.RLock()
+	store := w.store
+	w.mtx.RUnlock()
+	if store == nil {
+		return false, nil
+	}
+	return store.Alerted(key)
+}
+
+// SetAlerted records in the cache that the alert with key was raised at the
+// given time, so that it is not raised again in later runs. Nothing is
+// recorded while no cache is open.
+func (w *Wallet) SetAlerted(key string, at time.Time) error {
+	w.mtx.RLock()
+	store := w.store
+	w.mtx.RUnlock()
+	if store == nil {
+		return nil
+	}
+	return store.SetAlerted(key, at)
+}
+
+func (w *Wallet) LoadedWallet() bool {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+	return w.accounts != nil
+}
+
+func (w *Wallet) Shutdown() {
+	// Wait for a sync in progress before closing the cache it writes to.
+	w.syncer.Stop()
+	w.syncer.syncMtx.Lock()
+	defer w.syncer.syncMtx.Unlock()
+
+	if w.store != nil {
+		w.store.Close()
+		w.store = nil
+	}
+
+	w.mtx.Lock()
+	if w.webhookReceiver != nil {
+		w.webhookReceiver.Close()
+		w.webhookReceiver = nil
+	}
+	w.tokenSources = make(map[string]oauth2.TokenSource)
+	w.accounts = nil
+	w.SelectedAccount = nil
+	w.mtx.Unlock()
+}
+
+// FetchAccounts loads the accounts, balances and transactions of the
+// authenticated profiles through the provider. If a cache is open, only the
+// transactions newer than the last cached one are downloaded and the cache is
+// updated. A profile that fails to load does not stop the others: the error
+// is returned as ProfileErrors, listing a *ProfileError for every failed
+// profile, and the accounts loaded before are kept for those profiles. Such
+// an error wraps ErrTokenRevoked if the token source of a profile can no
+// longer supply a valid token and a new login is required. Nothing is
+// changed if no profile could be loaded.
+func (w *Wallet) FetchAccounts() error {
+	return w.fetchAccounts(nil)
+}
+
+// profileAccount is an account to fetch with the provider of its profile.
+type profileAccount struct {
+	account  *Account
+	profile  *Profile
+	provider Provider
+}
 
-	for _, account := range accounts {
+// fetchAccounts is FetchAccounts, calling progress after each account has
+// been fetched if it is not nil.
+func (w *Wallet) fetchAccounts(progress func(account *Account, done, total int)) error {
+	w.mtx.RLock()
+	profiles := w.profiles
+	previous := w.accounts
+	tokenSources := make(map[string]oauth2.TokenSource, len(profiles))
+	for _, profile := range profiles {
+		tokenSources[profile.ID] = w.tokenSources[profile.ID]
+	}
+	w.mtx.RUnlock()
 
-		balance, err := w.cl.Balance(account.ID)
+	// The accounts of all profiles are listed first, so that the progress
+	// can be reported against the total.
+	var (
+		list          []profileAccount
+		authenticated int
+		failed        ProfileErrors
+	)
+	seen := make(map[string]bool)
+	for _, profile := range profiles {
+		ts := tokenSources[profile.ID]
+		if ts == nil {
+			continue
+		}
+		authenticated++
+		accounts, err := w.listAccounts(profile, ts, seen)
 		if err != nil {
-			return err
+			failed = append(failed, &ProfileError{Profile: profile, Err: err})
+			continue
 		}
+		list = append(list, accounts...)
+	}
+	if authenticated == 0 {
+		return errors.New("wallet is not authenticated")
+	}
 
-		mTransactions, err := w.cl.Transactions(account.ID, false)
-		if err != nil {
+	fetched := make(map[string]Accounts)
+	loaded := make(map[string]bool)
+	for i, item := range list {
+		if failed.Failed(item.profile.ID) != nil {
+			continue
+		}
+		if err := w.fetchAccount(item); err != nil {
+			failed = append(failed, &ProfileError{Profile: item.profile, Err: err})
+			continue
+		}
+		fetched[item.profile.ID] = append(fetched[item.profile.ID], item.account)
+		loaded[item.account.ID] = true
+		if progress != nil {
+			progress(item.account, i+1, len(list))
+		}
+	}
+	if len(failed) == authenticated {
+		return failed
+	}
+
+	// The profiles that failed or are not authenticated keep the accounts
+	// loaded before, unless another profile loaded them, e.g. a joint
+	// account.
+	accounts := make(Accounts, 0, len(list))
+	for _, profile := range profiles {
+		if tokenSources[profile.ID] != nil && failed.Failed(profile.ID) == nil {
+			accounts = append(accounts, fetched[profile.ID]...)
+			continue
+		}
+		for _, account := range previous {
+			if accountProfileID(account) == profile.ID && !loaded[account.ID] {
+				accounts = append(accounts, account)
+			}
+		}
+	}
+
+	if w.store != nil {
+		if err := w.store.SaveAccounts(accounts); err != nil {
 			return err
 		}
+	}
+
+	w.mtx.Lock()
+	w.accounts = accounts
+	w.mtx.Unlock()
+
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// listAccounts lists the accounts of the profile that are not in seen yet and
+// adds them to seen if the profile could be listed.
+func (w *Wallet) listAccounts(profile *Profile, ts oauth2.TokenSource, seen map[string]bool) ([]profileAccount, error) {
+	token, err := ts.Token()
+	if err != nil {
+		return nil, err
+	}
+	provider := w.providerFor(token.AccessToken)
 
-		transactions := make([]*Transaction, 0, len(mTransactions))
-		for _, transaction := range mTransactions {
-			transactions = append(transactions, &Transaction{
-				ID:       transaction.ID,
-				Amount:   float64(transaction.Amount),
-				Created:  transaction.Created,
-				Merchant: transaction.Merchant.Name,
-			})
+	var list []profileAccount
+	listed := make(map[string]bool)
+	for _, accountType := range profile.AccountTypes {
+		accounts, err := provider.Accounts(accountType)
+		if err != nil {
+			return nil, err
 		}
+		for _, account := range accounts {
+			// A joint account is listed by the profiles of both
+			// holders.
+			if seen[account.ID] || listed[account.ID] {
+				continue
+			}
+			listed[account.ID] = true
+			account.ProfileID = profile.ID
+			account.Type = accountType
+			list = append(list, profileAccount{account: account, profile: profile, provider: provider})
+		}
+	}
+
+	for id := range listed {
+		seen[id] = true
+	}
+	return list, nil
+}
 
-		w.accounts = append(w.accounts, &Account{
-			ID:            account.ID,
-			Created:       account.Created,
-			AccountNumber: account.AccountNumber,
-			Balance:       float64(balance.Balance),
-			Transactions:  transactions,
-		})
+// fetchAccount loads the balance, transactions, pots and mandates of the
+// account.
+func (w *Wallet) fetchAccount(item profileAccount) error {
+	account := item.account
+	balance, err := item.provider.Balance(account.ID)
+	if err != nil {
+		return err
+	}
+	account.Balance = balance.Balance
+	account.SpendToday = balance.SpendToday
+
+	if account.Transactions, err = w.fetchTransactions(item.provider, account.ID); err != nil {
+		return err
 	}
 
+	if account.Pots, err = item.provider.Pots(account.ID); err != nil {
+		return err
+	}
+
+	account.Mandates, err = item.provider.Mandates(account.ID)
+	if err != nil && !errors.Is(err, ErrMandatesUnsupported) {
+		return err
+	}
 	return nil
 }
 
+// accountProfileID returns the ID of the profile the account was loaded
+// with. Accounts cached before profiles existed belong to the default one.
+func accountProfileID(account *Account) string {
+	if account.ProfileID == "" {
+		return DefaultProfileID
+	}
+	return account.ProfileID
+}
+
+// providerFor returns a provider authenticated with accessToken.
+func (w *Wallet) providerFor(accessToken string) Provider {
+	if w.newProvider == nil {
+		w.newProvider = MonzoProvider(MonzoBaseURL)
+	}
+	return w.newProvider(accessToken)
+}
+
+// fetchTransactions downloads the transactions of the account that are not
+// cached yet and returns the full history.
+func (w *Wallet) fetchTransactions(provider Provider, accountID string) ([]*Transaction, error) {
+	if w.store == nil {
+		return provider.Transactions(accountID, "")
+	}
+
+	since, err := w.syncStart(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := provider.Transactions(accountID, since)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.store.AddTransactions(accountID, transactions); err != nil {
+		return nil, err
+	}
+	if len(transactions) > 0 {
+		if err := w.store.SetSyncedUntil(accountID, transactions[len(transactions)-1].ID); err != nil {
+			return nil, err
+		}
+	}
+	return w.store.Transactions(accountID)
+}
+
+// syncStart returns the since argument for Provider.Transactions that
+// fetches the transactions of the account that are not cached yet, and the
+// recent ones that were still pending when they were cached.
+func (w *Wallet) syncStart(accountID string) (string, error) {
+	pending, err := w.store.OldestPending(accountID, time.Now().Add(-pendingWindow))
+	if err != nil {
+		return "", err
+	}
+	if pending != nil {
+		// Timestamps are inclusive, unlike IDs, so the pending
+		// transaction is fetched again.
+		return pending.Created.UTC().Format(time.RFC3339), nil
+	}
+
+	// Transactions received through webhooks are not used as the start,
+	// since those created before them may not be fetched yet.
+	synced, err := w.store.SyncedUntil(accountID)
+	if err != nil || synced != "" {
+		return synced, err
+	}
+
+	// Caches written before the synced transaction was recorded.
+	last, err := w.store.LastTransaction(accountID)
+	if err != nil || last == nil {
+		return "", err
+	}
+	return last.ID, nil
+}
+
 func (w *Wallet) AccountsList() Accounts {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
 	return w.accounts
 }
 
-func openbrowser(url string) error {
-	var err error
-
-	switch runtime.GOOS {
-	case "linux":
-		err = exec.Command("xdg-open", url).Start()
-	case "windows":
-		err = exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
-	case "darwin":
-		err = exec.Command("open", url).Start()
-	default:
-		err = fmt.Errorf("unsupported platform")
+// NewDedupeID returns a random idempotency key for Deposit and Withdraw.
+// The same key should be passed when a request is retried, so that the money
+// is only moved once.
+func NewDedupeID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
 	}
+	return hex.EncodeToString(b)
+}
+
+// Deposit moves amount from the account into one of its pots. dedupeID
+// identifies the deposit, see NewDedupeID. The pot is updated in place and a
+// sync is started to pick up the new balance and transaction.
+func (w *Wallet) Deposit(accountID, potID string, amount Money, dedupeID string) error {
+	return w.movePotMoney(accountID, potID, amount, dedupeID, false)
+}
 
-	return err
+// Withdraw moves amount from a pot back into its account. Locked pots cannot
+// be withdrawn from and return ErrPotLocked without calling the API.
+// dedupeID identifies the withdrawal, see NewDedupeID.
+func (w *Wallet) Withdraw(accountID, potID string, amount Money, dedupeID string) error {
+	return w.movePotMoney(accountID, potID, amount, dedupeID, true)
 }
 
-func generateRandomState() string {
-	randomBytes := make([]byte, 32)
-	_, err := rand.Read(randomBytes)
+func (w *Wallet) movePotMoney(accountID, potID string, amount Money, dedupeID string, withdraw bool) error {
+	if amount.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if dedupeID == "" {
+		return errors.New("missing dedupe ID")
+	}
 
+	w.mtx.RLock()
+	account, i := w.findPot(accountID, potID)
+	var (
+		pot *Pot
+		ts  oauth2.TokenSource
+	)
+	if account != nil {
+		pot = account.Pots[i]
+		ts = w.tokenSources[account.ProfileID]
+		if account.ProfileID == "" {
+			ts = w.tokenSources[DefaultProfileID]
+		}
+	}
+	w.mtx.RUnlock()
+	if pot == nil {
+		return fmt.Errorf("pot %s not found", potID)
+	}
+	if withdraw && pot.Locked {
+		return ErrPotLocked
+	}
+	if pot.Balance.Currency != "" && amount.Currency != pot.Balance.Currency {
+		return ErrCurrencyMismatch
+	}
+	provider, err := w.providerForTokenSource(ts)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	move := provider.Deposit
+	if withdraw {
+		move = provider.Withdraw
+	}
+	updated, err := move(potID, accountID, amount, dedupeID)
+	if err != nil {
+		return err
 	}
 
-	return hex.EncodeToString(randomBytes)
+	// The pot is replaced rather than modified, since the UI may be reading
+	// it.
+	w.mtx.Lock()
+	if account, i := w.findPot(accountID, potID); account != nil {
+		pots := append([]*Pot(nil), account.Pots...)
+		pots[i] = updated
+		account.Pots = pots
+	}
+	w.mtx.Unlock()
+
+	w.syncer.SyncNow()
+	return nil
+}
+
+// findPot returns the account with the given ID and the index of the pot in
+// its Pots, or nil if either is not found. w.mtx must be held.
+func (w *Wallet) findPot(accountID, potID string) (*Account, int) {
+	for _, account := range w.accounts {
+		if account.ID != accountID {
+			continue
+		}
+		for i, pot := range account.Pots {
+			if pot.ID == potID {
+				return account, i
+			}
+		}
+	}
+	return nil, -1
 }
@@ -0,0 +1,320 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// DefaultWebhookAddr is the local address the webhook receiver listens on if
+// the config does not set one.
+const DefaultWebhookAddr = "127.0.0.1:8082"
+
+const (
+	// webhookPathPrefix prefixes the path of the receiver. The path ends in a
+	// random secret, so that only the API, which is told the URL, can post
+	// events to it.
+	webhookPathPrefix = "/webhook/"
+	// maxWebhookBody limits the size of a posted event.
+	maxWebhookBody = 1 << 20
+	// eventTransactionCreated is the type of the event posted for every new
+	// transaction.
+	eventTransactionCreated = "transaction.created"
+)
+
+// Webhook is a URL the API posts the events of an account to.
+type Webhook struct {
+	ID        string `json:"id"`
+	AccountID string `json:"account_id"`
+	URL       string `json:"url"`
+}
+
+// webhookEvent is the body of a request posted to a webhook.
+type webhookEvent struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// webhookTransaction is the data of a transaction.created event: the
+// transaction as returned by the API plus its account.
+type webhookTransaction struct {
+	monzoTransaction
+	AccountID string `json:"account_id"`
+}
+
+// WebhookReceiver is an HTTP server that receives the transaction.created
+// events of a wallet's accounts and adds the transactions to the wallet, see
+// Wallet.StartWebhooks.
+type WebhookReceiver struct {
+	wallet    *Wallet
+	publicURL string
+	secret    string
+	listener  net.Listener
+	server    *http.Server
+}
+
+// NewWebhookReceiver listens on addr for the events of the accounts of
+// wallet. publicURL is the URL at which the API reaches addr, e.g. that of a
+// tunnel. Start serves the events.
+func NewWebhookReceiver(wallet *Wallet, addr, publicURL string) (*WebhookReceiver, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &WebhookReceiver{
+		wallet:    wallet,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		secret:    hex.EncodeToString(b),
+		listener:  listener,
+	}
+	r.server = &http.Server{
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return r, nil
+}
+
+// Start serves the events in the background until Close is called.
+func (r *WebhookReceiver) Start() {
+	go func() {
+		if err := r.server.Serve(r.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Info("webhook receiver stopped:", err)
+		}
+	}()
+}
+
+// URL returns the URL to register with the API.
+func (r *WebhookReceiver) URL() string {
+	return r.publicURL + webhookPathPrefix + r.secret
+}
+
+// LocalURL returns the URL of the receiver on the local address, e.g. to post
+// events to it by hand.
+func (r *WebhookReceiver) LocalURL() string {
+	return "http://" + r.listener.Addr().String() + webhookPathPrefix + r.secret
+}
+
+// Close stops the receiver.
+func (r *WebhookReceiver) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return r.server.Shutdown(ctx)
+}
+
+// ServeHTTP validates a posted event and adds the transaction of a
+// transaction.created event to the wallet. Other events are acknowledged and
+// ignored, so that the API does not retry them.
+func (r *WebhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	secret := strings.TrimPrefix(req.URL.Path, webhookPathPrefix)
+	if !strings.HasPrefix(req.URL.Path, webhookPathPrefix) ||
+		subtle.ConstantTimeCompare([]byte(secret), []byte(r.secret)) != 1 {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "reading body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var event webhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "invalid event: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if event.Type != eventTransactionCreated {
+		logrus.Infof("ignoring webhook event %q", event.Type)
+		return
+	}
+
+	accountID, transaction, err := parseWebhookTransaction(event.Data)
+	if err != nil {
+		http.Error(w, "invalid transaction: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := r.wallet.ReceiveTransaction(accountID, transaction); err != nil {
+		logrus.Info("receiving webhook transaction:", err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+}
+
+// parseWebhookTransaction decodes the data of a transaction.created event and
+// checks the fields the wallet relies on.
+func parseWebhookTransaction(data json.RawMessage) (string, *Transaction, error) {
+	var t webhookTransaction
+	if err := json.Unmarshal(data, &t); err != nil {
+		return "", nil, err
+	}
+	switch {
+	case t.AccountID == "":
+		return "", nil, errors.New("missing account_id")
+	case t.ID == "":
+		return "", nil, errors.New("missing id")
+	case t.Currency == "":
+		return "", nil, errors.New("missing currency")
+	}
+	transaction := t.toTransaction()
+	if transaction.Created.IsZero() {
+		return "", nil, fmt.Errorf("invalid created time %q", t.Created)
+	}
+	return t.AccountID, transaction, nil
+}
+
+// ReceiveTransaction adds a transaction received outside of a sync, e.g.
+// through a webhook, to the account with the given ID and to the cache. A
+// TransactionReceived event is published unless the transaction was known
+// already.
+func (w *Wallet) ReceiveTransaction(accountID string, transaction *Transaction) error {
+	w.mtx.Lock()
+	var account *Account
+	for _, a := range w.accounts {
+		if a.ID == accountID {
+			account = a
+			break
+		}
+	}
+	if account == nil {
+		w.mtx.Unlock()
+		return fmt.Errorf("unknown account %s", accountID)
+	}
+
+	// The transactions are replaced rather than modified, since the UI may
+	// be reading them.
+	isNew := true
+	transactions := append([]*Transaction(nil), account.Transactions...)
+	for i, t := range transactions {
+		if t.ID == transaction.ID {
+			transactions[i] = transaction
+			isNew = false
+			break
+		}
+	}
+	if isNew {
+		transactions = append(transactions, transaction)
+	}
+	account.Transactions = transactions
+	store := w.store
+	w.mtx.Unlock()
+
+	if store != nil {
+		if err := store.AddTransactions(accountID, []*Transaction{transaction}); err != nil {
+			return err
+		}
+	}
+	if isNew {
+		w.syncer.notify(TransactionReceived{AccountID: accountID, Transaction: transaction})
+	}
+	return nil
+}
+
+// StartWebhooks starts a WebhookReceiver on addr and registers it with the API
+// for every account, replacing the webhooks registered by earlier runs.
+// publicURL is the URL at which the API reaches addr. The receiver runs until
+// Shutdown; calling StartWebhooks again replaces it.
+func (w *Wallet) StartWebhooks(addr, publicURL string) error {
+	w.mtx.Lock()
+	if w.webhookReceiver != nil {
+		w.webhookReceiver.Close()
+		w.webhookReceiver = nil
+	}
+	w.mtx.Unlock()
+
+	receiver, err := NewWebhookReceiver(w, addr, publicURL)
+	if err != nil {
+		return err
+	}
+	receiver.Start()
+	logrus.Infof("receiving webhooks at %s", receiver.LocalURL())
+
+	w.mtx.Lock()
+	w.webhookReceiver = receiver
+	accounts := w.accounts
+	w.mtx.Unlock()
+
+	stalePrefix := strings.TrimSuffix(publicURL, "/") + webhookPathPrefix
+	for _, account := range accounts {
+		provider, err := w.providerForAccount(account)
+		if err != nil {
+			return err
+		}
+		if err := registerWebhook(provider, account.ID, receiver.URL(), stalePrefix); err != nil {
+			return fmt.Errorf("registering webhook for %s: %w", account.ID, err)
+		}
+	}
+	return nil
+}
+
+// registerWebhook registers url for the account unless it is already, and
+// deletes the other webhooks whose URL starts with stalePrefix.
+func registerWebhook(provider Provider, accountID, url, stalePrefix string) error {
+	webhooks, err := provider.Webhooks(accountID)
+	if err != nil {
+		return err
+	}
+	registered := false
+	for _, webhook := range webhooks {
+		switch {
+		case webhook.URL == url:
+			registered = true
+		case strings.HasPrefix(webhook.URL, stalePrefix):
+			if err := provider.DeleteWebhook(webhook.ID); err != nil {
+				return err
+			}
+		}
+	}
+	if registered {
+		return nil
+	}
+	_, err = provider.RegisterWebhook(accountID, url)
+	return err
+}
+
+// providerForAccount returns a provider authenticated for the profile of the
+// account.
+func (w *Wallet) providerForAccount(account *Account) (Provider, error) {
+	profileID := account.ProfileID
+	if profileID == "" {
+		profileID = DefaultProfileID
+	}
+
+	w.mtx.RLock()
+	ts := w.tokenSources[profileID]
+	w.mtx.RUnlock()
+	return w.providerForTokenSource(ts)
+}
+
+// providerForTokenSource returns a provider authenticated with a token from
+// ts.
+func (w *Wallet) providerForTokenSource(ts oauth2.TokenSource) (Provider, error) {
+	if ts == nil {
+		return nil, errors.New("wallet is not authenticated")
+	}
+	token, err := ts.Token()
+	if err != nil {
+		return nil, err
+	}
+	return w.providerFor(token.AccessToken), nil
+}
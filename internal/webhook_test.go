@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-monzo-wallet/internal/fakemonzo"
+	"golang.org/x/oauth2"
+)
+
+// webhookAccountID is the account of fakemonzo.WebhookTransactionCreated.
+const webhookAccountID = "acc_00009237aqC8c5umZmrRdh"
+
+// startWebhookReceiver serves a receiver for a wallet loaded from the fake
+// server and returns the URL to post its events to.
+func startWebhookReceiver(t *testing.T) (*Wallet, string) {
+	t.Helper()
+	fake, api, _ := startFakeMonzo(t)
+	w := NewWallet(MonzoProvider(api.URL))
+	w.Authenticate(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: fake.IssueToken()}))
+	if err := w.FetchAccounts(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewWebhookReceiver(w, "127.0.0.1:0", "https://example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The receiver is served by httptest rather than on its own listener.
+	r.listener.Close()
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return w, srv.URL + webhookPathPrefix + r.secret
+}
+
+// webhookAccount returns the account of the webhook event in w.
+func webhookAccount(t *testing.T, w *Wallet) *Account {
+	t.Helper()
+	for _, account := range w.AccountsList() {
+		if account.ID == webhookAccountID {
+			return account
+		}
+	}
+	t.Fatalf("the fake server has no account %s", webhookAccountID)
+	return nil
+}
+
+func postEvent(t *testing.T, url string, body []byte) int {
+	t.Helper()
+	rsp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	return rsp.StatusCode
+}
+
+func TestWebhookTransactionCreated(t *testing.T) {
+	body, err := fakemonzo.WebhookTransactionCreated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, url := startWebhookReceiver(t)
+	before := len(webhookAccount(t, w).Transactions)
+	events, unsubscribe := w.Syncer().Subscribe()
+	defer unsubscribe()
+
+	if status := postEvent(t, url, body); status != http.StatusOK {
+		t.Fatalf("posting the event returned status %d, want %d", status, http.StatusOK)
+	}
+
+	transactions := webhookAccount(t, w).Transactions
+	if len(transactions) != before+1 {
+		t.Fatalf("the account has %d transactions, want %d", len(transactions), before+1)
+	}
+	var transaction *Transaction
+	for _, tx := range transactions {
+		if tx.ID == "tx_0000WebhookFixture0001" {
+			transaction = tx
+		}
+	}
+	if transaction == nil || transaction.Amount != NewMoney(-450, "GBP") || transaction.Title() != "Pret A Manger" {
+		t.Errorf("stored transaction %s of %v at %q, want the one of the event",
+			transaction.ID, transaction.Amount, transaction.Title())
+	}
+
+	select {
+	case event := <-events:
+		received, ok := event.(TransactionReceived)
+		if !ok || received.AccountID != webhookAccountID || received.Transaction != transaction {
+			t.Errorf("got event %#v, want TransactionReceived for the stored transaction", event)
+		}
+	case <-time.After(time.Second):
+		t.Error("no TransactionReceived event was published")
+	}
+}
+
+func TestWebhookRejectsEvents(t *testing.T) {
+	body, err := fakemonzo.WebhookTransactionCreated()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownAccount := []byte(strings.Replace(string(body), webhookAccountID, "acc_unknown", 1))
+
+	tests := []struct {
+		name   string
+		path   func(url string) string
+		body   []byte
+		status int
+	}{
+		{
+			name:   "wrong secret",
+			path:   func(url string) string { return url[:strings.LastIndex(url, "/")+1] + "wrong" },
+			body:   body,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "outside the webhook path",
+			path:   func(url string) string { return url[:strings.Index(url, webhookPathPrefix)] + "/" },
+			body:   body,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "unknown account",
+			path:   func(url string) string { return url },
+			body:   unknownAccount,
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w, url := startWebhookReceiver(t)
+			before := len(webhookAccount(t, w).Transactions)
+			events, unsubscribe := w.Syncer().Subscribe()
+			defer unsubscribe()
+
+			if status := postEvent(t, test.path(url), test.body); status != test.status {
+				t.Errorf("posting the event returned status %d, want %d", status, test.status)
+			}
+			if n := len(webhookAccount(t, w).Transactions); n != before {
+				t.Errorf("the account has %d transactions, want the %d loaded before", n, before)
+			}
+			select {
+			case event := <-events:
+				t.Errorf("got event %#v for a rejected event", event)
+			default:
+			}
+		})
+	}
+}
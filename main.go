@@ -1,18 +1,42 @@
 package main
 
 import (
+	"flag"
 	"gioui.org/app"
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/internal/cli"
 	"go-monzo-wallet/ui"
 	"os"
 )
 
 func main() {
+	// Subcommands run without a window, so they work without a display
+	// server, e.g. over SSH. This binary still needs cgo and the display
+	// server libraries to build; cmd/go-monzo-wallet-cli runs the same
+	// subcommands without them.
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
+	// The window only takes the flags that override the config.
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	internal.AddConfigFlags(flags)
+	flags.Parse(os.Args[1:])
 
 	win, err := ui.CreateWindow()
 	if err != nil {
 		os.Exit(1)
 	}
 
+	// A monzo-wallet:// link, e.g. from the desktop entry that handles the
+	// scheme, opens the app at the page it leads to.
+	for _, arg := range flags.Args() {
+		if internal.IsDeepLink(arg) {
+			win.OpenLink(arg)
+			break
+		}
+	}
+
 	go func() {
 		win.HandleEvents() // blocks until the app window is closed
 		os.Exit(0)
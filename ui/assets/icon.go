@@ -50,3 +50,9 @@ func ReadIcons() (map[string]image.Image, error) {
 
 	return decredIcons, nil
 }
+
+// ReadIcon returns the PNG data of the icon with the given name, e.g. to
+// hand it to the OS.
+func ReadIcon(name string) ([]byte, error) {
+	return content.ReadFile("icons/" + name + ".png")
+}
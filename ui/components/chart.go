@@ -0,0 +1,311 @@
+package components
+
+import (
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+	"go-monzo-wallet/ui/values"
+	"image"
+	"image/color"
+	"math"
+)
+
+// ChartSeries is a named set of values drawn in one color, e.g. the income of
+// every month.
+type ChartSeries struct {
+	Name   string
+	Color  color.NRGBA
+	Values []float64
+}
+
+// ChartSlice is a slice of a DonutChart.
+type ChartSlice struct {
+	Label string
+	Color color.NRGBA
+	Value float64
+}
+
+// BarChart draws the values of its series as groups of bars, one group per
+// label, e.g. income next to outgoings for every month. Negative values are
+// not drawn.
+type BarChart struct {
+	theme  *Theme
+	Height unit.Dp
+	Labels []string
+	Series []ChartSeries
+}
+
+// LineChart draws each of its series as a line over evenly spaced points,
+// e.g. a daily balance. Only the first and last labels are shown.
+type LineChart struct {
+	theme  *Theme
+	Height unit.Dp
+	Labels []string
+	Series []ChartSeries
+}
+
+// DonutChart draws its slices as a ring with a legend, e.g. the spending by
+// category.
+type DonutChart struct {
+	theme  *Theme
+	Size   unit.Dp
+	Slices []ChartSlice
+}
+
+// BarChart returns a bar chart of the series with one group per label.
+func (t *Theme) BarChart(labels []string, series ...ChartSeries) BarChart {
+	return BarChart{theme: t, Height: values.MarginPadding150, Labels: labels, Series: series}
+}
+
+// LineChart returns a line chart of the series.
+func (t *Theme) LineChart(labels []string, series ...ChartSeries) LineChart {
+	return LineChart{theme: t, Height: values.MarginPadding150, Labels: labels, Series: series}
+}
+
+// DonutChart returns a donut chart of the slices.
+func (t *Theme) DonutChart(slices []ChartSlice) DonutChart {
+	return DonutChart{theme: t, Size: values.MarginPadding150, Slices: slices}
+}
+
+// ChartColors returns the colors given to series and slices in order. Charts
+// with more entries reuse them.
+func (t *Theme) ChartColors() []color.NRGBA {
+	return []color.NRGBA{
+		t.Color.Primary,
+		t.Color.Turquoise700,
+		t.Color.Orange,
+		t.Color.Green500,
+		t.Color.Yellow,
+		t.Color.Danger,
+		t.Color.LightBlue6,
+		t.Color.Gray3,
+	}
+}
+
+func (c BarChart) Layout(gtx C) D {
+	max := 0.0
+	for _, series := range c.Series {
+		for _, v := range series.Values {
+			max = math.Max(max, v)
+		}
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			size := image.Pt(gtx.Constraints.Max.X, gtx.Dp(c.Height))
+			if len(c.Labels) == 0 || len(c.Series) == 0 {
+				return D{Size: size}
+			}
+
+			groupWidth := float32(size.X) / float32(len(c.Labels))
+			// A quarter of each group is left as the gap to the next.
+			barWidth := groupWidth * 0.75 / float32(len(c.Series))
+			for i := range c.Labels {
+				for j, series := range c.Series {
+					if i >= len(series.Values) || series.Values[i] <= 0 || max == 0 {
+						continue
+					}
+					height := float32(size.Y) * float32(series.Values[i]/max)
+					x := groupWidth*float32(i) + groupWidth*0.125 + barWidth*float32(j)
+					bar := image.Rect(int(x), size.Y-int(height), int(x+barWidth), size.Y)
+					paint.FillShape(gtx.Ops, series.Color, clip.UniformRRect(bar, gtx.Dp(values.MarginPadding2)).Op(gtx.Ops))
+				}
+			}
+			return D{Size: size}
+		}),
+		layout.Rigid(c.theme.Separator().Layout),
+		layout.Rigid(func(gtx C) D {
+			labels := make([]layout.FlexChild, len(c.Labels))
+			for i, label := range c.Labels {
+				lbl := c.theme.Caption(label)
+				lbl.Color = c.theme.Color.GrayText2
+				labels[i] = layout.Flexed(1, func(gtx C) D {
+					return layout.N.Layout(gtx, lbl.Layout)
+				})
+			}
+			return layout.Inset{Top: values.MarginPadding4}.Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, labels...)
+			})
+		}),
+		layout.Rigid(func(gtx C) D {
+			return chartLegend(gtx, c.theme, seriesLegend(c.Series))
+		}),
+	)
+}
+
+func (c LineChart) Layout(gtx C) D {
+	min, max := math.Inf(1), math.Inf(-1)
+	points := 0
+	for _, series := range c.Series {
+		for _, v := range series.Values {
+			min, max = math.Min(min, v), math.Max(max, v)
+		}
+		if len(series.Values) > points {
+			points = len(series.Values)
+		}
+	}
+	if max == min {
+		// Flat lines are drawn in the middle.
+		min, max = min-1, max+1
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			size := image.Pt(gtx.Constraints.Max.X, gtx.Dp(c.Height))
+			if points < 2 {
+				return D{Size: size}
+			}
+
+			// The zero line is drawn if it is in range, e.g. for an
+			// overdrawn balance.
+			if min < 0 && max > 0 {
+				y := float32(size.Y) * float32(max/(max-min))
+				zero := image.Rect(0, int(y), size.X, int(y)+1)
+				paint.FillShape(gtx.Ops, c.theme.Color.Gray3, clip.Rect(zero).Op())
+			}
+
+			width := float32(gtx.Dp(values.MarginPadding2))
+			step := float32(size.X) / float32(points-1)
+			for _, series := range c.Series {
+				if len(series.Values) < 2 {
+					continue
+				}
+				var path clip.Path
+				path.Begin(gtx.Ops)
+				for i, v := range series.Values {
+					pt := f32.Pt(step*float32(i), float32(size.Y)*float32((max-v)/(max-min)))
+					if i == 0 {
+						path.MoveTo(pt)
+					} else {
+						path.LineTo(pt)
+					}
+				}
+				paint.FillShape(gtx.Ops, series.Color, clip.Stroke{Path: path.End(), Width: width}.Op())
+			}
+			return D{Size: size}
+		}),
+		layout.Rigid(c.theme.Separator().Layout),
+		layout.Rigid(func(gtx C) D {
+			if len(c.Labels) == 0 {
+				return D{}
+			}
+			first := c.theme.Caption(c.Labels[0])
+			last := c.theme.Caption(c.Labels[len(c.Labels)-1])
+			first.Color, last.Color = c.theme.Color.GrayText2, c.theme.Color.GrayText2
+			return layout.Inset{Top: values.MarginPadding4}.Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+					layout.Rigid(first.Layout),
+					layout.Flexed(1, func(gtx C) D { return D{} }),
+					layout.Rigid(last.Layout),
+				)
+			})
+		}),
+		layout.Rigid(func(gtx C) D {
+			if len(c.Series) < 2 {
+				return D{}
+			}
+			return chartLegend(gtx, c.theme, seriesLegend(c.Series))
+		}),
+	)
+}
+
+func (c DonutChart) Layout(gtx C) D {
+	total := 0.0
+	for _, slice := range c.Slices {
+		if slice.Value > 0 {
+			total += slice.Value
+		}
+	}
+
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			size := gtx.Dp(c.Size)
+			thickness := float32(size) / 5
+			radius := (float32(size) - thickness) / 2
+			center := f32.Pt(float32(size)/2, float32(size)/2)
+
+			if total == 0 {
+				inset := int(thickness / 2)
+				ring := clip.Ellipse{Min: image.Pt(inset, inset), Max: image.Pt(size-inset, size-inset)}
+				paint.FillShape(gtx.Ops, c.theme.Color.Gray2, clip.Stroke{Path: ring.Path(gtx.Ops), Width: thickness}.Op())
+				return D{Size: image.Pt(size, size)}
+			}
+
+			// Slices start at the top and go clockwise.
+			angle := -math.Pi / 2
+			for _, slice := range c.Slices {
+				if slice.Value <= 0 {
+					continue
+				}
+				sweep := 2 * math.Pi * slice.Value / total
+				var path clip.Path
+				path.Begin(gtx.Ops)
+				path.MoveTo(f32.Pt(
+					center.X+radius*float32(math.Cos(angle)),
+					center.Y+radius*float32(math.Sin(angle)),
+				))
+				path.ArcTo(center, center, float32(sweep))
+				paint.FillShape(gtx.Ops, slice.Color, clip.Stroke{Path: path.End(), Width: thickness}.Op())
+				angle += sweep
+			}
+			return D{Size: image.Pt(size, size)}
+		}),
+		layout.Flexed(1, func(gtx C) D {
+			return layout.Inset{Left: values.MarginPadding16}.Layout(gtx, func(gtx C) D {
+				return chartLegendList(gtx, c.theme, c.Slices)
+			})
+		}),
+	)
+}
+
+func seriesLegend(series []ChartSeries) []ChartSlice {
+	legend := make([]ChartSlice, len(series))
+	for i, s := range series {
+		legend[i] = ChartSlice{Label: s.Name, Color: s.Color}
+	}
+	return legend
+}
+
+// chartLegend lays out the labels of the entries in a row.
+func chartLegend(gtx C, th *Theme, entries []ChartSlice) D {
+	items := make([]layout.FlexChild, len(entries))
+	for i, entry := range entries {
+		entry := entry
+		items[i] = layout.Rigid(func(gtx C) D {
+			return layout.Inset{Top: values.MarginPadding8, Right: values.MarginPadding16}.Layout(gtx, func(gtx C) D {
+				return legendEntry(gtx, th, entry)
+			})
+		})
+	}
+	return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, items...)
+}
+
+// chartLegendList lays out the labels of the entries below each other.
+func chartLegendList(gtx C, th *Theme, entries []ChartSlice) D {
+	items := make([]layout.FlexChild, len(entries))
+	for i, entry := range entries {
+		entry := entry
+		items[i] = layout.Rigid(func(gtx C) D {
+			return layout.Inset{Bottom: values.MarginPadding4}.Layout(gtx, func(gtx C) D {
+				return legendEntry(gtx, th, entry)
+			})
+		})
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, items...)
+}
+
+func legendEntry(gtx C, th *Theme, entry ChartSlice) D {
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			size := gtx.Dp(values.MarginPadding10)
+			box := image.Rect(0, 0, size, size)
+			paint.FillShape(gtx.Ops, entry.Color, clip.UniformRRect(box, size/4).Op(gtx.Ops))
+			return D{Size: box.Max}
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Left: values.MarginPadding4}.Layout(gtx, th.Caption(entry.Label).Layout)
+		}),
+	)
+}
@@ -93,6 +93,19 @@ func (d *DropDown) SelectedIndex() int {
 	return d.selectedIndex
 }
 
+// SetSelectedIndex selects the item at index, e.g. to preselect a default,
+// without Changed reporting it.
+func (d *DropDown) SetSelectedIndex(index int) {
+	if index >= 0 && index < len(d.items) {
+		d.selectedIndex = index
+	}
+}
+
+// IsOpen reports whether the menu of the dropdown is shown.
+func (d *DropDown) IsOpen() bool {
+	return d.isOpen
+}
+
 func (d *DropDown) Len() int {
 	return len(d.items)
 }
@@ -5,9 +5,9 @@ import (
 	"gioui.org/layout"
 	"gioui.org/op"
 	"github.com/gen2brain/beeep"
+	"go-monzo-wallet/ui/assets"
 	"go-monzo-wallet/ui/values"
 	"os"
-	"path"
 	"path/filepath"
 	"sync"
 	"time"
@@ -20,8 +20,11 @@ const (
 	Long
 )
 const (
-	icon  = "ui/assets/decredicons/qrcodeSymbol.png"
-	title = "Godcr"
+	// notificationIcon is the icon shown with system notifications.
+	notificationIcon = "monzo_logo"
+	// appDirName is the directory below the user cache directory the
+	// notification icon is written to.
+	appDirName = "go-monzo-wallet"
 )
 
 type (
@@ -124,41 +127,40 @@ func (t *Toast) handleToastDisplay(gtx layout.Context) {
 	}
 }
 
+// SystemNotification shows notifications through the OS, e.g. for
+// transactions received while the app is in the background.
 type SystemNotification struct {
 	iconPath string
-	message  string
 }
 
+// NewSystemNotification writes the app icon to the user cache directory,
+// where the OS can read it from, and returns a SystemNotification that shows
+// it.
 func NewSystemNotification() (*SystemNotification, error) {
-	absolutePath, err := getAbsolutePath()
+	iconData, err := assets.ReadIcon(notificationIcon)
 	if err != nil {
 		return nil, err
 	}
-
-	return &SystemNotification{
-		iconPath: filepath.Join(absolutePath, icon),
-	}, nil
-}
-
-func (s *SystemNotification) Notify(message string) error {
-	err := beeep.Notify(title, message, s.iconPath)
+	cacheDir, err := os.UserCacheDir()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
-}
-
-func getAbsolutePath() (string, error) {
-	ex, err := os.Executable()
-	if err != nil {
-		return "", fmt.Errorf("error getting executable path: %s", err.Error())
+	dir := filepath.Join(cacheDir, appDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
 	}
-
-	exSym, err := filepath.EvalSymlinks(ex)
-	if err != nil {
-		return "", fmt.Errorf("error getting filepath after evaluating sym links")
+	iconPath := filepath.Join(dir, notificationIcon+".png")
+	if err := os.WriteFile(iconPath, iconData, 0600); err != nil {
+		return nil, fmt.Errorf("writing notification icon: %v", err)
 	}
 
-	return path.Dir(exSym), nil
+	return &SystemNotification{
+		iconPath: iconPath,
+	}, nil
+}
+
+// Notify shows message with the app name as title.
+func (s *SystemNotification) Notify(message string) error {
+	return beeep.Notify(values.String(values.StrAppName), message, s.iconPath)
 }
@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"sync/atomic"
+
 	"go-monzo-wallet/internal"
 	"go-monzo-wallet/ui/components"
 	"golang.org/x/text/message"
 )
 
+// HiddenAmount is shown instead of amounts of money while they are hidden.
+const HiddenAmount = "••••"
+
 type Load struct {
 	Theme *components.Theme
 
@@ -14,8 +19,19 @@ type Load struct {
 	CurrentAppWidth int
 	Toast           *components.Toast
 	WL              *internal.Wallet
+	// SystemNotification is nil if notifications could not be set up.
+	SystemNotification *components.SystemNotification
+	Budgets            *internal.BudgetMonitor
+	Recurring          *internal.RecurringMonitor
+	// privacyMode is 1 in privacy mode, see IsPrivacyMode. It is accessed
+	// atomically as amounts are also formatted outside the UI goroutine,
+	// e.g. for notifications.
+	privacyMode int32
+	// Locker locks the app when it is idle, which hides amounts too.
+	Locker *internal.Locker
 
 	ToggleSync             func()
+	TogglePrivacyMode      func()
 	DarkModeSettingChanged func(bool)
 	LanguageSettingChanged func()
 	CurrencySettingChanged func()
@@ -27,6 +43,32 @@ func (l *Load) RefreshTheme(window *DefaultWindowNavigator) {
 	window.Reload()
 }
 
+// FormatMoney formats an amount of money for display, or returns
+// HiddenAmount in privacy mode or while the app is locked. All balances and
+// amounts shown by the app are formatted with it, apart from those the user
+// enters.
+func (l *Load) FormatMoney(amount internal.Money) string {
+	if l.IsPrivacyMode() || l.Locker.Locked() {
+		return HiddenAmount
+	}
+	return amount.Format(l.Printer)
+}
+
+// IsPrivacyMode reports whether privacy mode is on, in which all amounts of
+// money are replaced with HiddenAmount, e.g. while sharing the screen.
+func (l *Load) IsPrivacyMode() bool {
+	return atomic.LoadInt32(&l.privacyMode) == 1
+}
+
+// SetPrivacyMode switches privacy mode on or off.
+func (l *Load) SetPrivacyMode(on bool) {
+	var privacyMode int32
+	if on {
+		privacyMode = 1
+	}
+	atomic.StoreInt32(&l.privacyMode, privacyMode)
+}
+
 // GetCurrentAppWidth returns the current width of the app's window.
 func (l *Load) GetCurrentAppWidth() int {
 	return l.CurrentAppWidth
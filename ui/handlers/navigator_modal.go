@@ -28,3 +28,10 @@ type Modal interface {
 	// components unless they'll be recreated in the OnResume() method.
 	OnDismiss()
 }
+
+// CancelableModal is implemented by modals that know whether they may be
+// dismissed without choosing one of their buttons.
+type CancelableModal interface {
+	Modal
+	IsCancelable() bool
+}
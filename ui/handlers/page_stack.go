@@ -31,6 +31,13 @@ func (pageStack *PageStack) Top() Page {
 	return nil
 }
 
+// Len returns the number of pages in the stack.
+func (pageStack *PageStack) Len() int {
+	pageStack.mtx.Lock()
+	defer pageStack.mtx.Unlock()
+	return len(pageStack.pages)
+}
+
 // Push pushes the specified page to the top of the stack, removing all other
 // instances of the same page from the stack. An about-to-display signal is sent
 // to the new page via newPage.OnNavigatedTo() while page.OnNavigatedFrom() is
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrRouteNotFound is returned by Router.Resolve for paths that match no
+// route.
+var ErrRouteNotFound = errors.New("no page at this address")
+
+// Route is a path resolved by a Router.
+type Route struct {
+	// Path is the path that was resolved, e.g. /account/acc_1/transactions.
+	Path string
+	// Params are the values of the parameters of the pattern that matched,
+	// e.g. "acc_1" for {id} in /account/{id}/transactions.
+	Params map[string]string
+	Query  url.Values
+}
+
+// PageConstructor returns the page a route leads to, or an error if the route
+// refers to something that does not exist.
+type PageConstructor func(route Route) (Page, error)
+
+type routeHandler struct {
+	segments []string
+	newPage  PageConstructor
+}
+
+// Router maps paths to the pages they lead to, so that pages can be opened
+// by address, e.g. from a link.
+type Router struct {
+	routes []routeHandler
+}
+
+// NewRouter returns a Router without routes.
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle adds a route for the paths that match pattern. Patterns are paths
+// whose segments in braces are parameters matching any one segment, e.g.
+// /account/{id}/transactions. Routes are matched in the order they are added.
+func (router *Router) Handle(pattern string, newPage PageConstructor) {
+	router.routes = append(router.routes, routeHandler{
+		segments: splitPath(pattern),
+		newPage:  newPage,
+	})
+}
+
+// Resolve returns the page at path, which may include a query, e.g.
+// /account/acc_1/transactions?since=2022-06-01. It returns ErrRouteNotFound
+// if no route matches.
+func (router *Router) Resolve(path string) (Page, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The segments are split before they are unescaped, so that IDs may
+	// contain escaped slashes.
+	segments := splitPath(u.EscapedPath())
+	for i, segment := range segments {
+		if segments[i], err = url.PathUnescape(segment); err != nil {
+			return nil, err
+		}
+	}
+	for _, handler := range router.routes {
+		params, ok := matchSegments(handler.segments, segments)
+		if !ok {
+			continue
+		}
+		return handler.newPage(Route{
+			Path:   u.Path,
+			Params: params,
+			Query:  u.Query(),
+		})
+	}
+	return nil, fmt.Errorf("%s: %w", u.Path, ErrRouteNotFound)
+}
+
+func matchSegments(pattern, segments []string) (map[string]string, bool) {
+	if len(pattern) != len(segments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, segment := range pattern {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[segment[1:len(segment)-1]] = segments[i]
+		} else if segment != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func splitPath(path string) []string {
+	var segments []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
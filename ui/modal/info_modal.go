@@ -81,6 +81,12 @@ func (in *InfoModal) SetCancelable(min bool) *InfoModal {
 	return in
 }
 
+// IsCancelable reports whether the modal may be dismissed without choosing
+// one of its buttons, e.g. by clicking outside of it.
+func (in *InfoModal) IsCancelable() bool {
+	return in.isCancelable
+}
+
 func (in *InfoModal) SetContentAlignment(title, btn layout.Direction) *InfoModal {
 	in.titleAlignment = title
 	in.btnAlignment = btn
@@ -111,6 +111,12 @@ func (pm *PasswordModal) SetCancelable(min bool) *PasswordModal {
 	return pm
 }
 
+// IsCancelable reports whether the modal may be dismissed without choosing
+// one of its buttons, e.g. by clicking outside of it.
+func (pm *PasswordModal) IsCancelable() bool {
+	return pm.isCancelable
+}
+
 func (pm *PasswordModal) SetError(err string) {
 	if err == "" {
 		pm.password.ClearError()
@@ -81,6 +81,12 @@ func (tm *TextInputModal) SetCancelable(min bool) *TextInputModal {
 	return tm
 }
 
+// IsCancelable reports whether the modal may be dismissed without choosing
+// one of its buttons, e.g. by clicking outside of it.
+func (tm *TextInputModal) IsCancelable() bool {
+	return tm.isCancelable
+}
+
 func (tm *TextInputModal) SetTextWithTemplate(template string) *TextInputModal {
 	switch template {
 	case AllowUnmixedSpendingTemplate:
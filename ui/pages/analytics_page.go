@@ -0,0 +1,381 @@
+package pages
+
+import (
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/ui/components"
+	"go-monzo-wallet/ui/handlers"
+	"go-monzo-wallet/ui/modal"
+	"go-monzo-wallet/ui/values"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	AnalyticsPageID = "analytics_page"
+
+	// donutCategories is the number of categories shown in the donut
+	// chart. The others are combined into one slice.
+	donutCategories = 6
+	// topMerchants is the number of merchants listed.
+	topMerchants = 5
+	// defaultAnalyticsPeriod is the index in analyticsMonths selected when
+	// the page opens.
+	defaultAnalyticsPeriod = 1
+)
+
+// analyticsMonths are the periods offered by the period dropdown, in calendar
+// months up to and including the current one.
+var analyticsMonths = []int{1, 3, 6, 12}
+
+// analyticsPage charts the spending, income and balance of the account
+// selected in l.WL over a period.
+type analyticsPage struct {
+	*handlers.Load
+	*modal.GenericPageModal
+
+	unsubscribe func()
+
+	mtx       sync.Mutex
+	account   *internal.Account
+	analytics *internal.Analytics
+
+	backButton      components.IconButton
+	periodDropDown  *components.DropDown
+	scrollContainer *widget.List
+	shadowBox       *components.Shadow
+}
+
+// NewAnalyticsPage returns the analytics page of the account selected in
+// l.WL.
+func NewAnalyticsPage(l *handlers.Load) handlers.Page {
+	pg := &analyticsPage{
+		Load:             l,
+		GenericPageModal: modal.NewGenericPageModal(AnalyticsPageID),
+		backButton:       l.Theme.IconButton(l.Theme.Icons.NavigationArrowBack),
+		scrollContainer: &widget.List{
+			List: layout.List{Axis: layout.Vertical},
+		},
+		shadowBox: l.Theme.Shadow(),
+	}
+
+	periodItems := make([]components.DropDownItem, len(analyticsMonths))
+	for i, months := range analyticsMonths {
+		periodItems[i] = components.DropDownItem{Text: periodName(months)}
+	}
+	pg.periodDropDown = l.Theme.DropDown(periodItems, 1, 0)
+	pg.periodDropDown.SetSelectedIndex(defaultAnalyticsPeriod)
+
+	pg.showAccount(l.WL.SelectedAccount)
+	return pg
+}
+
+func periodName(months int) string {
+	if months == 1 {
+		return values.String(values.StrThisMonth)
+	}
+	return values.StringF(values.StrLastMonths, months)
+}
+
+// OnNavigatedTo is called when the page is about to be displayed and
+// may be used to initialize page features that are only relevant when
+// the page is displayed.
+// Part of the load.Page interface.
+func (pg *analyticsPage) OnNavigatedTo() {
+	var events <-chan internal.SyncEvent
+	events, pg.unsubscribe = pg.WL.Syncer().Subscribe()
+	go pg.listenForSyncEvents(events)
+}
+
+// listenForSyncEvents recomputes the charts when the transactions of the
+// account change, until the subscription is cancelled.
+func (pg *analyticsPage) listenForSyncEvents(events <-chan internal.SyncEvent) {
+	for event := range events {
+		switch event.(type) {
+		case internal.SyncFinished, internal.TransactionReceived:
+		default:
+			continue
+		}
+
+		pg.mtx.Lock()
+		id := pg.account.ID
+		pg.mtx.Unlock()
+
+		for _, account := range pg.WL.AccountsList() {
+			if account.ID == id {
+				pg.showAccount(account)
+				pg.ParentWindow().Reload()
+				break
+			}
+		}
+	}
+}
+
+// showAccount computes the analytics of account for the selected period.
+func (pg *analyticsPage) showAccount(account *internal.Account) {
+	months := analyticsMonths[pg.periodDropDown.SelectedIndex()]
+	from, to := internal.MonthsBack(months, time.Now(), time.Local)
+	analytics := internal.Analyze(account, from, to, time.Local)
+
+	pg.mtx.Lock()
+	pg.account = account
+	pg.analytics = analytics
+	pg.mtx.Unlock()
+}
+
+// HandleUserInteractions is called just before Layout() to determine
+// if any user interaction recently occurred on the page and may be
+// used to update the page's UI components shortly before they are
+// displayed.
+// Part of the load.Page interface.
+func (pg *analyticsPage) HandleUserInteractions() {
+	if pg.backButton.Button.Clicked() {
+		pg.ParentNavigator().CloseCurrentPage()
+	}
+
+	if pg.periodDropDown.Changed() {
+		pg.mtx.Lock()
+		account := pg.account
+		pg.mtx.Unlock()
+		pg.showAccount(account)
+	}
+}
+
+// OnNavigatedFrom is called when the page is about to be removed from
+// the displayed window. This method should ideally be used to disable
+// features that are irrelevant when the page is NOT displayed.
+// NOTE: The page may be re-displayed on the app's window, in which case
+// OnNavigatedTo() will be called again. This method should not destroy UI
+// components unless they'll be recreated in the OnNavigatedTo() method.
+// Part of the load.Page interface.
+func (pg *analyticsPage) OnNavigatedFrom() {
+	if pg.unsubscribe != nil {
+		pg.unsubscribe()
+	}
+}
+
+// Layout draws the page UI components into the provided C
+// to be eventually drawn on screen.
+// Part of the load.Page interface.
+func (pg *analyticsPage) Layout(gtx C) D {
+	pg.mtx.Lock()
+	account, analytics := pg.account, pg.analytics
+	pg.mtx.Unlock()
+
+	gtx.Constraints.Min = gtx.Constraints.Max
+	return components.UniformPadding(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(pg.backButton.Layout),
+					layout.Rigid(func(gtx C) D {
+						return layout.Inset{Left: values.MarginPadding10}.Layout(gtx,
+							pg.Theme.H6(values.String(values.StrAnalytics)).Layout)
+					}),
+					layout.Flexed(1, func(gtx C) D {
+						lbl := pg.Theme.Body2(account.AccountNumber)
+						lbl.Color = pg.Theme.Color.GrayText2
+						return layout.Inset{Left: values.MarginPadding10}.Layout(gtx, lbl.Layout)
+					}),
+				)
+			}),
+			layout.Flexed(1, func(gtx C) D {
+				return layout.Inset{Top: values.MarginPadding16}.Layout(gtx, func(gtx C) D {
+					// The dropdown is stacked on top of the charts so
+					// that its menu opens over them.
+					return layout.Stack{}.Layout(gtx,
+						layout.Expanded(func(gtx C) D {
+							return layout.Inset{Top: values.MarginPadding60}.Layout(gtx, func(gtx C) D {
+								return pg.charts(gtx, analytics)
+							})
+						}),
+						layout.Stacked(func(gtx C) D {
+							return pg.periodDropDown.Layout(gtx, 0, false)
+						}),
+					)
+				})
+			}),
+		)
+	})
+}
+
+func (pg *analyticsPage) charts(gtx C, analytics *internal.Analytics) D {
+	sections := []layout.Widget{
+		func(gtx C) D {
+			return pg.totals(gtx, analytics)
+		},
+		func(gtx C) D {
+			return pg.section(gtx, values.String(values.StrIncomeVsOutgoings), func(gtx C) D {
+				return pg.monthsChart(gtx, analytics)
+			})
+		},
+		func(gtx C) D {
+			return pg.section(gtx, values.String(values.StrSpendingByCategory), func(gtx C) D {
+				return pg.categoryChart(gtx, analytics)
+			})
+		},
+		func(gtx C) D {
+			return pg.section(gtx, values.String(values.StrTopMerchants), func(gtx C) D {
+				return pg.merchantList(gtx, analytics)
+			})
+		},
+		func(gtx C) D {
+			return pg.section(gtx, values.String(values.StrBalanceOverTime), func(gtx C) D {
+				return pg.balanceChart(gtx, analytics)
+			})
+		},
+	}
+
+	return pg.Theme.List(pg.scrollContainer).Layout(gtx, len(sections), func(gtx C, i int) D {
+		return layout.Inset{Bottom: values.MarginPadding16}.Layout(gtx, sections[i])
+	})
+}
+
+// section lays out a card with a title above body.
+func (pg *analyticsPage) section(gtx C, title string, body layout.Widget) D {
+	pg.shadowBox.SetShadowRadius(14)
+	return components.LinearLayout{
+		Width:       components.MatchParent,
+		Height:      components.WrapContent,
+		Orientation: layout.Vertical,
+		Padding:     layout.UniformInset(values.MarginPadding16),
+		Background:  pg.Theme.Color.Surface,
+		Shadow:      pg.shadowBox,
+		Border:      components.Border{Radius: components.NewRadius(14)},
+	}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			if title == "" {
+				return D{}
+			}
+			return layout.Inset{Bottom: values.MarginPadding16}.Layout(gtx,
+				pg.Theme.Text(values.TextSize16, title).Layout)
+		}),
+		layout.Rigid(body),
+	)
+}
+
+func (pg *analyticsPage) totals(gtx C, analytics *internal.Analytics) D {
+	net, err := analytics.Income.Sub(analytics.Outgoings)
+	return pg.section(gtx, "", func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return pg.row(gtx, values.String(values.StrIncome), pg.FormatMoney(analytics.Income))
+			}),
+			layout.Rigid(func(gtx C) D {
+				return pg.row(gtx, values.String(values.StrOutgoings), pg.FormatMoney(analytics.Outgoings))
+			}),
+			layout.Rigid(func(gtx C) D {
+				if err != nil {
+					return D{}
+				}
+				return pg.row(gtx, values.String(values.StrNet), pg.FormatMoney(net))
+			}),
+		)
+	})
+}
+
+// row lays out label on the left and value on the right.
+func (pg *analyticsPage) row(gtx C, label, value string) D {
+	return layout.Inset{Bottom: values.MarginPadding5}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+			layout.Flexed(1, func(gtx C) D {
+				lbl := pg.Theme.Body2(label)
+				lbl.Color = pg.Theme.Color.GrayText2
+				return lbl.Layout(gtx)
+			}),
+			layout.Rigid(pg.Theme.Body1(value).Layout),
+		)
+	})
+}
+
+func (pg *analyticsPage) monthsChart(gtx C, analytics *internal.Analytics) D {
+	labels := make([]string, len(analytics.Months))
+	income := make([]float64, len(analytics.Months))
+	outgoings := make([]float64, len(analytics.Months))
+	for i, month := range analytics.Months {
+		labels[i] = month.Month.Format("Jan")
+		income[i] = majorUnits(month.Income)
+		outgoings[i] = majorUnits(month.Outgoings)
+	}
+
+	return pg.Theme.BarChart(labels,
+		components.ChartSeries{Name: values.String(values.StrIncome), Color: pg.Theme.Color.Green500, Values: income},
+		components.ChartSeries{Name: values.String(values.StrOutgoings), Color: pg.Theme.Color.Danger, Values: outgoings},
+	).Layout(gtx)
+}
+
+func (pg *analyticsPage) categoryChart(gtx C, analytics *internal.Analytics) D {
+	if len(analytics.ByCategory) == 0 {
+		return pg.Theme.Body2(values.String(values.StrNoSpending)).Layout(gtx)
+	}
+
+	colors := pg.Theme.ChartColors()
+	var slices []components.ChartSlice
+	var other internal.Money
+	for i, total := range analytics.ByCategory {
+		if i >= donutCategories {
+			other.Currency = total.Amount.Currency
+			other.Amount += total.Amount.Amount
+			continue
+		}
+		name := CategoryName(total.Key)
+		if name == "" {
+			name = values.String(values.StrUncategorized)
+		}
+		slices = append(slices, components.ChartSlice{
+			Label: name + " · " + pg.FormatMoney(total.Amount),
+			Color: colors[i%len(colors)],
+			Value: majorUnits(total.Amount),
+		})
+	}
+	if other.Amount > 0 {
+		slices = append(slices, components.ChartSlice{
+			Label: values.String(values.StrOtherCategories) + " · " + pg.FormatMoney(other),
+			Color: pg.Theme.Color.Gray3,
+			Value: majorUnits(other),
+		})
+	}
+
+	return pg.Theme.DonutChart(slices).Layout(gtx)
+}
+
+func (pg *analyticsPage) merchantList(gtx C, analytics *internal.Analytics) D {
+	if len(analytics.ByMerchant) == 0 {
+		return pg.Theme.Body2(values.String(values.StrNoSpending)).Layout(gtx)
+	}
+
+	merchants := analytics.ByMerchant
+	if len(merchants) > topMerchants {
+		merchants = merchants[:topMerchants]
+	}
+	rows := make([]layout.FlexChild, len(merchants))
+	for i, total := range merchants {
+		total := total
+		rows[i] = layout.Rigid(func(gtx C) D {
+			return pg.row(gtx, total.Key, pg.FormatMoney(total.Amount))
+		})
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, rows...)
+}
+
+func (pg *analyticsPage) balanceChart(gtx C, analytics *internal.Analytics) D {
+	labels := make([]string, len(analytics.Balance))
+	balances := make([]float64, len(analytics.Balance))
+	for i, point := range analytics.Balance {
+		labels[i] = point.Day.Format("2 Jan")
+		balances[i] = majorUnits(point.Balance)
+	}
+
+	return pg.Theme.LineChart(labels, components.ChartSeries{
+		Name:   values.String(values.StrBalance),
+		Color:  pg.Theme.Color.Primary,
+		Values: balances,
+	}).Layout(gtx)
+}
+
+// majorUnits returns the amount in major units, e.g. pounds, for charting.
+func majorUnits(m internal.Money) float64 {
+	return float64(m.Amount) / math.Pow10(m.Scale())
+}
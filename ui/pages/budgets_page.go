@@ -0,0 +1,375 @@
+package pages
+
+import (
+	"errors"
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"github.com/sirupsen/logrus"
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/ui/components"
+	"go-monzo-wallet/ui/handlers"
+	"go-monzo-wallet/ui/modal"
+	"go-monzo-wallet/ui/values"
+	"strings"
+	"sync"
+	"time"
+)
+
+const BudgetsPageID = "budgets_page"
+
+// budgetRow is a budget in the budget list.
+type budgetRow struct {
+	status       internal.BudgetStatus
+	removeButton components.IconButton
+}
+
+// budgetsPage shows the spending against each budget this month and lets the
+// user add and remove budgets, which are saved to the config.
+type budgetsPage struct {
+	*handlers.Load
+	*modal.GenericPageModal
+
+	unsubscribe func()
+
+	mtx            sync.Mutex
+	rows           []budgetRow
+	warningPercent int
+
+	backButton      components.IconButton
+	addButton       components.Button
+	scrollContainer *widget.List
+	shadowBox       *components.Shadow
+	category        components.Editor
+	limit           components.Editor
+}
+
+// NewBudgetsPage returns the page that manages the budgets of l.Budgets.
+func NewBudgetsPage(l *handlers.Load) handlers.Page {
+	pg := &budgetsPage{
+		Load:             l,
+		GenericPageModal: modal.NewGenericPageModal(BudgetsPageID),
+		backButton:       l.Theme.IconButton(l.Theme.Icons.NavigationArrowBack),
+		addButton:        l.Theme.OutlineButton(values.String(values.StrAddBudget)),
+		scrollContainer: &widget.List{
+			List: layout.List{Axis: layout.Vertical},
+		},
+		shadowBox: l.Theme.Shadow(),
+		category:  l.Theme.Editor(new(widget.Editor), values.String(values.StrBudgetCategory)),
+		limit:     l.Theme.Editor(new(widget.Editor), values.String(values.StrBudgetLimit)),
+	}
+	pg.category.Editor.SingleLine = true
+	pg.limit.Editor.SingleLine = true
+
+	pg.refresh()
+	return pg
+}
+
+// OnNavigatedTo is called when the page is about to be displayed and
+// may be used to initialize page features that are only relevant when
+// the page is displayed.
+// Part of the load.Page interface.
+func (pg *budgetsPage) OnNavigatedTo() {
+	var events <-chan internal.SyncEvent
+	events, pg.unsubscribe = pg.WL.Syncer().Subscribe()
+	go pg.listenForSyncEvents(events)
+}
+
+// listenForSyncEvents updates the spending when the transactions change,
+// until the subscription is cancelled.
+func (pg *budgetsPage) listenForSyncEvents(events <-chan internal.SyncEvent) {
+	for event := range events {
+		switch event.(type) {
+		case internal.SyncFinished, internal.TransactionReceived:
+			pg.refresh()
+			pg.ParentWindow().Reload()
+		}
+	}
+}
+
+// refresh evaluates the budgets against the accounts of the wallet.
+func (pg *budgetsPage) refresh() {
+	statuses := pg.Budgets.Status(pg.WL.AccountsList(), time.Now())
+	_, warningPercent := pg.Budgets.Budgets()
+
+	rows := make([]budgetRow, len(statuses))
+	for i, status := range statuses {
+		rows[i] = budgetRow{
+			status:       status,
+			removeButton: pg.Theme.IconButton(pg.Theme.Icons.ContentRemove),
+		}
+	}
+
+	pg.mtx.Lock()
+	pg.rows = rows
+	pg.warningPercent = warningPercent
+	pg.mtx.Unlock()
+}
+
+// HandleUserInteractions is called just before Layout() to determine
+// if any user interaction recently occurred on the page and may be
+// used to update the page's UI components shortly before they are
+// displayed.
+// Part of the load.Page interface.
+func (pg *budgetsPage) HandleUserInteractions() {
+	if pg.backButton.Button.Clicked() {
+		pg.ParentNavigator().CloseCurrentPage()
+	}
+
+	if pg.addButton.Clicked() {
+		pg.addBudget()
+	}
+
+	pg.mtx.Lock()
+	rows := pg.rows
+	pg.mtx.Unlock()
+	for _, row := range rows {
+		if row.removeButton.Button.Clicked() {
+			pg.confirmRemoveBudget(row.status.Budget)
+		}
+	}
+}
+
+// addBudget saves the budget entered in the form, replacing the budget of
+// the same category if there is one.
+func (pg *budgetsPage) addBudget() {
+	pg.category.SetError("")
+	pg.limit.SetError("")
+
+	category := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(pg.category.Editor.Text())), " ", "_")
+	if category == "" {
+		pg.category.SetError(values.String(values.StrMissingCategory))
+		return
+	}
+	limit := strings.TrimSpace(pg.limit.Editor.Text())
+
+	err := pg.updateBudgets(func(budgets []internal.BudgetConfig) []internal.BudgetConfig {
+		for i, budget := range budgets {
+			if budget.Category == category {
+				budgets[i].Limit = limit
+				return budgets
+			}
+		}
+		return append(budgets, internal.BudgetConfig{Category: category, Limit: limit})
+	})
+	var invalid internal.ValidationError
+	if errors.As(err, &invalid) && invalid.Field(internal.ConfigKeyBudgets) != nil {
+		pg.limit.SetError(values.String(values.StrInvalidLimit))
+		return
+	}
+	if err != nil {
+		logrus.Info("saving budget:", err)
+		pg.Toast.NotifyError(err.Error())
+		return
+	}
+
+	pg.category.Editor.SetText("")
+	pg.limit.Editor.SetText("")
+	pg.Toast.Notify(values.StringF(values.StrBudgetSaved, CategoryName(category)))
+}
+
+func (pg *budgetsPage) confirmRemoveBudget(budget internal.Budget) {
+	removeModal := modal.NewInfoModal(pg.Load).
+		Title(values.String(values.StrRemove)).
+		Body(values.StringF(values.StrRemoveBudgetConfirm, CategoryName(budget.Category))).
+		NegativeButton(values.String(values.StrCancel), func() {})
+
+	removeModal.PositiveButton(values.String(values.StrRemove), func(isChecked bool) bool {
+		err := pg.updateBudgets(func(budgets []internal.BudgetConfig) []internal.BudgetConfig {
+			kept := budgets[:0]
+			for _, b := range budgets {
+				if b.Category != budget.Category {
+					kept = append(kept, b)
+				}
+			}
+			return kept
+		})
+		if err != nil {
+			logrus.Info("removing budget:", err)
+			pg.Toast.NotifyError(err.Error())
+			return false
+		}
+
+		pg.Toast.Notify(values.StringF(values.StrBudgetRemoved, CategoryName(budget.Category)))
+		pg.ParentWindow().DismissModal(removeModal.ID())
+		return false
+	})
+	pg.ParentWindow().ShowModal(removeModal)
+}
+
+// updateBudgets changes the budgets in the config file with update and
+// applies them.
+func (pg *budgetsPage) updateBudgets(update func([]internal.BudgetConfig) []internal.BudgetConfig) error {
+	cfg, err := internal.UpdateConfig(func(cfg *internal.Config) {
+		cfg.Budgets = update(append([]internal.BudgetConfig(nil), cfg.Budgets...))
+	})
+	if err != nil {
+		return err
+	}
+	budgets, err := cfg.BudgetList()
+	if err != nil {
+		return err
+	}
+
+	pg.Budgets.SetBudgets(budgets, cfg.BudgetWarning)
+	pg.refresh()
+	return nil
+}
+
+// OnNavigatedFrom is called when the page is about to be removed from
+// the displayed window. This method should ideally be used to disable
+// features that are irrelevant when the page is NOT displayed.
+// NOTE: The page may be re-displayed on the app's window, in which case
+// OnNavigatedTo() will be called again. This method should not destroy UI
+// components unless they'll be recreated in the OnNavigatedTo() method.
+// Part of the load.Page interface.
+func (pg *budgetsPage) OnNavigatedFrom() {
+	if pg.unsubscribe != nil {
+		pg.unsubscribe()
+	}
+}
+
+// Layout draws the page UI components into the provided C
+// to be eventually drawn on screen.
+// Part of the load.Page interface.
+func (pg *budgetsPage) Layout(gtx C) D {
+	pg.mtx.Lock()
+	rows, warningPercent := pg.rows, pg.warningPercent
+	pg.mtx.Unlock()
+
+	content := []layout.Widget{
+		func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(pg.backButton.Layout),
+				layout.Rigid(func(gtx C) D {
+					return layout.Inset{Left: values.MarginPadding10}.Layout(gtx,
+						pg.Theme.H6(values.String(values.StrBudgets)).Layout)
+				}),
+			)
+		},
+		func(gtx C) D {
+			lbl := pg.Theme.Body2(values.StringF(values.StrBudgetWarningAt, warningPercent))
+			lbl.Color = pg.Theme.Color.GrayText2
+			return lbl.Layout(gtx)
+		},
+	}
+	if len(rows) == 0 {
+		content = append(content, pg.Theme.Body1(values.String(values.StrNoBudgets)).Layout)
+	}
+	for _, row := range rows {
+		row := row
+		content = append(content, func(gtx C) D {
+			return pg.budgetItem(gtx, row)
+		})
+	}
+	content = append(content, pg.budgetForm)
+
+	gtx.Constraints.Min = gtx.Constraints.Max
+	return components.UniformPadding(gtx, func(gtx C) D {
+		return pg.Theme.List(pg.scrollContainer).Layout(gtx, len(content), func(gtx C, i int) D {
+			return layout.Inset{Bottom: values.MarginPadding16}.Layout(gtx, content[i])
+		})
+	})
+}
+
+// budgetItem lays out the spending against a budget with a progress bar
+// colored by its level.
+func (pg *budgetsPage) budgetItem(gtx C, row budgetRow) D {
+	status := row.status
+	pg.shadowBox.SetShadowRadius(14)
+	return components.LinearLayout{
+		Width:       components.MatchParent,
+		Height:      components.WrapContent,
+		Orientation: layout.Vertical,
+		Padding:     layout.UniformInset(values.MarginPadding16),
+		Background:  pg.Theme.Color.Surface,
+		Shadow:      pg.shadowBox,
+		Border:      components.Border{Radius: components.NewRadius(14)},
+	}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, pg.Theme.Body1(CategoryName(status.Budget.Category)).Layout),
+				layout.Rigid(pg.Theme.Body2(values.StringF(values.StrBudgetSpent,
+					pg.FormatMoney(status.Spent), pg.FormatMoney(status.Budget.Limit))).Layout),
+				layout.Rigid(row.removeButton.Layout),
+			)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Top: values.MarginPadding8}.Layout(gtx, func(gtx C) D {
+				return pg.progressBar(gtx, status)
+			})
+		}),
+		layout.Rigid(func(gtx C) D {
+			over := status.ProjectedOverspend()
+			if over.Amount == 0 {
+				return D{}
+			}
+			lbl := pg.Theme.Caption(values.StringF(values.StrBudgetProjected,
+				pg.FormatMoney(status.Projected), pg.FormatMoney(over)))
+			lbl.Color = pg.Theme.Color.Danger
+			return layout.Inset{Top: values.MarginPadding8}.Layout(gtx, lbl.Layout)
+		}),
+	)
+}
+
+func (pg *budgetsPage) progressBar(gtx C, status internal.BudgetStatus) D {
+	progress := status.Progress
+	if progress > 1 {
+		progress = 1
+	}
+
+	height := gtx.Dp(values.MarginPadding4)
+	track := pg.Theme.Line(height, gtx.Constraints.Max.X)
+	track.Color = pg.Theme.Color.Gray2
+	bar := pg.Theme.Line(height, int(progress*float64(gtx.Constraints.Max.X)))
+	switch status.Level {
+	case internal.BudgetExceeded:
+		bar.Color = pg.Theme.Color.Danger
+	case internal.BudgetWarning:
+		bar.Color = pg.Theme.Color.Orange
+	default:
+		bar.Color = pg.Theme.Color.Primary
+	}
+
+	return layout.Stack{}.Layout(gtx,
+		layout.Stacked(track.Layout),
+		layout.Stacked(func(gtx C) D {
+			if bar.Width == 0 {
+				return D{}
+			}
+			return bar.Layout(gtx)
+		}),
+	)
+}
+
+// budgetForm lays out the fields of a new budget, with the categories of the
+// wallet's transactions as a hint.
+func (pg *budgetsPage) budgetForm(gtx C) D {
+	var transactions []*internal.Transaction
+	for _, account := range pg.WL.AccountsList() {
+		transactions = append(transactions, account.Transactions...)
+	}
+	categories := internal.Categories(transactions)
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(pg.Theme.Text(values.TextSize16, values.String(values.StrAddBudget)).Layout),
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Top: values.MarginPadding8}.Layout(gtx, pg.category.Layout)
+		}),
+		layout.Rigid(func(gtx C) D {
+			if len(categories) == 0 {
+				return D{}
+			}
+			lbl := pg.Theme.Caption(values.StringF(values.StrKnownCategories, strings.Join(categories, ", ")))
+			lbl.Color = pg.Theme.Color.GrayText2
+			return layout.Inset{Top: values.MarginPadding4}.Layout(gtx, lbl.Layout)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Top: values.MarginPadding8}.Layout(gtx, pg.limit.Layout)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Top: values.MarginPadding8}.Layout(gtx, func(gtx C) D {
+				return layout.E.Layout(gtx, pg.addButton.Layout)
+			})
+		}),
+	)
+}
@@ -0,0 +1,143 @@
+package pages
+
+import (
+	"errors"
+	"gioui.org/layout"
+	"gioui.org/text"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"github.com/sirupsen/logrus"
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/ui/components"
+	"go-monzo-wallet/ui/handlers"
+	"go-monzo-wallet/ui/modal"
+	"go-monzo-wallet/ui/values"
+	"time"
+)
+
+const LockPageID = "lock_page"
+
+// lockPage covers the app while it is locked and unlocks it with the
+// startup password or the unlock PIN.
+type lockPage struct {
+	*handlers.Load
+	*modal.GenericPageModal
+
+	password     components.Editor
+	unlockButton components.Button
+	loader       material.LoaderStyle
+	unlocking    bool
+}
+
+// NewLockPage returns the page shown instead of the others while l.Locker is
+// locked.
+func NewLockPage(l *handlers.Load) handlers.Page {
+	pg := &lockPage{
+		Load:             l,
+		GenericPageModal: modal.NewGenericPageModal(LockPageID),
+		unlockButton:     l.Theme.Button(values.String(values.StrUnlock)),
+		loader:           material.Loader(l.Theme.Base),
+	}
+	pg.unlockButton.Font.Weight = text.Medium
+
+	pg.password = l.Theme.EditorPassword(new(widget.Editor), values.String(values.StrPasswordOrPIN))
+	pg.password.Editor.SingleLine, pg.password.Editor.Submit = true, true
+	return pg
+}
+
+// OnNavigatedTo is called when the page is about to be displayed and
+// may be used to initialize page features that are only relevant when
+// the page is displayed.
+// Part of the load.Page interface.
+func (pg *lockPage) OnNavigatedTo() {
+	pg.password.Editor.Focus()
+}
+
+// HandleUserInteractions is called just before Layout() to determine
+// if any user interaction recently occurred on the page and may be
+// used to update the page's UI components shortly before they are
+// displayed.
+// Part of the load.Page interface.
+func (pg *lockPage) HandleUserInteractions() {
+	isSubmit, isChanged := components.HandleEditorEvents(pg.password.Editor)
+	if isChanged {
+		pg.password.SetError("")
+	}
+
+	if !pg.unlockButton.Clicked() && !isSubmit || pg.unlocking {
+		return
+	}
+	secret := pg.password.Editor.Text()
+	if secret == "" {
+		pg.password.SetError(values.String(values.StrEnterSpendingPassword))
+		return
+	}
+
+	// Deriving the key takes a moment, so it is done in the background.
+	pg.unlocking = true
+	pg.unlockButton.SetEnabled(false)
+	go func() {
+		err := pg.Locker.Unlock(secret, time.Now())
+		pg.unlocking = false
+		pg.unlockButton.SetEnabled(true)
+		pg.password.Editor.SetText("")
+		var throttled *internal.UnlockThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			wait := time.Until(throttled.RetryAt).Round(time.Second)
+			pg.password.SetError(values.StringF(values.StrUnlockThrottled, wait))
+		case errors.Is(err, internal.ErrWrongPassphrase):
+			pg.password.SetError(values.String(values.StrInvalidPassphrase))
+		case err != nil:
+			logrus.Info("unlocking:", err)
+			pg.password.SetError(err.Error())
+		}
+		pg.ParentWindow().Reload()
+	}()
+}
+
+// OnNavigatedFrom is called when the page is about to be removed from
+// the displayed window. This method should ideally be used to disable
+// features that are irrelevant when the page is NOT displayed.
+// NOTE: The page may be re-displayed on the app's window, in which case
+// OnNavigatedTo() will be called again. This method should not destroy UI
+// components unless they'll be recreated in the OnNavigatedTo() method.
+// Part of the load.Page interface.
+func (pg *lockPage) OnNavigatedFrom() {}
+
+// Layout draws the page UI components into the provided C
+// to be eventually drawn on screen.
+// Part of the load.Page interface.
+func (pg *lockPage) Layout(gtx C) D {
+	gtx.Constraints.Min = gtx.Constraints.Max
+	return components.UniformPadding(gtx, func(gtx C) D {
+		return layout.Center.Layout(gtx, func(gtx C) D {
+			gtx.Constraints.Max.X = gtx.Dp(values.MarginPadding350)
+			gtx.Constraints.Min.X = gtx.Constraints.Max.X
+			return layout.Flex{Axis: layout.Vertical, Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(func(gtx C) D {
+					return components.NewImage(pg.Theme.Icons.MonzoLogo).LayoutSize(gtx, values.MarginPadding150)
+				}),
+				layout.Rigid(func(gtx C) D {
+					return layout.Inset{Top: values.MarginPadding24}.Layout(gtx,
+						pg.Theme.H6(values.String(values.StrWalletLocked)).Layout)
+				}),
+				layout.Rigid(func(gtx C) D {
+					return layout.Inset{Top: values.MarginPadding8}.Layout(gtx,
+						pg.Theme.Body2(values.String(values.StrWalletLockedInfo)).Layout)
+				}),
+				layout.Rigid(func(gtx C) D {
+					return layout.Inset{Top: values.MarginPadding16}.Layout(gtx, pg.password.Layout)
+				}),
+				layout.Rigid(func(gtx C) D {
+					return layout.Inset{Top: values.MarginPadding16}.Layout(gtx, func(gtx C) D {
+						if pg.unlocking {
+							return pg.loader.Layout(gtx)
+						}
+						return pg.unlockButton.Layout(gtx)
+					})
+				}),
+			)
+		})
+	})
+}
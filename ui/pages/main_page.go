@@ -0,0 +1,427 @@
+package pages
+
+import (
+	"gioui.org/io/key"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"go-monzo-wallet/ui/components"
+	"go-monzo-wallet/ui/handlers"
+	"go-monzo-wallet/ui/modal"
+	"go-monzo-wallet/ui/values"
+)
+
+const MainPageID = "main_page"
+
+var (
+	NavDrawerWidth          = unit.Dp(160)
+	NavDrawerMinimizedWidth = unit.Dp(72)
+)
+
+// navTab is a sub-page of the main page that can be selected in the
+// navigation drawer or bottom bar.
+type navTab struct {
+	pageID  string
+	title   string // key of the title in values
+	icon    *widget.Icon
+	newPage func(l *handlers.Load) handlers.Page
+	// needsAccount is set for the tabs that show the selected account.
+	needsAccount bool
+	clickable    *components.Clickable
+}
+
+// mainPage hosts the pages of the wallet in its own page stack, with a
+// navigation drawer on wide windows and a bottom navigation bar on narrow
+// ones to switch between them. Pages displayed from within a tab are pushed
+// on top of it, while displaying the page of another tab switches to that
+// tab.
+type mainPage struct {
+	*handlers.Load
+	*modal.GenericPageModal
+
+	subPages *handlers.PageStack
+	tabs     []*navTab
+	// selectedTab is the index of the tab in tabs that is shown. It is kept
+	// by the page, so it survives reloads of the window.
+	selectedTab int
+
+	drawerMinimized bool
+	drawerToggle    components.IconButton
+	// privacyToggle toggles privacy mode from the drawer or bottom bar.
+	privacyToggle components.IconButton
+}
+
+// NewMainPage returns the main page, showing the accounts tab.
+func NewMainPage(l *handlers.Load) handlers.Page {
+	mp := &mainPage{
+		Load:             l,
+		GenericPageModal: modal.NewGenericPageModal(MainPageID),
+		subPages:         handlers.NewPageStack(MainPageID),
+		drawerToggle:     l.Theme.IconButton(l.Theme.Icons.NavigationMenu),
+		privacyToggle:    l.Theme.IconButton(l.Theme.Icons.RevealIcon),
+	}
+
+	mp.tabs = []*navTab{
+		{pageID: StartPageID, title: values.StrAccounts, icon: l.Theme.Icons.AccountBalance, newPage: NewStartPage},
+		{pageID: WalletPageID, title: values.StrTransactions, icon: l.Theme.Icons.Receipt, newPage: NewWalletPage, needsAccount: true},
+		{pageID: PotsPageID, title: values.StrPots, icon: l.Theme.Icons.Savings, newPage: NewPotsPage, needsAccount: true},
+		{pageID: AnalyticsPageID, title: values.StrAnalytics, icon: l.Theme.Icons.Chart, newPage: NewAnalyticsPage, needsAccount: true},
+		{pageID: SettingsPageID, title: values.StrSettings, icon: l.Theme.Icons.Settings, newPage: NewSettingsPage},
+	}
+	for _, tab := range mp.tabs {
+		tab.clickable = l.Theme.NewClickable(true)
+	}
+	return mp
+}
+
+// tabIndex returns the index of the tab that shows the page with the given
+// ID, or -1 if no tab does.
+func (mp *mainPage) tabIndex(pageID string) int {
+	for i, tab := range mp.tabs {
+		if tab.pageID == pageID {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectTab shows the tab at index i with page as its only page, or a new
+// page of the tab if page is nil. Tabs that show the selected account select
+// the first account if there is none. Without accounts, the accounts tab is
+// shown instead.
+func (mp *mainPage) selectTab(i int, page handlers.Page) {
+	if tab := mp.tabs[i]; tab.needsAccount && mp.WL.SelectedAccount == nil {
+		if accounts := mp.WL.AccountsList(); len(accounts) > 0 {
+			mp.WL.SelectedAccount = accounts[0]
+		} else {
+			i, page = 0, nil
+		}
+	}
+	tab := mp.tabs[i]
+	if page == nil {
+		page = tab.newPage(mp.Load)
+	}
+
+	mp.selectedTab = i
+	page.OnAttachedToNavigator(mp)
+	mp.subPages.Reset(page)
+	mp.ParentWindow().Reload()
+}
+
+// showsNavigation reports whether the drawer or bottom bar is shown. They
+// are hidden while the wallet is opened or set up.
+func (mp *mainPage) showsNavigation() bool {
+	return mp.WL.LoadedWallet()
+}
+
+// CurrentPage returns the page that is at the top of the stack. Returns nil
+// if the stack is empty.
+// Part of the PageNavigator interface.
+func (mp *mainPage) CurrentPage() handlers.Page {
+	return mp.subPages.Top()
+}
+
+// CurrentPageID returns the ID of the current page or an empty string if no
+// page is displayed.
+// Part of the PageNavigator interface.
+func (mp *mainPage) CurrentPageID() string {
+	if currentPage := mp.CurrentPage(); currentPage != nil {
+		return currentPage.ID()
+	}
+	return ""
+}
+
+// Display causes the specified page to be displayed on this page. The page of
+// a tab is displayed by switching to that tab, other pages are pushed on top
+// of the current tab.
+// Part of the PageNavigator interface.
+func (mp *mainPage) Display(page handlers.Page) {
+	if i := mp.tabIndex(page.ID()); i >= 0 {
+		mp.selectTab(i, page)
+		return
+	}
+	if mp.subPages.Push(page, mp) {
+		mp.ParentWindow().Reload()
+	}
+}
+
+// CloseCurrentPage dismisses the page at the top of the stack and gets the
+// next page ready for display. Closing the page of a tab returns to the
+// accounts tab.
+// Part of the PageNavigator interface.
+func (mp *mainPage) CloseCurrentPage() {
+	if mp.subPages.Len() > 1 {
+		mp.subPages.Pop()
+		mp.ParentWindow().Reload()
+		return
+	}
+	if mp.selectedTab != 0 {
+		mp.selectTab(0, nil)
+	}
+}
+
+// ClosePagesAfter dismisses all pages from the top of the stack until (and
+// excluding) the page with the specified ID. If no page is found with the
+// provided ID, no page will be popped. The page with the specified ID will be
+// displayed after the other pages are popped.
+// Part of the PageNavigator interface.
+func (mp *mainPage) ClosePagesAfter(keepPageID string) {
+	popped := mp.subPages.PopAfter(func(page handlers.Page) bool {
+		return page.ID() == keepPageID
+	})
+	if popped {
+		mp.ParentWindow().Reload()
+	}
+}
+
+// ClearStackAndDisplay dismisses all pages in the stack and displays the
+// specified page, in its tab if it is the page of one.
+// Part of the PageNavigator interface.
+func (mp *mainPage) ClearStackAndDisplay(page handlers.Page) {
+	if i := mp.tabIndex(page.ID()); i >= 0 {
+		mp.selectTab(i, page)
+		return
+	}
+	page.OnAttachedToNavigator(mp)
+	mp.subPages.Reset(page)
+	mp.ParentWindow().Reload()
+}
+
+// CloseAllPages dismisses all pages in the stack. The selected tab is shown
+// again the next time the page is displayed.
+// Part of the PageNavigator interface.
+func (mp *mainPage) CloseAllPages() {
+	mp.subPages.Reset()
+	mp.ParentWindow().Reload()
+}
+
+// OnNavigatedTo is called when the page is about to be displayed and
+// may be used to initialize page features that are only relevant when
+// the page is displayed.
+// Part of the load.Page interface.
+func (mp *mainPage) OnNavigatedTo() {
+	if currentPage := mp.CurrentPage(); currentPage != nil {
+		currentPage.OnNavigatedTo()
+		return
+	}
+	mp.selectTab(mp.selectedTab, nil)
+}
+
+// HandleUserInteractions is called just before Layout() to determine
+// if any user interaction recently occurred on the page and may be
+// used to update the page's UI components shortly before they are
+// displayed.
+// Part of the load.Page interface.
+func (mp *mainPage) HandleUserInteractions() {
+	if mp.CurrentPage() == nil {
+		mp.selectTab(mp.selectedTab, nil)
+	}
+
+	if mp.drawerToggle.Button.Clicked() {
+		mp.drawerMinimized = !mp.drawerMinimized
+	}
+
+	if mp.privacyToggle.Button.Clicked() {
+		mp.TogglePrivacyMode()
+	}
+
+	for i, tab := range mp.tabs {
+		if tab.clickable.Clicked() && mp.showsNavigation() {
+			mp.selectTab(i, nil)
+		}
+	}
+
+	if currentPage := mp.CurrentPage(); currentPage != nil {
+		currentPage.HandleUserInteractions()
+	}
+}
+
+// KeysToHandle returns the keys of the current page, if it handles any.
+// Part of the load.KeyEventHandler interface.
+func (mp *mainPage) KeysToHandle() key.Set {
+	if handler, ok := mp.CurrentPage().(handlers.KeyEventHandler); ok {
+		return handler.KeysToHandle()
+	}
+	return ""
+}
+
+// HandleKeyPress passes key presses on to the current page.
+// Part of the load.KeyEventHandler interface.
+func (mp *mainPage) HandleKeyPress(evt *key.Event) {
+	if handler, ok := mp.CurrentPage().(handlers.KeyEventHandler); ok {
+		handler.HandleKeyPress(evt)
+	}
+}
+
+// OnNavigatedFrom is called when the page is about to be removed from
+// the displayed window. This method should ideally be used to disable
+// features that are irrelevant when the page is NOT displayed.
+// NOTE: The page may be re-displayed on the app's window, in which case
+// OnNavigatedTo() will be called again. This method should not destroy UI
+// components unless they'll be recreated in the OnNavigatedTo() method.
+// Part of the load.Page interface.
+func (mp *mainPage) OnNavigatedFrom() {
+	if currentPage := mp.CurrentPage(); currentPage != nil {
+		currentPage.OnNavigatedFrom()
+	}
+}
+
+// OnClosed closes the pages of the stack.
+// Part of the load.Closable interface.
+func (mp *mainPage) OnClosed() {
+	mp.subPages.Reset()
+}
+
+// Layout draws the page UI components into the provided C
+// to be eventually drawn on screen.
+// Part of the load.Page interface.
+func (mp *mainPage) Layout(gtx C) D {
+	currentPage := mp.CurrentPage()
+	content := func(gtx C) D {
+		if currentPage == nil {
+			return D{}
+		}
+		return currentPage.Layout(gtx)
+	}
+
+	gtx.Constraints.Min = gtx.Constraints.Max
+	switch {
+	case !mp.showsNavigation():
+		return content(gtx)
+	case mp.GetCurrentAppWidth() <= gtx.Dp(values.StartMobileView):
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Flexed(1, content),
+			layout.Rigid(mp.bottomBar),
+		)
+	default:
+		return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+			layout.Rigid(mp.drawer),
+			layout.Flexed(1, content),
+		)
+	}
+}
+
+// drawer lays out the tabs vertically with their titles, or only their icons
+// while it is minimized.
+func (mp *mainPage) drawer(gtx C) D {
+	width := NavDrawerWidth
+	if mp.drawerMinimized {
+		width = NavDrawerMinimizedWidth
+	}
+	gtx.Constraints.Min.X = gtx.Dp(width)
+	gtx.Constraints.Max.X = gtx.Constraints.Min.X
+
+	items := []layout.FlexChild{
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Left: values.MarginPadding12, Bottom: values.MarginPadding8}.Layout(gtx, mp.drawerToggle.Layout)
+		}),
+	}
+	for i, tab := range mp.tabs {
+		i, tab := i, tab
+		items = append(items, layout.Rigid(func(gtx C) D {
+			gtx.Constraints.Min.X = gtx.Constraints.Max.X
+			return tab.clickable.Layout(gtx, func(gtx C) D {
+				return layout.Inset{
+					Top:    values.MarginPadding12,
+					Bottom: values.MarginPadding12,
+					Left:   values.MarginPadding24,
+				}.Layout(gtx, func(gtx C) D {
+					children := []layout.FlexChild{layout.Rigid(func(gtx C) D {
+						return mp.tabIcon(gtx, tab, i == mp.selectedTab)
+					})}
+					if !mp.drawerMinimized {
+						children = append(children, layout.Rigid(func(gtx C) D {
+							return layout.Inset{Left: values.MarginPadding12}.Layout(gtx, mp.tabTitle(tab, i == mp.selectedTab).Layout)
+						}))
+					}
+					return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, children...)
+				})
+			})
+		}))
+	}
+	items = append(items,
+		layout.Flexed(1, func(gtx C) D { return D{} }),
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Left: values.MarginPadding12, Bottom: values.MarginPadding8}.Layout(gtx, mp.privacyToggleLayout)
+		}),
+	)
+
+	return layout.Stack{}.Layout(gtx,
+		layout.Expanded(func(gtx C) D {
+			return components.Fill(gtx, mp.Theme.Color.Surface)
+		}),
+		layout.Stacked(func(gtx C) D {
+			gtx.Constraints.Min.Y = gtx.Constraints.Max.Y
+			return layout.Inset{Top: values.MarginPadding8}.Layout(gtx, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx, items...)
+			})
+		}),
+	)
+}
+
+// bottomBar lays out the tabs side by side, with their titles below their
+// icons, followed by the privacy mode toggle.
+func (mp *mainPage) bottomBar(gtx C) D {
+	items := make([]layout.FlexChild, len(mp.tabs), len(mp.tabs)+1)
+	for i, tab := range mp.tabs {
+		i, tab := i, tab
+		items[i] = layout.Flexed(1, func(gtx C) D {
+			gtx.Constraints.Min.X = gtx.Constraints.Max.X
+			return tab.clickable.Layout(gtx, func(gtx C) D {
+				return layout.UniformInset(values.MarginPadding8).Layout(gtx, func(gtx C) D {
+					return layout.Flex{Axis: layout.Vertical, Alignment: layout.Middle}.Layout(gtx,
+						layout.Rigid(func(gtx C) D {
+							return mp.tabIcon(gtx, tab, i == mp.selectedTab)
+						}),
+						layout.Rigid(func(gtx C) D {
+							title := mp.tabTitle(tab, i == mp.selectedTab)
+							title.TextSize = values.TextSize12
+							return title.Layout(gtx)
+						}),
+					)
+				})
+			})
+		})
+	}
+	items = append(items, layout.Rigid(func(gtx C) D {
+		return layout.Inset{Right: values.MarginPadding8}.Layout(gtx, mp.privacyToggleLayout)
+	}))
+
+	return layout.Stack{}.Layout(gtx,
+		layout.Expanded(func(gtx C) D {
+			return components.Fill(gtx, mp.Theme.Color.Surface)
+		}),
+		layout.Stacked(func(gtx C) D {
+			gtx.Constraints.Min.X = gtx.Constraints.Max.X
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx, items...)
+		}),
+	)
+}
+
+// privacyToggleLayout lays out the privacy mode toggle, with a crossed out
+// eye while amounts are hidden.
+func (mp *mainPage) privacyToggleLayout(gtx C) D {
+	mp.privacyToggle.Icon = mp.Theme.Icons.RevealIcon
+	if mp.IsPrivacyMode() {
+		mp.privacyToggle.Icon = mp.Theme.Icons.ConcealIcon
+	}
+	return mp.privacyToggle.Layout(gtx)
+}
+
+func (mp *mainPage) tabIcon(gtx C, tab *navTab, selected bool) D {
+	icon := components.NewIcon(tab.icon)
+	icon.Color = mp.Theme.Color.GrayText2
+	if selected {
+		icon.Color = mp.Theme.Color.Primary
+	}
+	return icon.Layout(gtx, values.MarginPadding24)
+}
+
+func (mp *mainPage) tabTitle(tab *navTab, selected bool) components.Text {
+	title := mp.Theme.Body1(values.String(tab.title))
+	title.Color = mp.Theme.Color.GrayText2
+	if selected {
+		title.Color = mp.Theme.Color.Primary
+	}
+	return title
+}
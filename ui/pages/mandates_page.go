@@ -0,0 +1,292 @@
+package pages
+
+import (
+	"errors"
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/ui/components"
+	"go-monzo-wallet/ui/handlers"
+	"go-monzo-wallet/ui/modal"
+	"go-monzo-wallet/ui/values"
+	"sync"
+)
+
+const (
+	MandatesPageID = "mandates_page"
+
+	// mandateDateFormat is the layout of collection dates.
+	mandateDateFormat = "2 January 2006"
+)
+
+// mandateRow is a direct debit or standing order in the list.
+type mandateRow struct {
+	mandate      *internal.Mandate
+	cancelButton components.IconButton
+}
+
+// mandatesPage lists the direct debits and standing orders of the selected
+// account and lets the user cancel them.
+type mandatesPage struct {
+	*handlers.Load
+	*modal.GenericPageModal
+
+	unsubscribe func()
+
+	mtx            sync.Mutex
+	accountID      string
+	directDebits   []mandateRow
+	standingOrders []mandateRow
+
+	backButton      components.IconButton
+	scrollContainer *widget.List
+	shadowBox       *components.Shadow
+}
+
+// NewMandatesPage returns the page that lists the mandates of the selected
+// account.
+func NewMandatesPage(l *handlers.Load) handlers.Page {
+	pg := &mandatesPage{
+		Load:             l,
+		GenericPageModal: modal.NewGenericPageModal(MandatesPageID),
+		backButton:       l.Theme.IconButton(l.Theme.Icons.NavigationArrowBack),
+		scrollContainer: &widget.List{
+			List: layout.List{Axis: layout.Vertical},
+		},
+		shadowBox: l.Theme.Shadow(),
+	}
+
+	pg.showAccount(l.WL.SelectedAccount)
+	return pg
+}
+
+// OnNavigatedTo is called when the page is about to be displayed and
+// may be used to initialize page features that are only relevant when
+// the page is displayed.
+// Part of the load.Page interface.
+func (pg *mandatesPage) OnNavigatedTo() {
+	var events <-chan internal.SyncEvent
+	events, pg.unsubscribe = pg.WL.Syncer().Subscribe()
+	go pg.listenForSyncEvents(events)
+}
+
+// listenForSyncEvents shows the mandates loaded by every sync, until the
+// subscription is cancelled.
+func (pg *mandatesPage) listenForSyncEvents(events <-chan internal.SyncEvent) {
+	for event := range events {
+		if _, ok := event.(internal.SyncFinished); ok {
+			pg.refresh()
+			pg.ParentWindow().Reload()
+		}
+	}
+}
+
+// refresh shows the mandates of the account as they are in the wallet.
+func (pg *mandatesPage) refresh() {
+	pg.mtx.Lock()
+	id := pg.accountID
+	pg.mtx.Unlock()
+
+	for _, account := range pg.WL.AccountsList() {
+		if account.ID == id {
+			pg.showAccount(account)
+			return
+		}
+	}
+}
+
+func (pg *mandatesPage) showAccount(account *internal.Account) {
+	var directDebits, standingOrders []mandateRow
+	for _, mandate := range account.Mandates {
+		row := mandateRow{
+			mandate:      mandate,
+			cancelButton: pg.Theme.IconButton(pg.Theme.Icons.ContentRemove),
+		}
+		if mandate.Type == internal.MandateStandingOrder {
+			standingOrders = append(standingOrders, row)
+		} else {
+			directDebits = append(directDebits, row)
+		}
+	}
+
+	pg.mtx.Lock()
+	pg.accountID = account.ID
+	pg.directDebits = directDebits
+	pg.standingOrders = standingOrders
+	pg.mtx.Unlock()
+}
+
+// HandleUserInteractions is called just before Layout() to determine
+// if any user interaction recently occurred on the page and may be
+// used to update the page's UI components shortly before they are
+// displayed.
+// Part of the load.Page interface.
+func (pg *mandatesPage) HandleUserInteractions() {
+	if pg.backButton.Button.Clicked() {
+		pg.ParentNavigator().CloseCurrentPage()
+	}
+
+	pg.mtx.Lock()
+	rows := append(append([]mandateRow(nil), pg.directDebits...), pg.standingOrders...)
+	pg.mtx.Unlock()
+	for _, row := range rows {
+		if row.cancelButton.Button.Clicked() {
+			pg.confirmCancel(row.mandate)
+		}
+	}
+}
+
+// confirmCancel asks for the startup password before cancelling mandate.
+func (pg *mandatesPage) confirmCancel(mandate *internal.Mandate) {
+	description := values.StringF(values.StrConfirmCancelDirectDebit, mandate.Payee)
+	done := values.StringF(values.StrDirectDebitCancelled, mandate.Payee)
+	if mandate.Type == internal.MandateStandingOrder {
+		description = values.StringF(values.StrConfirmCancelStandingOrder, mandate.Payee)
+		done = values.StringF(values.StrStandingOrderCancelled, mandate.Payee)
+	}
+
+	passwordModal := modal.NewPasswordModal(pg.Load).
+		Title(values.String(values.StrCancelMandate)).
+		Description(description).
+		Hint(values.String(values.StrStartupPassword)).
+		NegativeButton(values.String(values.StrCancel), func() {})
+
+	passwordModal.PositiveButton(values.String(values.StrConfirm), func(password string, m *modal.PasswordModal) bool {
+		go func() {
+			if err := checkPassphrase(password); err != nil {
+				m.SetLoading(false)
+				if errors.Is(err, internal.ErrWrongPassphrase) {
+					m.SetError(values.String(values.StrInvalidPassphrase))
+				} else {
+					pg.Toast.NotifyError(err.Error())
+				}
+				pg.ParentWindow().Reload()
+				return
+			}
+
+			err := pg.WL.CancelMandate(mandate.AccountID, mandate.ID)
+			m.SetLoading(false)
+			if err != nil {
+				pg.Toast.NotifyError(values.StringF(values.StrCancelMandateFailed, err))
+				pg.ParentWindow().Reload()
+				return
+			}
+
+			pg.refresh()
+			pg.Toast.Notify(done)
+			pg.ParentWindow().DismissModal(m.ID())
+		}()
+		return false
+	})
+	pg.ParentWindow().ShowModal(passwordModal)
+}
+
+// OnNavigatedFrom is called when the page is about to be removed from
+// the displayed window. This method should ideally be used to disable
+// features that are irrelevant when the page is NOT displayed.
+// NOTE: The page may be re-displayed on the app's window, in which case
+// OnNavigatedTo() will be called again. This method should not destroy UI
+// components unless they'll be recreated in the OnNavigatedTo() method.
+// Part of the load.Page interface.
+func (pg *mandatesPage) OnNavigatedFrom() {
+	if pg.unsubscribe != nil {
+		pg.unsubscribe()
+	}
+}
+
+// Layout draws the page UI components into the provided C
+// to be eventually drawn on screen.
+// Part of the load.Page interface.
+func (pg *mandatesPage) Layout(gtx C) D {
+	pg.mtx.Lock()
+	directDebits, standingOrders := pg.directDebits, pg.standingOrders
+	pg.mtx.Unlock()
+
+	content := []layout.Widget{
+		func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(pg.backButton.Layout),
+				layout.Rigid(func(gtx C) D {
+					return layout.Inset{Left: values.MarginPadding10}.Layout(gtx,
+						pg.Theme.H6(values.String(values.StrMandates)).Layout)
+				}),
+			)
+		},
+	}
+	if len(directDebits) == 0 && len(standingOrders) == 0 {
+		content = append(content, pg.Theme.Body1(values.String(values.StrNoMandates)).Layout)
+	}
+	for _, section := range []struct {
+		title string
+		rows  []mandateRow
+	}{
+		{values.String(values.StrDirectDebits), directDebits},
+		{values.String(values.StrStandingOrders), standingOrders},
+	} {
+		if len(section.rows) == 0 {
+			continue
+		}
+		content = append(content, pg.Theme.Text(values.TextSize16, section.title).Layout)
+		for _, row := range section.rows {
+			row := row
+			content = append(content, func(gtx C) D {
+				return pg.mandateItem(gtx, row)
+			})
+		}
+	}
+
+	gtx.Constraints.Min = gtx.Constraints.Max
+	return components.UniformPadding(gtx, func(gtx C) D {
+		return pg.Theme.List(pg.scrollContainer).Layout(gtx, len(content), func(gtx C, i int) D {
+			return layout.Inset{Bottom: values.MarginPadding16}.Layout(gtx, content[i])
+		})
+	})
+}
+
+// mandateItem lays out the payee, reference and last and next payments of a
+// mandate.
+func (pg *mandatesPage) mandateItem(gtx C, row mandateRow) D {
+	mandate := row.mandate
+
+	var details []layout.FlexChild
+	caption := func(txt string) {
+		lbl := pg.Theme.Caption(txt)
+		lbl.Color = pg.Theme.Color.GrayText2
+		details = append(details, layout.Rigid(func(gtx C) D {
+			return layout.Inset{Top: values.MarginPadding4}.Layout(gtx, lbl.Layout)
+		}))
+	}
+	if mandate.Reference != "" {
+		caption(values.StringF(values.StrReference, mandate.Reference))
+	}
+	if !mandate.LastCollection.IsZero() {
+		caption(values.StringF(values.StrLastPaid, pg.FormatMoney(mandate.LastAmount),
+			mandate.LastCollection.Local().Format(mandateDateFormat)))
+	}
+	if next := mandate.NextCollection; !next.IsZero() {
+		if mandate.Amount.Amount != 0 {
+			caption(values.StringF(values.StrNextPayment, pg.FormatMoney(mandate.Amount),
+				next.Local().Format(mandateDateFormat)))
+		} else {
+			caption(values.StringF(values.StrNextCollection, next.Local().Format(mandateDateFormat)))
+		}
+	}
+
+	pg.shadowBox.SetShadowRadius(14)
+	return components.LinearLayout{
+		Width:       components.MatchParent,
+		Height:      components.WrapContent,
+		Orientation: layout.Horizontal,
+		Alignment:   layout.Middle,
+		Padding:     layout.UniformInset(values.MarginPadding16),
+		Background:  pg.Theme.Color.Surface,
+		Shadow:      pg.shadowBox,
+		Border:      components.Border{Radius: components.NewRadius(14)},
+	}.Layout(gtx,
+		layout.Flexed(1, func(gtx C) D {
+			return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+				append([]layout.FlexChild{layout.Rigid(pg.Theme.Body1(mandate.Payee).Layout)}, details...)...)
+		}),
+		layout.Rigid(row.cancelButton.Layout),
+	)
+}
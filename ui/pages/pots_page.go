@@ -0,0 +1,396 @@
+package pages
+
+import (
+	"errors"
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/ui/components"
+	"go-monzo-wallet/ui/handlers"
+	"go-monzo-wallet/ui/modal"
+	"go-monzo-wallet/ui/values"
+	"sync"
+)
+
+const PotsPageID = "pots_page"
+
+// potsPage shows the pots of the selected account and moves money into and
+// out of them.
+type potsPage struct {
+	*handlers.Load
+	*modal.GenericPageModal
+
+	unsubscribe func()
+
+	mtx     sync.Mutex
+	account *internal.Account
+
+	backButton     components.IconButton
+	shadowBox      *components.Shadow
+	potList        *components.ClickableList
+	selectedPot    string // ID of the pot the transfer controls act on
+	potAmount      components.Editor
+	depositButton  components.Button
+	withdrawButton components.Button
+}
+
+// NewPotsPage returns the page that shows the pots of the account selected in
+// l.WL.
+func NewPotsPage(l *handlers.Load) handlers.Page {
+	pg := &potsPage{
+		Load:             l,
+		GenericPageModal: modal.NewGenericPageModal(PotsPageID),
+		account:          l.WL.SelectedAccount,
+		backButton:       l.Theme.IconButton(l.Theme.Icons.NavigationArrowBack),
+		shadowBox:        l.Theme.Shadow(),
+		potList:          l.Theme.NewClickableList(layout.Horizontal),
+		depositButton:    l.Theme.Button(values.String(values.StrDeposit)),
+		withdrawButton:   l.Theme.OutlineButton(values.String(values.StrWithdraw)),
+	}
+
+	pg.potAmount = l.Theme.Editor(new(widget.Editor), values.String(values.StrAmount))
+	pg.potAmount.Editor.SingleLine = true
+	return pg
+}
+
+// OnNavigatedTo is called when the page is about to be displayed and
+// may be used to initialize page features that are only relevant when
+// the page is displayed.
+// Part of the load.Page interface.
+func (pg *potsPage) OnNavigatedTo() {
+	var events <-chan internal.SyncEvent
+	events, pg.unsubscribe = pg.WL.Syncer().Subscribe()
+	go pg.listenForSyncEvents(events)
+}
+
+// listenForSyncEvents shows the pot balances of every sync and received
+// transaction until the subscription is cancelled.
+func (pg *potsPage) listenForSyncEvents(events <-chan internal.SyncEvent) {
+	for event := range events {
+		switch event.(type) {
+		case internal.SyncFinished, internal.TransactionReceived:
+		default:
+			continue
+		}
+
+		pg.mtx.Lock()
+		id := pg.account.ID
+		pg.mtx.Unlock()
+
+		for _, account := range pg.WL.AccountsList() {
+			if account.ID == id {
+				pg.mtx.Lock()
+				pg.account = account
+				pg.mtx.Unlock()
+				pg.ParentWindow().Reload()
+				break
+			}
+		}
+	}
+}
+
+// HandleUserInteractions is called just before Layout() to determine
+// if any user interaction recently occurred on the page and may be
+// used to update the page's UI components shortly before they are
+// displayed.
+// Part of the load.Page interface.
+func (pg *potsPage) HandleUserInteractions() {
+	if pg.backButton.Button.Clicked() {
+		pg.ParentNavigator().CloseCurrentPage()
+	}
+
+	pg.handlePotInteractions()
+}
+
+// handlePotInteractions selects the pot that was clicked and confirms
+// deposits into and withdrawals from the selected pot.
+func (pg *potsPage) handlePotInteractions() {
+	pg.mtx.Lock()
+	account := pg.account
+	pg.mtx.Unlock()
+	pots := account.Pots
+
+	if ok, i := pg.potList.ItemClicked(); ok && i < len(pots) {
+		if pots[i].ID == pg.selectedPot {
+			pg.selectedPot = ""
+		} else {
+			pg.selectedPot = pots[i].ID
+		}
+		pg.potAmount.Editor.SetText("")
+		pg.potAmount.SetError("")
+	}
+
+	var pot *internal.Pot
+	for _, p := range pots {
+		if p.ID == pg.selectedPot {
+			pot = p
+		}
+	}
+	if pot == nil {
+		return
+	}
+
+	if _, changed := components.HandleEditorEvents(pg.potAmount.Editor); changed {
+		pg.potAmount.SetError("")
+	}
+	pg.withdrawButton.SetEnabled(!pot.Locked)
+
+	deposit, withdraw := pg.depositButton.Clicked(), pg.withdrawButton.Clicked() && !pot.Locked
+	if !deposit && !withdraw {
+		return
+	}
+
+	amount, err := internal.ParseMoney(pg.potAmount.Editor.Text(), pot.Balance.Currency)
+	if err != nil || amount.Sign() <= 0 {
+		pg.potAmount.SetError(values.String(values.StrInvalidAmount))
+		return
+	}
+
+	if deposit {
+		pg.confirmDeposit(account, pot, amount)
+	} else {
+		pg.confirmWithdrawal(account, pot, amount)
+	}
+}
+
+// confirmDeposit asks for confirmation before moving amount from the account
+// into pot. The dialog keeps its dedupe ID, so confirming again after a
+// failure cannot move the money twice.
+func (pg *potsPage) confirmDeposit(account *internal.Account, pot *internal.Pot, amount internal.Money) {
+	dedupeID := internal.NewDedupeID()
+	formatted := amount.Format(pg.Printer)
+
+	confirmModal := modal.NewInfoModal(pg.Load).
+		Title(values.String(values.StrDeposit)).
+		Body(values.StringF(values.StrConfirmDeposit, formatted, pot.Name)).
+		NegativeButton(values.String(values.StrCancel), func() {})
+
+	confirmModal.PositiveButton(values.String(values.StrConfirm), func(isChecked bool) bool {
+		confirmModal.SetLoading(true)
+		go func() {
+			err := pg.WL.Deposit(account.ID, pot.ID, amount, dedupeID)
+			confirmModal.SetLoading(false)
+			if err != nil {
+				pg.Toast.NotifyError(values.StringF(values.StrPotTransferFailed, err))
+				pg.ParentWindow().Reload()
+				return
+			}
+
+			pg.potTransferDone(values.StringF(values.StrDepositedToPot, formatted, pot.Name))
+			pg.ParentWindow().DismissModal(confirmModal.ID())
+		}()
+		return false
+	})
+	pg.ParentWindow().ShowModal(confirmModal)
+}
+
+// confirmWithdrawal asks for the startup password before moving amount from
+// pot into the account. Like confirmDeposit, retries reuse the dedupe ID.
+func (pg *potsPage) confirmWithdrawal(account *internal.Account, pot *internal.Pot, amount internal.Money) {
+	dedupeID := internal.NewDedupeID()
+	formatted := amount.Format(pg.Printer)
+
+	passwordModal := modal.NewPasswordModal(pg.Load).
+		Title(values.String(values.StrWithdraw)).
+		Description(values.StringF(values.StrConfirmWithdraw, formatted, pot.Name)).
+		Hint(values.String(values.StrStartupPassword)).
+		NegativeButton(values.String(values.StrCancel), func() {})
+
+	passwordModal.PositiveButton(values.String(values.StrConfirm), func(password string, m *modal.PasswordModal) bool {
+		go func() {
+			if err := checkPassphrase(password); err != nil {
+				m.SetLoading(false)
+				if errors.Is(err, internal.ErrWrongPassphrase) {
+					m.SetError(values.String(values.StrInvalidPassphrase))
+				} else {
+					pg.Toast.NotifyError(err.Error())
+				}
+				pg.ParentWindow().Reload()
+				return
+			}
+
+			err := pg.WL.Withdraw(account.ID, pot.ID, amount, dedupeID)
+			m.SetLoading(false)
+			if errors.Is(err, internal.ErrPotLocked) {
+				pg.Toast.NotifyError(values.StringF(values.StrPotLockedError, pot.Name))
+				pg.ParentWindow().DismissModal(m.ID())
+				return
+			}
+			if err != nil {
+				pg.Toast.NotifyError(values.StringF(values.StrPotTransferFailed, err))
+				pg.ParentWindow().Reload()
+				return
+			}
+
+			pg.potTransferDone(values.StringF(values.StrWithdrawnFromPot, formatted, pot.Name))
+			pg.ParentWindow().DismissModal(m.ID())
+		}()
+		return false
+	})
+	pg.ParentWindow().ShowModal(passwordModal)
+}
+
+// potTransferDone clears the transfer controls and reports a completed
+// deposit or withdrawal.
+func (pg *potsPage) potTransferDone(message string) {
+	pg.potAmount.Editor.SetText("")
+	pg.Toast.Notify(message)
+	pg.ParentWindow().Reload()
+}
+
+// OnNavigatedFrom is called when the page is about to be removed from
+// the displayed window. This method should ideally be used to disable
+// features that are irrelevant when the page is NOT displayed.
+// NOTE: The page may be re-displayed on the app's window, in which case
+// OnNavigatedTo() will be called again. This method should not destroy UI
+// components unless they'll be recreated in the OnNavigatedTo() method.
+// Part of the load.Page interface.
+func (pg *potsPage) OnNavigatedFrom() {
+	if pg.unsubscribe != nil {
+		pg.unsubscribe()
+	}
+}
+
+// Layout draws the page UI components into the provided C
+// to be eventually drawn on screen.
+// Part of the load.Page interface.
+func (pg *potsPage) Layout(gtx C) D {
+	pg.mtx.Lock()
+	account := pg.account
+	pg.mtx.Unlock()
+
+	gtx.Constraints.Min = gtx.Constraints.Max
+	return components.UniformPadding(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(pg.backButton.Layout),
+					layout.Rigid(func(gtx C) D {
+						return layout.Inset{Left: values.MarginPadding10}.Layout(gtx,
+							pg.Theme.H6(account.AccountNumber).Layout)
+					}),
+				)
+			}),
+			layout.Rigid(func(gtx C) D {
+				return layout.Inset{Top: values.MarginPadding16}.Layout(gtx, func(gtx C) D {
+					return pg.potSection(gtx, account)
+				})
+			}),
+		)
+	})
+}
+
+// potSection lays out the pots of the account side by side, followed by the
+// transfer controls of the selected pot.
+func (pg *potsPage) potSection(gtx C, account *internal.Account) D {
+	pots := account.Pots
+	var selected *internal.Pot
+	for _, pot := range pots {
+		if pot.ID == pg.selectedPot {
+			selected = pot
+		}
+	}
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(pg.Theme.Text(values.TextSize20, values.String(values.StrPots)).Layout),
+		layout.Rigid(func(gtx C) D {
+			if len(pots) == 0 {
+				return layout.Inset{Top: values.MarginPadding8}.Layout(gtx, pg.Theme.Body1(values.String(values.StrNoPots)).Layout)
+			}
+			return pg.potList.Layout(gtx, len(pots), func(gtx C, i int) D {
+				return pg.potItem(gtx, pots[i], pots[i] == selected)
+			})
+		}),
+		layout.Rigid(func(gtx C) D {
+			if selected == nil {
+				return D{}
+			}
+			return layout.Inset{Top: values.MarginPadding8}.Layout(gtx, pg.potTransfer)
+		}),
+	)
+}
+
+func (pg *potsPage) potItem(gtx C, pot *internal.Pot, selected bool) D {
+	background := pg.Theme.Color.Surface
+	if selected {
+		background = pg.Theme.Color.Gray2
+	}
+
+	pg.shadowBox.SetShadowRadius(14)
+	return components.LinearLayout{
+		Width:       gtx.Dp(values.MarginPadding180),
+		Height:      components.WrapContent,
+		Orientation: layout.Vertical,
+		Padding:     layout.UniformInset(values.MarginPadding12),
+		Margin:      layout.UniformInset(values.MarginPadding5),
+		Background:  background,
+		Shadow:      pg.shadowBox,
+		Border:      components.Border{Radius: components.NewRadius(14)},
+	}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+				layout.Flexed(1, pg.Theme.Body1(pot.Name).Layout),
+				layout.Rigid(func(gtx C) D {
+					if !pot.Locked {
+						return D{}
+					}
+					lbl := pg.Theme.Caption(values.String(values.StrLocked))
+					lbl.Color = pg.Theme.Color.GrayText3
+					return lbl.Layout(gtx)
+				}),
+			)
+		}),
+		layout.Rigid(pg.Theme.H6(pg.FormatMoney(pot.Balance)).Layout),
+		layout.Rigid(func(gtx C) D {
+			if pot.Goal.IsZero() {
+				return D{}
+			}
+			return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+				layout.Rigid(func(gtx C) D {
+					return layout.Inset{Top: values.MarginPadding4, Bottom: values.MarginPadding4}.Layout(gtx, func(gtx C) D {
+						return pg.potProgress(gtx, pot.Progress())
+					})
+				}),
+				layout.Rigid(func(gtx C) D {
+					lbl := pg.Theme.Caption(values.StringF(values.StrPotGoal,
+						pg.FormatMoney(pot.Balance), pg.FormatMoney(pot.Goal)))
+					lbl.Color = pg.Theme.Color.GrayText3
+					return lbl.Layout(gtx)
+				}),
+			)
+		}),
+	)
+}
+
+// potProgress draws a bar filled up to progress, in the range [0, 1].
+func (pg *potsPage) potProgress(gtx C, progress float64) D {
+	height := gtx.Dp(values.MarginPadding4)
+	track := pg.Theme.Line(height, gtx.Constraints.Max.X)
+	track.Color = pg.Theme.Color.Gray2
+	bar := pg.Theme.Line(height, int(progress*float64(gtx.Constraints.Max.X)))
+	bar.Color = pg.Theme.Color.Primary
+
+	return layout.Stack{}.Layout(gtx,
+		layout.Stacked(track.Layout),
+		layout.Stacked(func(gtx C) D {
+			if bar.Width == 0 {
+				return D{}
+			}
+			return bar.Layout(gtx)
+		}),
+	)
+}
+
+// potTransfer lays out the amount field and the buttons that move money into
+// and out of the selected pot.
+func (pg *potsPage) potTransfer(gtx C) D {
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		layout.Flexed(1, pg.potAmount.Layout),
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Left: values.MarginPadding8}.Layout(gtx, pg.depositButton.Layout)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Left: values.MarginPadding8}.Layout(gtx, pg.withdrawButton.Layout)
+		}),
+	)
+}
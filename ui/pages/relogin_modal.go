@@ -0,0 +1,80 @@
+package pages
+
+import (
+	"context"
+	"errors"
+	"github.com/sirupsen/logrus"
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/ui/handlers"
+	"go-monzo-wallet/ui/modal"
+	"go-monzo-wallet/ui/values"
+)
+
+// ShowReloginModal asks over the current page for the startup password and
+// logs in to profile again in the browser, e.g. after the background sync
+// found its token revoked. Cancelling leaves the profile out of the sync
+// until it is logged in from the start page.
+func ShowReloginModal(l *handlers.Load, window handlers.WindowNavigator, profile *internal.Profile) {
+	passwordModal := modal.NewPasswordModal(l).
+		Title(values.StringF(values.StrLogInProfile, profile.Name)).
+		Description(values.StringF(values.StrLoginExpired, profile.Name)).
+		Hint(values.String(values.StrStartupPassword)).
+		NegativeButton(values.String(values.StrCancel), func() {})
+
+	passwordModal.PositiveButton(values.String(values.StrLogIn), func(password string, m *modal.PasswordModal) bool {
+		go func() {
+			err := reconnectProfile(l, profile, password)
+			m.SetLoading(false)
+			if err != nil {
+				logrus.Info("logging in again:", err)
+				if errors.Is(err, internal.ErrWrongPassphrase) {
+					m.SetError(values.String(values.StrInvalidPassphrase))
+				} else {
+					l.Toast.NotifyError(loginErrorMessage(err))
+				}
+				window.Reload()
+				return
+			}
+
+			l.Toast.Notify(values.StringF(values.StrLoggedInAgain, profile.Name))
+			window.DismissModal(m.ID())
+		}()
+		return false
+	})
+	window.ShowModal(passwordModal)
+}
+
+// reconnectProfile logs in to profile again, saves its new token and syncs
+// the wallet right away, which refreshes the accounts of the pages on
+// display.
+func reconnectProfile(l *handlers.Load, profile *internal.Profile, passphrase string) error {
+	if err := checkPassphrase(passphrase); err != nil {
+		return err
+	}
+	cfg, err := internal.LoadConfig()
+	if err != nil {
+		return err
+	}
+	dir, err := internal.AppDataDir()
+	if err != nil {
+		return err
+	}
+
+	store, err := internal.OpenProfileTokenStore(dir, profile, passphrase)
+	if err != nil {
+		return err
+	}
+	token, err := l.WL.Connect(context.Background(), cfg.OAuth2())
+	if err != nil {
+		return err
+	}
+	if err := store.Save(token); err != nil {
+		return err
+	}
+	l.WL.AuthenticateProfile(profile.ID, store.TokenSource(context.Background(), cfg.OAuth2(), token))
+
+	syncer := l.WL.Syncer()
+	syncer.Start()
+	syncer.SyncNow()
+	return nil
+}
@@ -0,0 +1,95 @@
+package pages
+
+import (
+	"fmt"
+	"go-monzo-wallet/ui/handlers"
+	"time"
+)
+
+// sinceFormats are the layouts accepted by the since parameter of the
+// transactions route.
+var sinceFormats = []string{"2006-01-02", time.RFC3339}
+
+// NewRouter returns the routes of the pages that can be opened by address,
+// e.g. from a monzo-wallet:// link:
+//
+//	/accounts
+//	/account/{id}
+//	/account/{id}/transactions?since=2022-06-01
+//	/account/{id}/transactions/{transaction}
+//	/account/{id}/pots
+//	/account/{id}/analytics
+//	/account/{id}/mandates
+//	/budgets
+//	/subscriptions
+//	/settings
+//
+// The account routes select the account in l.WL.
+func NewRouter(l *handlers.Load) *handlers.Router {
+	router := handlers.NewRouter()
+	page := func(newPage func(l *handlers.Load) handlers.Page) handlers.PageConstructor {
+		return func(handlers.Route) (handlers.Page, error) {
+			return newPage(l), nil
+		}
+	}
+	accountPage := func(newPage func(route handlers.Route) (handlers.Page, error)) handlers.PageConstructor {
+		return func(route handlers.Route) (handlers.Page, error) {
+			if err := selectAccount(l, route.Params["id"]); err != nil {
+				return nil, err
+			}
+			return newPage(route)
+		}
+	}
+
+	router.Handle("/accounts", page(NewStartPage))
+	router.Handle("/account/{id}", accountPage(func(route handlers.Route) (handlers.Page, error) {
+		return NewWalletPage(l), nil
+	}))
+	router.Handle("/account/{id}/transactions", accountPage(func(route handlers.Route) (handlers.Page, error) {
+		var since time.Time
+		if s := route.Query.Get("since"); s != "" {
+			var err error
+			if since, err = parseSince(s); err != nil {
+				return nil, err
+			}
+		}
+		return newWalletPage(l, since, ""), nil
+	}))
+	router.Handle("/account/{id}/transactions/{transaction}", accountPage(func(route handlers.Route) (handlers.Page, error) {
+		return newWalletPage(l, time.Time{}, route.Params["transaction"]), nil
+	}))
+	router.Handle("/account/{id}/pots", accountPage(func(handlers.Route) (handlers.Page, error) {
+		return NewPotsPage(l), nil
+	}))
+	router.Handle("/account/{id}/analytics", accountPage(func(handlers.Route) (handlers.Page, error) {
+		return NewAnalyticsPage(l), nil
+	}))
+	router.Handle("/account/{id}/mandates", accountPage(func(handlers.Route) (handlers.Page, error) {
+		return NewMandatesPage(l), nil
+	}))
+	router.Handle("/budgets", page(NewBudgetsPage))
+	router.Handle("/subscriptions", page(NewSubscriptionsPage))
+	router.Handle("/settings", page(NewSettingsPage))
+	return router
+}
+
+// selectAccount makes the account with the given ID the selected account of
+// the wallet.
+func selectAccount(l *handlers.Load, id string) error {
+	for _, account := range l.WL.AccountsList() {
+		if account.ID == id {
+			l.WL.SelectedAccount = account
+			return nil
+		}
+	}
+	return fmt.Errorf("account %s not found", id)
+}
+
+func parseSince(s string) (time.Time, error) {
+	for _, layout := range sinceFormats {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected e.g. 2022-06-01", s)
+}
@@ -0,0 +1,421 @@
+package pages
+
+import (
+	"errors"
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"github.com/sirupsen/logrus"
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/ui/components"
+	"go-monzo-wallet/ui/handlers"
+	"go-monzo-wallet/ui/modal"
+	"go-monzo-wallet/ui/values"
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+	"sort"
+	"time"
+)
+
+const SettingsPageID = "settings_page"
+
+// settingsDropDownGroup is the dropdown group of the settings page, apart
+// from those of the wallet and analytics pages.
+const settingsDropDownGroup = 2
+
+// settingsLabelWidth is the width of the labels left of the dropdowns.
+const settingsLabelWidth = 180
+
+// settingsRowHeight is the height of each setting, which the dropdowns are
+// positioned by.
+var settingsRowHeight = values.MarginPadding56
+
+var (
+	// syncIntervals and autoLockTimeouts are the durations offered, to which
+	// a different duration set in the config file is added.
+	syncIntervals    = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute, time.Hour}
+	autoLockTimeouts = []time.Duration{0, time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute, time.Hour}
+
+	// budgetCurrencies are offered in addition to those of the accounts.
+	budgetCurrencies = []string{"EUR", "GBP", "USD"}
+)
+
+// settingsPage edits the preferences kept in the config file and applies
+// them right away.
+type settingsPage struct {
+	*handlers.Load
+	*modal.GenericPageModal
+
+	backButton  components.IconButton
+	darkMode    *widget.Bool
+	privacyMode *widget.Bool
+	// pinButton sets the unlock PIN, or removes it if one is set.
+	pinButton components.Button
+
+	languages      []string
+	currencies     []string
+	syncIntervals  []time.Duration
+	autoLocks      []time.Duration
+	languageDrop   *components.DropDown
+	currencyDrop   *components.DropDown
+	syncDrop       *components.DropDown
+	autoLockDrop   *components.DropDown
+	dropDowns      []*components.DropDown
+	dropDownLabels []string
+}
+
+// NewSettingsPage returns the settings page.
+func NewSettingsPage(l *handlers.Load) handlers.Page {
+	pg := &settingsPage{
+		Load:             l,
+		GenericPageModal: modal.NewGenericPageModal(SettingsPageID),
+		backButton:       l.Theme.IconButton(l.Theme.Icons.NavigationArrowBack),
+		darkMode:         new(widget.Bool),
+		privacyMode:      new(widget.Bool),
+		pinButton:        l.Theme.OutlineButton(values.String(values.StrSetUnlockPIN)),
+	}
+
+	cfg, err := internal.LoadConfig()
+	if cfg == nil {
+		logrus.Info("reading config:", err)
+		cfg = internal.DefaultConfig()
+	}
+	pg.darkMode.Value = cfg.Theme == internal.ThemeDark
+	pg.privacyMode.Value = l.IsPrivacyMode()
+
+	pg.languages = values.Languages
+	languageItems := make([]components.DropDownItem, len(pg.languages))
+	for i, lang := range pg.languages {
+		languageItems[i] = components.DropDownItem{Text: display.Self.Name(language.Make(lang))}
+	}
+	pg.languageDrop = l.Theme.DropDown(languageItems, settingsDropDownGroup, 0)
+	pg.languageDrop.SetSelectedIndex(indexOf(pg.languages, baseLanguage(cfg.Language)))
+
+	pg.currencies = settingsCurrencies(cfg.Currency, l.WL.AccountsList())
+	currencyItems := make([]components.DropDownItem, len(pg.currencies))
+	for i, code := range pg.currencies {
+		currencyItems[i] = components.DropDownItem{Text: code}
+	}
+	pg.currencyDrop = l.Theme.DropDown(currencyItems, settingsDropDownGroup, 1)
+	pg.currencyDrop.SetSelectedIndex(indexOf(pg.currencies, cfg.Currency))
+
+	pg.syncIntervals = withDuration(syncIntervals, cfg.SyncInterval)
+	pg.syncDrop = l.Theme.DropDown(durationItems(pg.syncIntervals), settingsDropDownGroup, 2)
+	pg.syncDrop.SetSelectedIndex(indexOf(pg.syncIntervals, cfg.SyncInterval))
+
+	pg.autoLocks = withDuration(autoLockTimeouts, cfg.AutoLock)
+	pg.autoLockDrop = l.Theme.DropDown(durationItems(pg.autoLocks), settingsDropDownGroup, 3)
+	pg.autoLockDrop.SetSelectedIndex(indexOf(pg.autoLocks, cfg.AutoLock))
+
+	pg.dropDowns = []*components.DropDown{pg.languageDrop, pg.currencyDrop, pg.syncDrop, pg.autoLockDrop}
+	pg.dropDownLabels = []string{
+		values.String(values.StrLanguage),
+		values.String(values.StrBudgetCurrency),
+		values.String(values.StrSyncEvery),
+		values.String(values.StrAutoLock),
+	}
+	return pg
+}
+
+// settingsCurrencies returns the currencies offered for budgets: the
+// configured one, those of the accounts and budgetCurrencies, sorted.
+func settingsCurrencies(configured string, accounts internal.Accounts) []string {
+	seen := map[string]bool{configured: true}
+	currencies := []string{configured}
+	add := func(code string) {
+		if code != "" && !seen[code] {
+			seen[code] = true
+			currencies = append(currencies, code)
+		}
+	}
+	for _, account := range accounts {
+		add(account.Balance.Currency)
+	}
+	for _, code := range budgetCurrencies {
+		add(code)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// withDuration returns durations with d added in order if it is missing.
+func withDuration(durations []time.Duration, d time.Duration) []time.Duration {
+	if indexOf(durations, d) >= 0 {
+		return durations
+	}
+	durations = append(append([]time.Duration(nil), durations...), d)
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return durations
+}
+
+func durationItems(durations []time.Duration) []components.DropDownItem {
+	items := make([]components.DropDownItem, len(durations))
+	for i, d := range durations {
+		items[i] = components.DropDownItem{Text: durationName(d)}
+	}
+	return items
+}
+
+// durationName returns a short name for d, e.g. "5 min", or "Never" for
+// zero.
+func durationName(d time.Duration) string {
+	switch {
+	case d == 0:
+		return values.String(values.StrNever)
+	case d%time.Hour == 0:
+		return values.StringF(values.StrHoursShort, int(d/time.Hour))
+	case d%time.Minute == 0:
+		return values.StringF(values.StrMinutesShort, int(d/time.Minute))
+	}
+	return d.String()
+}
+
+func indexOf[T comparable](list []T, v T) int {
+	for i, item := range list {
+		if item == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// baseLanguage returns the language of a BCP 47 tag without its region,
+// e.g. "en" for "en-GB".
+func baseLanguage(tag string) string {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return tag
+	}
+	base, _ := t.Base()
+	return base.String()
+}
+
+// OnNavigatedTo is called when the page is about to be displayed and
+// may be used to initialize page features that are only relevant when
+// the page is displayed.
+// Part of the load.Page interface.
+func (pg *settingsPage) OnNavigatedTo() {}
+
+// HandleUserInteractions is called just before Layout() to determine
+// if any user interaction recently occurred on the page and may be
+// used to update the page's UI components shortly before they are
+// displayed.
+// Part of the load.Page interface.
+func (pg *settingsPage) HandleUserInteractions() {
+	if pg.backButton.Button.Clicked() {
+		pg.ParentNavigator().CloseCurrentPage()
+	}
+
+	if pg.darkMode.Changed() {
+		isDarkModeOn := pg.darkMode.Value
+		theme := internal.ThemeLight
+		if isDarkModeOn {
+			theme = internal.ThemeDark
+		}
+		if pg.saveSetting(func(cfg *internal.Config) { cfg.Theme = theme }) {
+			pg.DarkModeSettingChanged(isDarkModeOn)
+		}
+	}
+
+	if pg.privacyMode.Changed() && pg.privacyMode.Value != pg.IsPrivacyMode() {
+		pg.TogglePrivacyMode()
+	}
+	// Privacy mode may also be toggled from the navigation or with a key.
+	pg.privacyMode.Value = pg.IsPrivacyMode()
+
+	if pg.languageDrop.Changed() {
+		lang := pg.languages[pg.languageDrop.SelectedIndex()]
+		if pg.saveSetting(func(cfg *internal.Config) { cfg.Language = lang }) {
+			pg.LanguageSettingChanged()
+			// The page itself is recreated to show its labels in the new
+			// language.
+			pg.ParentNavigator().CloseCurrentPage()
+			pg.ParentNavigator().Display(NewSettingsPage(pg.Load))
+		}
+	}
+
+	if pg.currencyDrop.Changed() {
+		code := pg.currencies[pg.currencyDrop.SelectedIndex()]
+		if pg.saveSetting(func(cfg *internal.Config) { cfg.Currency = code }) {
+			pg.CurrencySettingChanged()
+		}
+	}
+
+	if pg.syncDrop.Changed() {
+		interval := pg.syncIntervals[pg.syncDrop.SelectedIndex()]
+		if pg.saveSetting(func(cfg *internal.Config) { cfg.SyncInterval = interval }) {
+			pg.WL.Syncer().SetInterval(interval)
+		}
+	}
+
+	if pg.autoLockDrop.Changed() {
+		timeout := pg.autoLocks[pg.autoLockDrop.SelectedIndex()]
+		if pg.saveSetting(func(cfg *internal.Config) { cfg.AutoLock = timeout }) {
+			pg.Locker.SetTimeout(timeout)
+		}
+	}
+
+	if pg.pinButton.Clicked() {
+		if pg.Locker.HasPIN() {
+			pg.removePIN()
+		} else {
+			pg.setPIN()
+		}
+	}
+}
+
+// setPIN asks for a PIN that unlocks the app in addition to the startup
+// password.
+func (pg *settingsPage) setPIN() {
+	pinModal := modal.NewPasswordModal(pg.Load).
+		Title(values.String(values.StrSetUnlockPIN)).
+		Description(values.StringF(values.StrSetUnlockPINInfo, internal.MinLockPINLength)).
+		Hint(values.String(values.StrPIN)).
+		NegativeButton(values.String(values.StrCancel), func() {})
+
+	pinModal.PositiveButton(values.String(values.StrSave), func(pin string, m *modal.PasswordModal) bool {
+		go func() {
+			err := pg.withAppDataDir(func(dir string) error { return pg.Locker.SetPIN(dir, pin) })
+			m.SetLoading(false)
+			if err != nil {
+				logrus.Info("setting unlock PIN:", err)
+				if errors.Is(err, internal.ErrInvalidLockPIN) {
+					m.SetError(values.StringF(values.StrInvalidUnlockPIN, internal.MinLockPINLength))
+				} else {
+					pg.Toast.NotifyError(values.StringF(values.StrSaveSettingsFailed, err))
+				}
+				pg.ParentWindow().Reload()
+				return
+			}
+
+			pg.Toast.Notify(values.String(values.StrUnlockPINSaved))
+			pg.ParentWindow().DismissModal(m.ID())
+		}()
+		return false
+	})
+	pg.ParentWindow().ShowModal(pinModal)
+}
+
+// removePIN removes the unlock PIN, so that only the startup password
+// unlocks the app.
+func (pg *settingsPage) removePIN() {
+	if err := pg.withAppDataDir(pg.Locker.RemovePIN); err != nil {
+		logrus.Info("removing unlock PIN:", err)
+		pg.Toast.NotifyError(values.StringF(values.StrSaveSettingsFailed, err))
+		return
+	}
+	pg.Toast.Notify(values.String(values.StrUnlockPINRemoved))
+}
+
+// withAppDataDir calls fn with the directory the app keeps its files in.
+func (pg *settingsPage) withAppDataDir(fn func(dir string) error) error {
+	dir, err := internal.AppDataDir()
+	if err != nil {
+		return err
+	}
+	return fn(dir)
+}
+
+// saveSetting changes the config file with update and reports whether it was
+// saved. Failures are shown in a toast.
+func (pg *settingsPage) saveSetting(update func(cfg *internal.Config)) bool {
+	if _, err := internal.UpdateConfig(update); err != nil {
+		logrus.Info("saving settings:", err)
+		pg.Toast.NotifyError(values.StringF(values.StrSaveSettingsFailed, err))
+		return false
+	}
+	pg.ParentWindow().Reload()
+	return true
+}
+
+// OnNavigatedFrom is called when the page is about to be removed from
+// the displayed window. This method should ideally be used to disable
+// features that are irrelevant when the page is NOT displayed.
+// NOTE: The page may be re-displayed on the app's window, in which case
+// OnNavigatedTo() will be called again. This method should not destroy UI
+// components unless they'll be recreated in the OnNavigatedTo() method.
+// Part of the load.Page interface.
+func (pg *settingsPage) OnNavigatedFrom() {}
+
+// Layout draws the page UI components into the provided C
+// to be eventually drawn on screen.
+// Part of the load.Page interface.
+func (pg *settingsPage) Layout(gtx C) D {
+	gtx.Constraints.Min = gtx.Constraints.Max
+	return components.UniformPadding(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+					layout.Rigid(pg.backButton.Layout),
+					layout.Rigid(func(gtx C) D {
+						return layout.Inset{Left: values.MarginPadding10}.Layout(gtx,
+							pg.Theme.H6(values.String(values.StrSettings)).Layout)
+					}),
+				)
+			}),
+			layout.Flexed(1, func(gtx C) D {
+				return layout.Inset{Top: values.MarginPadding16}.Layout(gtx, pg.settings)
+			}),
+		)
+	})
+}
+
+// settings lays out a row per setting. The dropdowns are stacked over their
+// rows, the open one last so that its menu covers the rows below.
+func (pg *settingsPage) settings(gtx C) D {
+	rows := make([]layout.FlexChild, 0, len(pg.dropDownLabels)+3)
+	for _, label := range pg.dropDownLabels {
+		label := label
+		rows = append(rows, layout.Rigid(func(gtx C) D {
+			return pg.settingsRow(gtx, pg.Theme.Body1(label).Layout)
+		}))
+	}
+	for _, checkBox := range []components.CheckBoxStyle{
+		pg.Theme.CheckBox(pg.darkMode, values.String(values.StrDarkMode)),
+		pg.Theme.CheckBox(pg.privacyMode, values.String(values.StrPrivacyMode)),
+	} {
+		checkBox := checkBox
+		rows = append(rows, layout.Rigid(func(gtx C) D {
+			return pg.settingsRow(gtx, checkBox.Layout)
+		}))
+	}
+	rows = append(rows, layout.Rigid(func(gtx C) D {
+		pg.pinButton.Text = values.String(values.StrSetUnlockPIN)
+		if pg.Locker.HasPIN() {
+			pg.pinButton.Text = values.String(values.StrRemoveUnlockPIN)
+		}
+		return pg.settingsRow(gtx, pg.pinButton.Layout)
+	}))
+
+	children := []layout.StackChild{
+		layout.Expanded(func(gtx C) D {
+			return layout.Flex{Axis: layout.Vertical}.Layout(gtx, rows...)
+		}),
+	}
+	var open layout.StackChild
+	hasOpen := false
+	for i, dropDown := range pg.dropDowns {
+		dropDown, top := dropDown, settingsRowHeight*unit.Dp(i)+values.MarginPadding8
+		child := layout.Stacked(func(gtx C) D {
+			return layout.Inset{Top: top}.Layout(gtx, func(gtx C) D {
+				return dropDown.Layout(gtx, settingsLabelWidth, false)
+			})
+		})
+		if dropDown.IsOpen() {
+			open, hasOpen = child, true
+			continue
+		}
+		children = append(children, child)
+	}
+	if hasOpen {
+		children = append(children, open)
+	}
+	return layout.Stack{Alignment: layout.NW}.Layout(gtx, children...)
+}
+
+// settingsRow lays out w vertically centered in a row of settingsRowHeight.
+func (pg *settingsPage) settingsRow(gtx C, w layout.Widget) D {
+	gtx.Constraints.Min.Y = gtx.Dp(settingsRowHeight)
+	gtx.Constraints.Max.Y = gtx.Constraints.Min.Y
+	return layout.W.Layout(gtx, w)
+}
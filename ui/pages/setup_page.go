@@ -0,0 +1,186 @@
+package pages
+
+import (
+	"errors"
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"github.com/sirupsen/logrus"
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/ui/components"
+	"go-monzo-wallet/ui/handlers"
+	"go-monzo-wallet/ui/modal"
+	"go-monzo-wallet/ui/values"
+	"os"
+	"strings"
+)
+
+const SetupPageID = "setup_page"
+
+// setupPage walks a new user through registering an OAuth client with Monzo
+// and saves its credentials to the config file.
+type setupPage struct {
+	*handlers.Load
+	*modal.GenericPageModal
+
+	scrollContainer *widget.List
+	clientID        components.Editor
+	clientSecret    components.Editor
+	redirectURL     components.Editor
+	saveButton      components.Button
+	exitButton      components.Button
+}
+
+// NewSetupPage returns the setup page, prefilled with cfg if it is not nil.
+// cfg should be the config file as read by LoadConfigFile, so that values
+// set by the environment or flags are not saved to the file.
+func NewSetupPage(l *handlers.Load, cfg *internal.Config) handlers.Page {
+	if cfg == nil {
+		cfg = internal.DefaultConfig()
+	}
+
+	pg := &setupPage{
+		Load:             l,
+		GenericPageModal: modal.NewGenericPageModal(SetupPageID),
+		scrollContainer: &widget.List{
+			List: layout.List{Axis: layout.Vertical},
+		},
+		clientID:     l.Theme.Editor(new(widget.Editor), values.String(values.StrClientID)),
+		clientSecret: l.Theme.EditorPassword(new(widget.Editor), values.String(values.StrClientSecret)),
+		redirectURL:  l.Theme.Editor(new(widget.Editor), values.String(values.StrRedirectURL)),
+		saveButton:   l.Theme.Button(values.String(values.StrSaveConfig)),
+		exitButton:   l.Theme.OutlineButton(values.String(values.StrExit)),
+	}
+
+	for _, editor := range []*components.Editor{&pg.clientID, &pg.clientSecret, &pg.redirectURL} {
+		editor.Editor.SingleLine = true
+	}
+	pg.clientID.Editor.SetText(cfg.ClientID)
+	pg.clientSecret.Editor.SetText(cfg.ClientSecret)
+	pg.redirectURL.Editor.SetText(cfg.RedirectURL)
+
+	return pg
+}
+
+// OnNavigatedTo is called when the page is about to be displayed and
+// may be used to initialize page features that are only relevant when
+// the page is displayed.
+// Part of the load.Page interface.
+func (pg *setupPage) OnNavigatedTo() {}
+
+// HandleUserInteractions is called just before Layout() to determine
+// if any user interaction recently occurred on the page and may be
+// used to update the page's UI components shortly before they are
+// displayed.
+// Part of the load.Page interface.
+func (pg *setupPage) HandleUserInteractions() {
+	if pg.saveButton.Clicked() {
+		pg.save()
+	}
+	if pg.exitButton.Clicked() {
+		pg.WL.Shutdown()
+		os.Exit(0)
+	}
+}
+
+// save writes the config and returns to the start page, which unlocks the
+// wallet with it.
+func (pg *setupPage) save() {
+	clientID := strings.TrimSpace(pg.clientID.Editor.Text())
+	clientSecret := strings.TrimSpace(pg.clientSecret.Editor.Text())
+	redirectURL := strings.TrimSpace(pg.redirectURL.Editor.Text())
+
+	pg.clientID.SetError("")
+	pg.clientSecret.SetError("")
+	pg.redirectURL.SetError("")
+
+	_, err := internal.UpdateConfig(func(cfg *internal.Config) {
+		cfg.ClientID = clientID
+		cfg.ClientSecret = clientSecret
+		cfg.RedirectURL = redirectURL
+	})
+	var invalid internal.ValidationError
+	if errors.As(err, &invalid) {
+		var other []string
+		for _, fieldErr := range invalid {
+			switch fieldErr.Field {
+			case internal.ConfigKeyClientID:
+				pg.clientID.SetError(fieldErr.Message)
+			case internal.ConfigKeyClientSecret:
+				pg.clientSecret.SetError(fieldErr.Message)
+			case internal.ConfigKeyRedirectURL:
+				pg.redirectURL.SetError(fieldErr.Message)
+			default:
+				other = append(other, fieldErr.Error())
+			}
+		}
+		// Fields that are not on the page come from the file, the
+		// environment or flags; they have to be fixed there.
+		if other != nil {
+			pg.Toast.NotifyError(strings.Join(other, "\n"))
+		}
+		return
+	}
+	if err != nil {
+		logrus.Info("saving config:", err)
+		pg.Toast.NotifyError(err.Error())
+		return
+	}
+
+	path, _ := internal.ConfigPath()
+	pg.Toast.Notify(values.StringF(values.StrConfigSaved, path))
+	pg.ParentNavigator().CloseCurrentPage()
+}
+
+// OnNavigatedFrom is called when the page is about to be removed from
+// the displayed window. This method should ideally be used to disable
+// features that are irrelevant when the page is NOT displayed.
+// NOTE: The page may be re-displayed on the app's window, in which case
+// OnNavigatedTo() will be called again. This method should not destroy UI
+// components unless they'll be recreated in the OnNavigatedTo() method.
+// Part of the load.Page interface.
+func (pg *setupPage) OnNavigatedFrom() {}
+
+// Layout draws the page UI components into the provided C
+// to be eventually drawn on screen.
+// Part of the load.Page interface.
+func (pg *setupPage) Layout(gtx C) D {
+	gtx.Constraints.Min = gtx.Constraints.Max
+	return components.UniformPadding(gtx, func(gtx C) D {
+		return layout.N.Layout(gtx, func(gtx C) D {
+			gtx.Constraints.Max.X = gtx.Dp(values.MarginPadding550)
+			gtx.Constraints.Min.X = gtx.Constraints.Max.X
+
+			content := []layout.Widget{
+				func(gtx C) D {
+					return layout.Center.Layout(gtx, func(gtx C) D {
+						return components.NewImage(pg.Theme.Icons.MonzoLogo).LayoutSize(gtx, values.MarginPadding150)
+					})
+				},
+				pg.Theme.Text(values.TextSize20, values.String(values.StrSetupTitle)).Layout,
+				pg.Theme.Body1(values.String(values.StrSetupIntro)).Layout,
+				pg.Theme.Body1(values.String(values.StrSetupStepPortal)).Layout,
+				pg.Theme.Body1(values.String(values.StrSetupStepClient)).Layout,
+				pg.redirectURL.Layout,
+				pg.Theme.Body1(values.String(values.StrSetupStepCopy)).Layout,
+				pg.clientID.Layout,
+				pg.clientSecret.Layout,
+				pg.buttons,
+			}
+
+			return pg.Theme.List(pg.scrollContainer).Layout(gtx, len(content), func(gtx C, i int) D {
+				return layout.Inset{Top: values.MarginPadding16}.Layout(gtx, content[i])
+			})
+		})
+	})
+}
+
+func (pg *setupPage) buttons(gtx C) D {
+	return layout.E.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+			layout.Rigid(pg.exitButton.Layout),
+			layout.Rigid(func(gtx C) D {
+				return layout.Inset{Left: values.MarginPadding10}.Layout(gtx, pg.saveButton.Layout)
+			}),
+		)
+	})
+}
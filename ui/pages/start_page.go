@@ -2,28 +2,23 @@ package pages
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"gioui.org/layout"
 	"gioui.org/widget"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 	"go-monzo-wallet/internal"
 	"go-monzo-wallet/ui/components"
 	"go-monzo-wallet/ui/handlers"
 	"go-monzo-wallet/ui/modal"
 	"go-monzo-wallet/ui/values"
 	"golang.org/x/oauth2"
-	"math"
-	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
-	"time"
 )
 
-const (
-	StartPageID = "start_page"
-	cfgFile     = "config.json"
-)
+const StartPageID = "start_page"
 
 type startPage struct {
 	*handlers.Load
@@ -33,21 +28,52 @@ type startPage struct {
 	// and the root WindowNavigator.
 	*modal.GenericPageModal
 
-	loading   bool
-	ctx       context.Context // page context
-	ctxCancel context.CancelFunc
+	loading       bool
+	setupRequired bool            // no config exists yet
+	ctx           context.Context // page context
+	ctxCancel     context.CancelFunc
+
+	syncEvents          <-chan internal.SyncEvent
+	unsubscribe         func()
+	syncButton          components.Button
+	budgetsButton       components.Button
+	subscriptionsButton components.Button
 
 	listLock        sync.Mutex
 	scrollContainer *widget.List
 
-	mainAccountsList internal.Accounts
+	// accountGroups holds the accounts of the wallet grouped by profile and
+	// account type. groupLists are the lists of the groups by groupKey.
+	accountGroups []accountGroup
+	groupLists    map[string]*components.ClickableList
+	profileRows   []profileRow
 
-	shadowBox    *components.Shadow
-	accountsList *components.ClickableList
+	shadowBox        *components.Shadow
+	addProfileButton components.Button
+
+	profileName  components.Editor
+	accountTypes map[string]*widget.Bool
 
 	wallectSelected func()
 }
 
+// accountGroup is a list of accounts of the same profile and type.
+type accountGroup struct {
+	profile     *internal.Profile
+	accountType string
+	accounts    internal.Accounts
+}
+
+func (g accountGroup) key() string {
+	return g.profile.ID + "/" + g.accountType
+}
+
+// profileRow is a profile in the profile list.
+type profileRow struct {
+	profile      *internal.Profile
+	removeButton components.IconButton
+}
+
 func NewStartPage(l *handlers.Load) handlers.Page {
 	sp := &startPage{
 		Load:             l,
@@ -59,10 +85,23 @@ func NewStartPage(l *handlers.Load) handlers.Page {
 				Alignment: layout.Middle,
 			},
 		},
-		shadowBox: l.Theme.Shadow(),
+		shadowBox:           l.Theme.Shadow(),
+		syncButton:          l.Theme.OutlineButton(values.String(values.StrSyncNow)),
+		budgetsButton:       l.Theme.OutlineButton(values.String(values.StrBudgets)),
+		subscriptionsButton: l.Theme.OutlineButton(values.String(values.StrSubscriptions)),
+		addProfileButton:    l.Theme.OutlineButton(values.String(values.StrAddProfile)),
+		groupLists:          make(map[string]*components.ClickableList),
+		accountTypes:        make(map[string]*widget.Bool),
 	}
 
-	sp.accountsList = l.Theme.NewClickableList(layout.Vertical)
+	sp.profileName = l.Theme.Editor(new(widget.Editor), values.String(values.StrProfileName))
+	sp.profileName.Editor.SingleLine = true
+	for _, accountType := range internal.AccountTypes {
+		sp.accountTypes[accountType] = new(widget.Bool)
+	}
+	sp.wallectSelected = func() {
+		sp.ParentNavigator().Display(NewWalletPage(sp.Load))
+	}
 
 	return sp
 }
@@ -72,24 +111,17 @@ func NewStartPage(l *handlers.Load) handlers.Page {
 // the page is displayed.
 // Part of the load.Page interface.
 func (sp *startPage) OnNavigatedTo() {
+	sp.ctx, sp.ctxCancel = context.WithCancel(context.TODO())
+
+	sp.syncEvents, sp.unsubscribe = sp.WL.Syncer().Subscribe()
+	go sp.listenForSyncEvents(sp.syncEvents)
 
 	if sp.WL.LoadedWallet() {
+		sp.showAccounts()
 		sp.loading = false
 	} else {
-		err := sp.openWallet()
-		if err != nil {
-
-			startupPasswordModal := modal.NewInfoModal(sp.Load).
-				Title(values.String(values.StrUnlockWithPassword)).
-				NegativeButton(values.String(values.StrExit), func() {
-					sp.WL.Shutdown()
-					os.Exit(0)
-				})
-			sp.ParentWindow().ShowModal(startupPasswordModal)
-		}
-
+		sp.openWallet()
 	}
-
 }
 
 // HandleUserInteractions is called just before Layout() to determine
@@ -100,12 +132,43 @@ func (sp *startPage) OnNavigatedTo() {
 func (sp *startPage) HandleUserInteractions() {
 
 	sp.listLock.Lock()
-	mainWalletList := sp.mainAccountsList
+	groups := sp.accountGroups
+	profileRows := sp.profileRows
 	sp.listLock.Unlock()
 
-	if ok, selectedItem := sp.accountsList.ItemClicked(); ok {
-		sp.WL.SelectedAccount = mainWalletList[selectedItem]
-		sp.wallectSelected()
+	if sp.setupRequired {
+		sp.setupRequired = false
+		sp.ParentNavigator().Display(NewSetupPage(sp.Load, nil))
+		return
+	}
+
+	sp.syncButton.SetEnabled(!sp.WL.Syncer().Syncing())
+	if sp.syncButton.Clicked() {
+		sp.WL.Syncer().SyncNow()
+	}
+
+	if sp.budgetsButton.Clicked() {
+		sp.ParentNavigator().Display(NewBudgetsPage(sp.Load))
+	}
+
+	if sp.subscriptionsButton.Clicked() {
+		sp.ParentNavigator().Display(NewSubscriptionsPage(sp.Load))
+	}
+
+	for _, group := range groups {
+		if ok, selectedItem := sp.groupList(group).ItemClicked(); ok {
+			sp.WL.SelectedAccount = group.accounts[selectedItem]
+			sp.wallectSelected()
+		}
+	}
+
+	if sp.addProfileButton.Clicked() {
+		sp.showAddProfileModal()
+	}
+	for _, row := range profileRows {
+		if row.removeButton.Button.Clicked() {
+			sp.confirmRemoveProfile(row.profile)
+		}
 	}
 }
 
@@ -116,7 +179,32 @@ func (sp *startPage) HandleUserInteractions() {
 // OnNavigatedTo() will be called again. This method should not destroy UI
 // components unless they'll be recreated in the OnNavigatedTo() method.
 // Part of the load.Page interface.
-func (sp *startPage) OnNavigatedFrom() {}
+func (sp *startPage) OnNavigatedFrom() {
+	if sp.ctxCancel != nil {
+		sp.ctxCancel()
+	}
+	if sp.unsubscribe != nil {
+		sp.unsubscribe()
+	}
+}
+
+// listenForSyncEvents updates the page as the wallet is synced in the
+// background until the subscription is cancelled.
+func (sp *startPage) listenForSyncEvents(events <-chan internal.SyncEvent) {
+	for event := range events {
+		switch event := event.(type) {
+		case internal.SyncFinished, internal.TransactionReceived:
+			sp.showAccounts()
+		case internal.SyncFailed:
+			// Only the profiles that failed keep their old accounts.
+			sp.showAccounts()
+			if errors.Is(event.Err, internal.ErrTokenRevoked) {
+				sp.Toast.NotifyError(event.Err.Error())
+			}
+		}
+		sp.ParentWindow().Reload()
+	}
+}
 
 // Layout draws the page UI components into the provided C
 // to be eventually drawn on screen.
@@ -155,7 +243,6 @@ func (sp *startPage) loadingSection(gtx values.C) values.D {
 				layout.Rigid(func(gtx values.C) values.D {
 					return layout.Center.Layout(gtx, func(gtx values.C) values.D {
 						return components.NewImage(sp.Theme.Icons.MonzoLogo).LayoutSize(gtx, values.MarginPadding150)
-						return values.D{}
 					})
 				}),
 				layout.Rigid(func(gtx values.C) values.D {
@@ -168,7 +255,7 @@ func (sp *startPage) loadingSection(gtx values.C) values.D {
 						return layout.Inset{Top: values.MarginPadding24}.Layout(gtx, loadStatus.Layout)
 					}
 					pageContent := []func(gtx values.C) values.D{
-						sp.Theme.Text(values.TextSize20, values.String(values.StrSelectWalletToOpen)).Layout,
+						sp.titleRow,
 						sp.walletSection, // wallet list layout
 					}
 
@@ -193,6 +280,19 @@ func (sp *startPage) loadingSection(gtx values.C) values.D {
 	)
 }
 
+func (sp *startPage) titleRow(gtx values.C) values.D {
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		layout.Flexed(1, sp.Theme.Text(values.TextSize20, values.String(values.StrSelectWalletToOpen)).Layout),
+		layout.Rigid(func(gtx values.C) values.D {
+			return layout.Inset{Right: values.MarginPadding8}.Layout(gtx, sp.budgetsButton.Layout)
+		}),
+		layout.Rigid(func(gtx values.C) values.D {
+			return layout.Inset{Right: values.MarginPadding8}.Layout(gtx, sp.subscriptionsButton.Layout)
+		}),
+		layout.Rigid(sp.syncButton.Layout),
+	)
+}
+
 // Mobile layout
 func (sp *startPage) layoutMobile(gtx values.C) values.D {
 	gtx.Constraints.Min = gtx.Constraints.Max // use maximum height & width
@@ -203,142 +303,617 @@ func (sp *startPage) layoutMobile(gtx values.C) values.D {
 		layout.Rigid(func(gtx values.C) values.D {
 			return sp.loadingSection(gtx)
 		}),
-		//layout.Rigid(func(gtx values.C) values.D {
-		//	if sp.loading {
-		//		return values.D{}
-		//	}
-		//
-		//	gtx.Constraints.Max.X = gtx.Dp(values.MarginPadding350)
-		//	return layout.Inset{
-		//		Left:  values.MarginPadding24,
-		//		Right: values.MarginPadding24,
-		//	}.Layout(gtx, sp.addWalletButton.Layout)
-		//}),
 	)
 }
 
-func (sp *startPage) openWallet() error {
-	cfg, err := initConfig()
+func (sp *startPage) openWallet() {
+	cfg, err := internal.LoadConfig()
 	if err != nil {
 		logrus.Info("reading config:", err)
-		// show err dialog
-		return err
-	}
-
-	sp.wallectSelected = func() {
-		//sp.ParentNavigator().ClearStackAndDisplay(NewWalletPage(sp.Load))
-	}
-	token, err := sp.WL.Connect(cfg)
-	if err != nil {
-		logrus.Info("connecting to monzo:", err)
-		// show err dialog
-		return err
+		sp.showConfigError(err)
+		return
 	}
+	sp.WL.UseProvider(cfg.Provider())
+	sp.WL.Syncer().SetInterval(cfg.SyncInterval)
 
-	startupPasswordModal := modal.NewInfoModal(sp.Load).
+	startupPasswordModal := modal.NewPasswordModal(sp.Load).
 		Title(values.String(values.StrUnlockWithPassword)).
-		Body(values.String(values.StrStartupPassword)).
+		Hint(values.String(values.StrStartupPassword)).
+		SetCancelable(false).
 		NegativeButton(values.String(values.StrExit), func() {
 			os.Exit(0)
 		})
 
-	startupPasswordModal.PositiveButton(values.String(values.StrUnlock), func(bool) bool {
-		err = retry(5, time.Second*3, func() error {
-			err = sp.WL.FetchAccounts(token.AccessToken)
-			if err != nil {
+	startupPasswordModal.PositiveButton(values.String(values.StrUnlock), func(password string, m *modal.PasswordModal) bool {
+		go func() {
+			store, err := sp.unlockWallet(password)
+			if err == nil {
+				// The same password unlocks the app once it locks while
+				// idle.
+				if err := sp.Locker.SetPassphrase(password); err != nil {
+					logrus.Info("setting up the lock:", err)
+				}
+			}
+			if err == nil && sp.WL.LoadedWallet() {
+				// Show the cached accounts right away and refresh them in
+				// the background.
+				sp.showAccounts()
+				sp.loading = false
+				sp.ParentWindow().DismissModal(m.ID())
+				if err := sp.syncWallet(cfg.OAuth2(), password, store); err != nil {
+					logrus.Info("syncing wallet:", err)
+					sp.Toast.NotifyError(loginErrorMessage(err))
+				} else {
+					sp.startWebhooks(cfg)
+				}
+				sp.ParentWindow().Reload()
+				return
+			}
+			if err == nil {
+				err = sp.syncWallet(cfg.OAuth2(), password, store)
+			}
 
-				logrus.Info("fetching accounts:", err)
-				sp.Toast.NotifyError(err.Error())
-				return err
+			m.SetLoading(false)
+			if err != nil {
+				logrus.Info("unlocking wallet:", err)
+				if errors.Is(err, internal.ErrWrongPassphrase) {
+					m.SetError(values.String(values.StrInvalidPassphrase))
+				} else {
+					sp.Toast.NotifyError(loginErrorMessage(err))
+				}
+				sp.ParentWindow().Reload()
+				return
 			}
 
-			accounts := sp.WL.AccountsList()
+			sp.loading = false
+			sp.ParentWindow().DismissModal(m.ID())
+			sp.startWebhooks(cfg)
+		}()
+		return false
+	})
+	sp.ParentWindow().ShowModal(startupPasswordModal)
+}
+
+// startWebhooks receives new transactions in real time if a webhook URL is
+// configured.
+func (sp *startPage) startWebhooks(cfg *internal.Config) {
+	if cfg.WebhookURL == "" {
+		return
+	}
+	if err := sp.WL.StartWebhooks(cfg.WebhookAddr, cfg.WebhookURL); err != nil {
+		logrus.Info("starting webhooks:", err)
+		sp.Toast.NotifyError(values.StringF(values.StrWebhooksFailed, err.Error()), components.Long)
+	}
+}
 
-			sp.listLock.Lock()
-			sp.mainAccountsList = accounts
-			sp.listLock.Unlock()
+// showConfigError opens the setup page if there is no config yet, or explains
+// what is wrong with the config and offers to fix it there.
+func (sp *startPage) showConfigError(err error) {
+	if errors.Is(err, internal.ErrNoConfig) {
+		// Pages cannot be displayed while this one is being navigated
+		// to, so the setup page is opened on the next frame.
+		sp.setupRequired = true
+		sp.ParentWindow().Reload()
+		return
+	}
 
-			return nil
+	path, _ := internal.ConfigPath()
+	errorModal := modal.NewInfoModal(sp.Load).
+		Title(values.String(values.StrInvalidConfig)).
+		Body(values.StringF(values.StrInvalidConfigInfo, path, err)).
+		SetCancelable(false).
+		NegativeButton(values.String(values.StrExit), func() {
+			sp.WL.Shutdown()
+			os.Exit(0)
 		})
+	errorModal.PositiveButton(values.String(values.StrFixConfig), func(isChecked bool) bool {
+		sp.ParentWindow().DismissModal(errorModal.ID())
+		cfg, err := internal.LoadConfigFile()
 		if err != nil {
-			logrus.Info("retry error:", err)
-			sp.Toast.NotifyError(err.Error())
-			return false
+			logrus.Info("reading config file:", err)
 		}
-
-		sp.loading = false
-		startupPasswordModal.SetLoading(false)
-		startupPasswordModal.Dismiss()
-		sp.ParentWindow().DismissModal(startupPasswordModal.ID())
-		return true
+		sp.ParentNavigator().Display(NewSetupPage(sp.Load, cfg))
+		return false
 	})
-	sp.ParentWindow().ShowModal(startupPasswordModal)
-	return nil
-
+	sp.ParentWindow().ShowModal(errorModal)
 }
 
-func initConfig() (*oauth2.Config, error) {
+// unlockWallet opens the token store with passphrase and the offline cache,
+// which makes the accounts of the last session available without network
+// access.
+func (sp *startPage) unlockWallet(passphrase string) (*internal.TokenStore, error) {
+	dir, err := internal.AppDataDir()
+	if err != nil {
+		return nil, err
+	}
 
-	// Use config file from the flag.
-	viper.SetConfigFile(cfgFile)
+	store, err := internal.OpenTokenStore(filepath.Join(dir, internal.TokenFileName), passphrase)
+	if err != nil {
+		return nil, err
+	}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err != nil {
+	profiles, err := internal.LoadProfiles(dir)
+	if err != nil {
 		return nil, err
 	}
+	sp.WL.SetProfiles(profiles)
 
-	var cfg oauth2.Config
-	if err := viper.Unmarshal(&cfg); err != nil {
+	if err := sp.WL.OpenCache(filepath.Join(dir, internal.StoreFileName)); err != nil {
 		return nil, err
 	}
-	return &cfg, nil
+	return store, nil
 }
 
-func retry(attempts int, sleep time.Duration, f func() error) error {
-	if err := f(); err != nil {
-		if s, ok := err.(stop); ok {
-			// Return the original error for later checking
-			return s.error
-		}
+// syncWallet loads the accounts of all profiles with the tokens in their
+// token stores, which are unlocked with passphrase like store, the one of
+// the default profile. The browser login is only started if the default
+// profile has no token yet or if the stored refresh token of a profile is no
+// longer accepted.
+func (sp *startPage) syncWallet(cfg *oauth2.Config, passphrase string, store *internal.TokenStore) error {
+	token, err := store.Load()
+	if errors.Is(err, internal.ErrNoToken) {
+		token, err = sp.login(cfg, store)
+	}
+	if err != nil {
+		return err
+	}
+	sp.WL.Authenticate(store.TokenSource(context.Background(), cfg, token))
 
-		if attempts--; attempts > 0 {
-			// Add some randomness to prevent creating a Thundering Herd
-			jitter := time.Duration(rand.Int63n(int64(sleep)))
-			sleep = sleep + jitter/2
+	dir, err := internal.AppDataDir()
+	if err != nil {
+		return err
+	}
+	missing, err := sp.WL.UnlockProfiles(context.Background(), dir, cfg, passphrase)
+	if err != nil {
+		return err
+	}
+	for _, profile := range missing {
+		sp.Toast.NotifyError(values.StringF(values.StrProfileNotLoggedIn, profile.Name))
+	}
 
-			time.Sleep(sleep)
-			return retry(attempts, 2*sleep, f)
+	err = sp.fetchAccounts()
+	var profileErrs internal.ProfileErrors
+	if errors.As(err, &profileErrs) && len(profileErrs.Revoked()) > 0 {
+		logrus.Info("stored token rejected, logging in again:", err)
+		for _, profile := range profileErrs.Revoked() {
+			profileStore := store
+			if profile.ID != internal.DefaultProfileID {
+				if profileStore, err = internal.OpenProfileTokenStore(dir, profile, passphrase); err != nil {
+					return err
+				}
+			}
+			if token, err = sp.login(cfg, profileStore); err != nil {
+				return err
+			}
+			sp.WL.AuthenticateProfile(profile.ID, profileStore.TokenSource(context.Background(), cfg, token))
 		}
+		err = sp.fetchAccounts()
+	}
+	// Profiles that still fail are flagged in the profile list, the others
+	// are synced as usual.
+	if err != nil && !(errors.As(err, &profileErrs) && sp.WL.LoadedWallet()) {
 		return err
 	}
 
+	sp.WL.Syncer().Start()
 	return nil
 }
 
-type stop struct {
-	error
+// login runs the OAuth flow in the browser and saves the new token.
+func (sp *startPage) login(cfg *oauth2.Config, store *internal.TokenStore) (*oauth2.Token, error) {
+	token, err := sp.WL.Connect(sp.ctx, cfg)
+	if err != nil {
+		logrus.Info("connecting to monzo:", err)
+		return nil, err
+	}
+	return token, store.Save(token)
 }
 
-func (sp *startPage) walletList(gtx values.C) values.D {
+// loginErrorMessage returns the message shown to the user for an error
+// returned while logging in.
+func loginErrorMessage(err error) string {
+	var loginErr *internal.LoginError
+	switch {
+	case errors.Is(err, internal.ErrLoginTimeout):
+		return values.String(values.StrLoginTimedOut)
+	case errors.Is(err, internal.ErrLoginCancelled):
+		return values.String(values.StrLoginCancelled)
+	case errors.Is(err, internal.ErrStateMismatch):
+		return values.String(values.StrLoginStateMismatch)
+	case errors.As(err, &loginErr):
+		msg := loginErr.Description
+		if msg == "" {
+			msg = loginErr.Code
+		}
+		return values.StringF(values.StrLoginFailed, msg)
+	default:
+		return err.Error()
+	}
+}
+
+func (sp *startPage) fetchAccounts() error {
+	// Not tied to the page context: the accounts may be opened from the
+	// cache before this first sync completes.
+	err := sp.WL.Syncer().Sync(context.Background())
+	if err != nil {
+		logrus.Info("fetching accounts:", err)
+	}
+	// The profiles that loaded are shown even if others failed.
+	if sp.WL.LoadedWallet() {
+		sp.showAccounts()
+	}
+	return err
+}
+
+// showAccounts updates the account list with the accounts of the wallet,
+// grouped by profile and account type in the order of the profiles.
+func (sp *startPage) showAccounts() {
+	accounts := sp.WL.AccountsList()
+	profiles := sp.WL.Profiles()
+
+	var groups []accountGroup
+	for _, profile := range profiles {
+		// Types the profile no longer requests are kept until the next
+		// sync removes their accounts.
+		accountTypes := append([]string(nil), profile.AccountTypes...)
+		for _, accountType := range internal.AccountTypes {
+			if !containsString(accountTypes, accountType) {
+				accountTypes = append(accountTypes, accountType)
+			}
+		}
+
+		for _, accountType := range accountTypes {
+			group := accountGroup{profile: profile, accountType: accountType}
+			for _, account := range accounts {
+				if profileIDOf(account) == profile.ID && accountTypeOf(account) == accountType {
+					group.accounts = append(group.accounts, account)
+				}
+			}
+			if len(group.accounts) > 0 {
+				groups = append(groups, group)
+			}
+		}
+	}
+
 	sp.listLock.Lock()
-	mainWalletList := sp.mainAccountsList
-	sp.listLock.Unlock()
+	defer sp.listLock.Unlock()
+	sp.accountGroups = groups
+
+	rows := make([]profileRow, 0, len(profiles))
+	for _, profile := range profiles {
+		row := profileRow{profile: profile}
+		for _, old := range sp.profileRows {
+			if old.profile.ID == profile.ID {
+				row.removeButton = old.removeButton
+			}
+		}
+		if row.removeButton.Button == nil {
+			row.removeButton = sp.Theme.IconButton(sp.Theme.Icons.ContentRemove)
+		}
+		rows = append(rows, row)
+	}
+	sp.profileRows = rows
+}
 
-	return sp.accountsList.Layout(gtx, len(mainWalletList), func(gtx values.C, i int) values.D {
-		return sp.walletWrapper(gtx, mainWalletList[i])
-	})
+// profileIDOf returns the profile ID of the account. Accounts cached before
+// profiles were introduced belong to the default profile.
+func profileIDOf(account *internal.Account) string {
+	if account.ProfileID == "" {
+		return internal.DefaultProfileID
+	}
+	return account.ProfileID
+}
+
+// accountTypeOf returns the type of the account. Accounts cached before
+// types were recorded are retail accounts, the only type loaded then.
+func accountTypeOf(account *internal.Account) string {
+	if account.Type == "" {
+		return internal.AccountTypeRetail
+	}
+	return account.Type
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// groupList returns the list widget of the group, creating it the first time
+// the group is shown.
+func (sp *startPage) groupList(group accountGroup) *components.ClickableList {
+	sp.listLock.Lock()
+	defer sp.listLock.Unlock()
+
+	list, ok := sp.groupLists[group.key()]
+	if !ok {
+		list = sp.Theme.NewClickableList(layout.Vertical)
+		sp.groupLists[group.key()] = list
+	}
+	return list
+}
+
+// accountTypeName returns the heading of the accounts of a type.
+func accountTypeName(accountType string) string {
+	switch accountType {
+	case internal.AccountTypeJoint:
+		return values.String(values.StrAccountTypeJoint)
+	case internal.AccountTypeBusiness:
+		return values.String(values.StrAccountTypeBusiness)
+	default:
+		return values.String(values.StrAccountTypeRetail)
+	}
+}
+
+func (sp *startPage) groupSection(gtx values.C, group accountGroup) values.D {
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx values.C) values.D {
+			title := sp.Theme.Body2(values.StringF(values.StrAccountGroup, group.profile.Name, accountTypeName(group.accountType)))
+			title.Color = sp.Theme.Color.GrayText2
+			return layout.Inset{Top: values.MarginPadding16, Left: values.MarginPadding5}.Layout(gtx, title.Layout)
+		}),
+		layout.Rigid(func(gtx values.C) values.D {
+			return sp.groupList(group).Layout(gtx, len(group.accounts), func(gtx values.C, i int) values.D {
+				return sp.walletWrapper(gtx, group.accounts[i])
+			})
+		}),
+	)
 }
 
 func (sp *startPage) walletSection(gtx values.C) values.D {
-	walletSections := []func(gtx values.C) values.D{
-		sp.walletList,
+	sp.listLock.Lock()
+	groups := sp.accountGroups
+	sp.listLock.Unlock()
+
+	walletSections := make([]func(gtx values.C) values.D, 0, len(groups)+1)
+	for _, group := range groups {
+		group := group
+		walletSections = append(walletSections, func(gtx values.C) values.D {
+			return sp.groupSection(gtx, group)
+		})
 	}
+	walletSections = append(walletSections, sp.profileSection)
 
 	return sp.Theme.List(sp.scrollContainer).Layout(gtx, len(walletSections), func(gtx values.C, i int) values.D {
 		return walletSections[i](gtx)
 	})
 }
 
+// profileSection lists the profiles with a button to remove each except the
+// default one, whose token store holds the startup password, and a button to
+// add another.
+func (sp *startPage) profileSection(gtx values.C) values.D {
+	sp.listLock.Lock()
+	rows := sp.profileRows
+	sp.listLock.Unlock()
+
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx values.C) values.D {
+			return layout.Inset{Top: values.MarginPadding24}.Layout(gtx,
+				sp.Theme.Text(values.TextSize20, values.String(values.StrProfiles)).Layout)
+		}),
+	}
+	failed := sp.failedProfiles()
+	for _, row := range rows {
+		row := row
+		children = append(children, layout.Rigid(func(gtx values.C) values.D {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, func(gtx values.C) values.D {
+					return layout.Inset{Top: values.MarginPadding10, Bottom: values.MarginPadding10}.Layout(gtx, func(gtx values.C) values.D {
+						profileErr := failed.Failed(row.profile.ID)
+						if profileErr == nil {
+							return sp.Theme.Body1(row.profile.Name).Layout(gtx)
+						}
+						return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+							layout.Rigid(sp.Theme.Body1(row.profile.Name).Layout),
+							layout.Rigid(func(gtx values.C) values.D {
+								lbl := sp.Theme.Caption(values.StringF(values.StrProfileSyncFailed, profileErr.Err))
+								lbl.Color = sp.Theme.Color.Danger
+								return lbl.Layout(gtx)
+							}),
+						)
+					})
+				}),
+				layout.Rigid(func(gtx values.C) values.D {
+					if row.profile.ID == internal.DefaultProfileID {
+						return values.D{}
+					}
+					return row.removeButton.Layout(gtx)
+				}),
+			)
+		}))
+	}
+	children = append(children, layout.Rigid(func(gtx values.C) values.D {
+		return layout.Inset{Top: values.MarginPadding10}.Layout(gtx, sp.addProfileButton.Layout)
+	}))
+
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// failedProfiles returns the errors of the profiles that failed to load in
+// the last sync.
+func (sp *startPage) failedProfiles() internal.ProfileErrors {
+	var err error
+	switch event := sp.WL.Syncer().Status().(type) {
+	case internal.SyncFailed:
+		err = event.Err
+	case internal.SyncOffline:
+		err = event.Err
+	}
+	var profileErrs internal.ProfileErrors
+	errors.As(err, &profileErrs)
+	return profileErrs
+}
+
+// showAddProfileModal asks for the name and account types of a new profile.
+func (sp *startPage) showAddProfileModal() {
+	sp.profileName.Editor.SetText("")
+	for accountType, checked := range sp.accountTypes {
+		checked.Value = accountType == internal.AccountTypeRetail
+	}
+
+	profileModal := modal.NewInfoModal(sp.Load).
+		Title(values.String(values.StrAddProfile)).
+		Body(values.String(values.StrAddProfileInfo)).
+		UseCustomWidget(sp.profileForm).
+		NegativeButton(values.String(values.StrCancel), func() {})
+
+	profileModal.PositiveButton(values.String(values.StrNext), func(isChecked bool) bool {
+		var accountTypes []string
+		for _, accountType := range internal.AccountTypes {
+			if sp.accountTypes[accountType].Value {
+				accountTypes = append(accountTypes, accountType)
+			}
+		}
+
+		name := strings.TrimSpace(sp.profileName.Editor.Text())
+		switch {
+		case name == "":
+			sp.Toast.NotifyError(values.String(values.StrMissingProfileName))
+			return false
+		case len(accountTypes) == 0:
+			sp.Toast.NotifyError(values.String(values.StrNoAccountTypes))
+			return false
+		}
+
+		profile, err := internal.NewProfile(name, accountTypes)
+		if err != nil {
+			sp.Toast.NotifyError(err.Error())
+			return false
+		}
+		sp.ParentWindow().DismissModal(profileModal.ID())
+		sp.loginProfile(profile)
+		return false
+	})
+	sp.ParentWindow().ShowModal(profileModal)
+}
+
+// profileForm lays out the name editor and account type check boxes of a new
+// profile.
+func (sp *startPage) profileForm(gtx values.C) values.D {
+	children := []layout.FlexChild{
+		layout.Rigid(sp.profileName.Layout),
+	}
+	for _, accountType := range internal.AccountTypes {
+		checkBox := sp.Theme.CheckBox(sp.accountTypes[accountType], accountTypeName(accountType))
+		children = append(children, layout.Rigid(func(gtx values.C) values.D {
+			return layout.Inset{Top: values.MarginPadding10}.Layout(gtx, checkBox.Layout)
+		}))
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
+
+// loginProfile asks for the startup password, which protects the token store
+// of the profile like that of the default one, and logs in to the profile in
+// the browser.
+func (sp *startPage) loginProfile(profile *internal.Profile) {
+	passwordModal := modal.NewPasswordModal(sp.Load).
+		Title(values.StringF(values.StrLogInProfile, profile.Name)).
+		Description(values.StringF(values.StrLogInProfileInfo, profile.Name)).
+		Hint(values.String(values.StrStartupPassword)).
+		NegativeButton(values.String(values.StrCancel), func() {})
+
+	passwordModal.PositiveButton(values.String(values.StrLogIn), func(password string, m *modal.PasswordModal) bool {
+		go func() {
+			err := sp.addProfile(profile, password)
+			m.SetLoading(false)
+			if err != nil {
+				logrus.Info("adding profile:", err)
+				if errors.Is(err, internal.ErrWrongPassphrase) {
+					m.SetError(values.String(values.StrInvalidPassphrase))
+				} else {
+					sp.Toast.NotifyError(loginErrorMessage(err))
+				}
+				sp.ParentWindow().Reload()
+				return
+			}
+
+			sp.showAccounts()
+			sp.Toast.Notify(values.StringF(values.StrProfileAdded, profile.Name))
+			sp.ParentWindow().DismissModal(m.ID())
+		}()
+		return false
+	})
+	sp.ParentWindow().ShowModal(passwordModal)
+}
+
+// addProfile logs in to the new profile, saves its token and adds it to the
+// wallet and the profile list.
+func (sp *startPage) addProfile(profile *internal.Profile, passphrase string) error {
+	if err := checkPassphrase(passphrase); err != nil {
+		return err
+	}
+	cfg, err := internal.LoadConfig()
+	if err != nil {
+		return err
+	}
+	dir, err := internal.AppDataDir()
+	if err != nil {
+		return err
+	}
+
+	store, err := internal.OpenProfileTokenStore(dir, profile, passphrase)
+	if err != nil {
+		return err
+	}
+	token, err := sp.login(cfg.OAuth2(), store)
+	if err != nil {
+		return err
+	}
+
+	sp.WL.AddProfile(profile, store.TokenSource(context.Background(), cfg.OAuth2(), token))
+	if err := internal.SaveProfiles(dir, sp.WL.Profiles()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// confirmRemoveProfile removes the profile, its token and its cached accounts
+// once confirmed.
+func (sp *startPage) confirmRemoveProfile(profile *internal.Profile) {
+	removeModal := modal.NewInfoModal(sp.Load).
+		Title(values.String(values.StrRemoveProfile)).
+		Body(values.StringF(values.StrConfirmRemoveProfile, profile.Name)).
+		NegativeButton(values.String(values.StrCancel), func() {})
+
+	removeModal.PositiveButton(values.String(values.StrRemove), func(isChecked bool) bool {
+		removeModal.SetLoading(true)
+		go func() {
+			err := sp.removeProfile(profile)
+			removeModal.SetLoading(false)
+			if err != nil {
+				logrus.Info("removing profile:", err)
+				sp.Toast.NotifyError(err.Error())
+				sp.ParentWindow().Reload()
+				return
+			}
+
+			sp.showAccounts()
+			sp.Toast.Notify(values.StringF(values.StrProfileRemoved, profile.Name))
+			sp.ParentWindow().DismissModal(removeModal.ID())
+		}()
+		return false
+	})
+	sp.ParentWindow().ShowModal(removeModal)
+}
+
+func (sp *startPage) removeProfile(profile *internal.Profile) error {
+	dir, err := internal.AppDataDir()
+	if err != nil {
+		return err
+	}
+	if err := sp.WL.RemoveProfile(profile.ID); err != nil {
+		return err
+	}
+	if err := internal.SaveProfiles(dir, sp.WL.Profiles()); err != nil {
+		return err
+	}
+	return internal.RemoveProfileToken(dir, profile)
+}
+
 func (sp *startPage) walletWrapper(gtx values.C, item *internal.Account) values.D {
 	sp.shadowBox.SetShadowRadius(14)
 	return components.LinearLayout{
@@ -385,7 +960,7 @@ func (sp *startPage) walletWrapper(gtx values.C, item *internal.Account) values.
 			)
 		}),
 		layout.Flexed(1, func(gtx values.C) values.D {
-			balanceLabel := sp.Theme.Body1(fmt.Sprint(roundFloat(item.Balance, 2)))
+			balanceLabel := sp.Theme.Body1(sp.FormatMoney(item.Balance))
 			balanceLabel.Color = sp.Theme.Color.GrayText2
 			return layout.Inset{
 				Right: values.MarginPadding10,
@@ -398,21 +973,34 @@ func (sp *startPage) walletWrapper(gtx values.C, item *internal.Account) values.
 
 func (sp *startPage) syncStatusIcon(gtx values.C) values.D {
 	var (
-		syncStatusIcon *components.Image
+		syncStatusIcon layout.Widget
 		syncStatus     string
 	)
 
-	switch {
-	case sp.WL.LoadedWallet():
-		syncStatusIcon = components.NewImage(sp.Theme.Icons.SuccessIcon)
+	failedIcon := components.NewImage(sp.Theme.Icons.FailedIcon).Layout16dp
+	switch event := sp.WL.Syncer().Status().(type) {
+	case internal.SyncStarted:
+		syncStatusIcon = sp.syncingIcon
+		syncStatus = values.String(values.StrSyncingState)
+	case internal.SyncProgress:
+		syncStatusIcon = sp.syncingIcon
+		syncStatus = values.StringF(values.StrSyncingAccounts, event.Done, event.Total)
+	case internal.SyncFinished:
+		syncStatusIcon = components.NewImage(sp.Theme.Icons.SuccessIcon).Layout16dp
 		syncStatus = values.String(values.StrSynced)
+	case internal.SyncOffline:
+		syncStatusIcon = failedIcon
+		syncStatus = values.String(values.StrOffline)
+	case internal.SyncFailed:
+		syncStatusIcon = failedIcon
+		syncStatus = values.String(values.StrSyncFailed)
 	default:
-		syncStatusIcon = components.NewImage(sp.Theme.Icons.FailedIcon)
+		syncStatusIcon = failedIcon
 		syncStatus = values.String(values.StrWalletNotSynced)
 	}
 
 	return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
-		layout.Rigid(syncStatusIcon.Layout16dp),
+		layout.Rigid(syncStatusIcon),
 		layout.Rigid(func(gtx values.C) values.D {
 			return layout.Inset{
 				Left: values.MarginPadding5,
@@ -421,7 +1009,8 @@ func (sp *startPage) syncStatusIcon(gtx values.C) values.D {
 	)
 }
 
-func roundFloat(val float64, precision uint) float64 {
-	ratio := math.Pow(10, float64(precision))
-	return math.Round(val*ratio) / ratio
+func (sp *startPage) syncingIcon(gtx values.C) values.D {
+	ic := components.NewIcon(sp.Theme.Icons.Cached)
+	ic.Color = sp.Theme.Color.Gray1
+	return ic.Layout(gtx, values.MarginPadding16)
 }
@@ -0,0 +1,218 @@
+package pages
+
+import (
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/ui/components"
+	"go-monzo-wallet/ui/handlers"
+	"go-monzo-wallet/ui/modal"
+	"go-monzo-wallet/ui/values"
+	"sync"
+	"time"
+)
+
+const (
+	SubscriptionsPageID = "subscriptions_page"
+
+	// DueDateFormat is the layout of the dates of recurring payments.
+	DueDateFormat = "Mon 2 January 2006"
+)
+
+// subscriptionsPage lists the recurring payments detected in the
+// transactions of all accounts.
+type subscriptionsPage struct {
+	*handlers.Load
+	*modal.GenericPageModal
+
+	unsubscribe func()
+
+	mtx      sync.Mutex
+	payments []internal.RecurringPayment
+
+	backButton      components.IconButton
+	scrollContainer *widget.List
+	shadowBox       *components.Shadow
+}
+
+// NewSubscriptionsPage returns the page that lists the recurring payments of
+// the wallet.
+func NewSubscriptionsPage(l *handlers.Load) handlers.Page {
+	pg := &subscriptionsPage{
+		Load:             l,
+		GenericPageModal: modal.NewGenericPageModal(SubscriptionsPageID),
+		backButton:       l.Theme.IconButton(l.Theme.Icons.NavigationArrowBack),
+		scrollContainer: &widget.List{
+			List: layout.List{Axis: layout.Vertical},
+		},
+		shadowBox: l.Theme.Shadow(),
+	}
+
+	pg.refresh()
+	return pg
+}
+
+// OnNavigatedTo is called when the page is about to be displayed and
+// may be used to initialize page features that are only relevant when
+// the page is displayed.
+// Part of the load.Page interface.
+func (pg *subscriptionsPage) OnNavigatedTo() {
+	var events <-chan internal.SyncEvent
+	events, pg.unsubscribe = pg.WL.Syncer().Subscribe()
+	go pg.listenForSyncEvents(events)
+}
+
+// listenForSyncEvents detects the recurring payments again when the
+// transactions change, until the subscription is cancelled.
+func (pg *subscriptionsPage) listenForSyncEvents(events <-chan internal.SyncEvent) {
+	for event := range events {
+		switch event.(type) {
+		case internal.SyncFinished, internal.TransactionReceived:
+			pg.refresh()
+			pg.ParentWindow().Reload()
+		}
+	}
+}
+
+func (pg *subscriptionsPage) refresh() {
+	payments := internal.DetectRecurring(pg.WL.AccountsList(), time.Now())
+
+	pg.mtx.Lock()
+	pg.payments = payments
+	pg.mtx.Unlock()
+}
+
+// HandleUserInteractions is called just before Layout() to determine
+// if any user interaction recently occurred on the page and may be
+// used to update the page's UI components shortly before they are
+// displayed.
+// Part of the load.Page interface.
+func (pg *subscriptionsPage) HandleUserInteractions() {
+	if pg.backButton.Button.Clicked() {
+		pg.ParentNavigator().CloseCurrentPage()
+	}
+}
+
+// OnNavigatedFrom is called when the page is about to be removed from
+// the displayed window. This method should ideally be used to disable
+// features that are irrelevant when the page is NOT displayed.
+// NOTE: The page may be re-displayed on the app's window, in which case
+// OnNavigatedTo() will be called again. This method should not destroy UI
+// components unless they'll be recreated in the OnNavigatedTo() method.
+// Part of the load.Page interface.
+func (pg *subscriptionsPage) OnNavigatedFrom() {
+	if pg.unsubscribe != nil {
+		pg.unsubscribe()
+	}
+}
+
+// Layout draws the page UI components into the provided C
+// to be eventually drawn on screen.
+// Part of the load.Page interface.
+func (pg *subscriptionsPage) Layout(gtx C) D {
+	pg.mtx.Lock()
+	payments := pg.payments
+	pg.mtx.Unlock()
+
+	content := []layout.Widget{
+		func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(pg.backButton.Layout),
+				layout.Rigid(func(gtx C) D {
+					return layout.Inset{Left: values.MarginPadding10}.Layout(gtx,
+						pg.Theme.H6(values.String(values.StrSubscriptions)).Layout)
+				}),
+			)
+		},
+	}
+	if len(payments) == 0 {
+		content = append(content, pg.Theme.Body1(values.String(values.StrNoSubscriptions)).Layout)
+	}
+	for _, payment := range payments {
+		payment := payment
+		content = append(content, func(gtx C) D {
+			return pg.paymentItem(gtx, payment)
+		})
+	}
+
+	gtx.Constraints.Min = gtx.Constraints.Max
+	return components.UniformPadding(gtx, func(gtx C) D {
+		return pg.Theme.List(pg.scrollContainer).Layout(gtx, len(content), func(gtx C, i int) D {
+			return layout.Inset{Bottom: values.MarginPadding16}.Layout(gtx, content[i])
+		})
+	})
+}
+
+// paymentItem lays out a recurring payment with when the next payment is
+// due, or that it is missing or ended.
+func (pg *subscriptionsPage) paymentItem(gtx C, payment internal.RecurringPayment) D {
+	amount := pg.FormatMoney(payment.Amount)
+	due := payment.NextDue.Local().Format(DueDateFormat)
+
+	status := pg.Theme.Caption(values.StringF(values.StrNextPaymentDue, amount, due))
+	status.Color = pg.Theme.Color.GrayText2
+	switch payment.Status {
+	case internal.RecurringMissing:
+		status.Text = values.StringF(values.StrPaymentMissing, amount, due)
+		status.Color = pg.Theme.Color.Danger
+	case internal.RecurringEnded:
+		last := payment.Transactions[len(payment.Transactions)-1]
+		status.Text = values.StringF(values.StrPaymentEnded, last.Created.Local().Format(DueDateFormat))
+	}
+
+	pg.shadowBox.SetShadowRadius(14)
+	return components.LinearLayout{
+		Width:       components.MatchParent,
+		Height:      components.WrapContent,
+		Orientation: layout.Vertical,
+		Padding:     layout.UniformInset(values.MarginPadding16),
+		Background:  pg.Theme.Color.Surface,
+		Shadow:      pg.shadowBox,
+		Border:      components.Border{Radius: components.NewRadius(14)},
+	}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, func(gtx C) D {
+					return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+						layout.Rigid(pg.Theme.Body1(payment.Merchant).Layout),
+						layout.Rigid(func(gtx C) D {
+							lbl := pg.Theme.Caption(CategoryName(payment.Category))
+							lbl.Color = pg.Theme.Color.GrayText2
+							return lbl.Layout(gtx)
+						}),
+					)
+				}),
+				layout.Rigid(pg.Theme.Body2(values.StringF(values.StrRecurringAmount,
+					amount, CadenceName(payment.Cadence))).Layout),
+			)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Top: values.MarginPadding8}.Layout(gtx, status.Layout)
+		}),
+		layout.Rigid(func(gtx C) D {
+			increase := payment.PriceIncrease()
+			if increase.Amount == 0 {
+				return D{}
+			}
+			lbl := pg.Theme.Caption(values.StringF(values.StrPriceIncreased,
+				pg.FormatMoney(increase), pg.FormatMoney(payment.PreviousAmount)))
+			lbl.Color = pg.Theme.Color.Orange
+			return layout.Inset{Top: values.MarginPadding4}.Layout(gtx, lbl.Layout)
+		}),
+	)
+}
+
+// CadenceName returns the translated name of a cadence, e.g. "monthly".
+func CadenceName(cadence internal.Cadence) string {
+	switch cadence {
+	case internal.CadenceWeekly:
+		return values.String(values.StrCadenceWeekly)
+	case internal.CadenceFortnightly:
+		return values.String(values.StrCadenceFortnightly)
+	case internal.CadenceQuarterly:
+		return values.String(values.StrCadenceQuarterly)
+	case internal.CadenceYearly:
+		return values.String(values.StrCadenceYearly)
+	}
+	return values.String(values.StrCadenceMonthly)
+}
@@ -1,23 +1,754 @@
 package pages
 
 import (
+	"fmt"
 	"gioui.org/layout"
-	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+	"go-monzo-wallet/internal"
+	"go-monzo-wallet/internal/export"
+	"go-monzo-wallet/ui/components"
+	"go-monzo-wallet/ui/handlers"
+	"go-monzo-wallet/ui/modal"
+	"go-monzo-wallet/ui/values"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	WalletPageID = "Wallet"
+
+	// dayHeaderFormat is the layout of the date above the transactions of
+	// each day, except for today and yesterday.
+	dayHeaderFormat = "Monday, 2 January 2006"
+
+	// transactionsPageSize is the number of transactions added to the list
+	// each time it is scrolled to the end.
+	transactionsPageSize = 50
+
+	// sinceDateFormat is the layout of the start date of the date filter
+	// when the page is opened from a date.
+	sinceDateFormat = "2 Jan 2006"
+
+	// transactionTimeFormat is the layout of the time of a transaction shown
+	// on its own.
+	transactionTimeFormat = "Monday, 2 January 2006 15:04"
 )
 
+// periodDays are the periods offered by the date filter, after "All time".
+var periodDays = []int{7, 30, 90}
+
+// amountBounds are the bounds in minor units of the amount filter ranges,
+// after "Any amount": under the first, between both and over the second.
+var amountBounds = [2]int64{1000, 10000}
+
 type (
 	C = layout.Context
 	D = layout.Dimensions
 )
 
-var (
-	NavDrawerWidth          = unit.Dp(160)
-	NavDrawerMinimizedWidth = unit.Dp(72)
-)
+// transactionRow is a row of the transaction list: either the header of a
+// day or a transaction of that day.
+type transactionRow struct {
+	day         string
+	transaction *internal.Transaction
+}
+
+type walletPage struct {
+	*handlers.Load
+	// GenericPageModal defines methods such as ID() and OnAttachedToNavigator()
+	// that helps this Page satisfy the app.Page interface. It also defines
+	// helper methods for accessing the PageNavigator that displayed this page
+	// and the root WindowNavigator.
+	*modal.GenericPageModal
+
+	unsubscribe func()
+
+	rowsLock sync.Mutex
+	account  *internal.Account
+	query    internal.TransactionQuery
+	pages    int // number of pages of transactionsPageSize shown
+	rows     []transactionRow
+	shown    int // number of transactions in rows
+	total    int // number of transactions matching query
+
+	backButton      components.IconButton
+	exportButton    components.IconButton
+	analyticsButton components.IconButton
+	mandatesButton  components.IconButton
+	exportFormat    *widget.Enum
+	transactionList *widget.List
+	shadowBox       *components.Shadow
+
+	searchEditor      components.Editor
+	sortDropDown      *components.DropDown
+	directionDropDown *components.DropDown
+	categoryDropDown  *components.DropDown
+	periodDropDown    *components.DropDown
+	amountDropDown    *components.DropDown
+	categories        []string // categories in categoryDropDown, after "All categories"
+	// since is the start date offered by the date filter after periodDays,
+	// or the zero time.
+	since time.Time
+	// openTransaction is the ID of a transaction to show once the page is
+	// displayed.
+	openTransaction string
+}
+
+// NewWalletPage returns the page that shows the details and transactions of
+// the account selected in l.WL.
+func NewWalletPage(l *handlers.Load) handlers.Page {
+	return newWalletPage(l, time.Time{}, "")
+}
+
+// newWalletPage returns the wallet page with the date filter set to the
+// transactions since the given date, unless it is zero, and showing the
+// transaction with the given ID, unless it is empty.
+func newWalletPage(l *handlers.Load, since time.Time, transactionID string) *walletPage {
+	wp := &walletPage{
+		Load:             l,
+		GenericPageModal: modal.NewGenericPageModal(WalletPageID),
+		backButton:       l.Theme.IconButton(l.Theme.Icons.NavigationArrowBack),
+		exportButton:     l.Theme.IconButton(l.Theme.Icons.FileDownload),
+		analyticsButton:  l.Theme.IconButton(l.Theme.Icons.Chart),
+		mandatesButton:   l.Theme.IconButton(l.Theme.Icons.Event),
+		exportFormat:     &widget.Enum{Value: string(export.CSV)},
+		transactionList: &widget.List{
+			List: layout.List{Axis: layout.Vertical},
+		},
+		shadowBox:       l.Theme.Shadow(),
+		pages:           1,
+		since:           since,
+		openTransaction: transactionID,
+	}
+
+	account := l.WL.SelectedAccount
+	wp.searchEditor = l.Theme.IconEditor(new(widget.Editor), values.String(values.StrSearchTransactions), l.Theme.Icons.SearchIcon, false)
+	wp.searchEditor.Editor.SingleLine = true
+	wp.initFilters(account)
+	wp.query = wp.filterQuery()
+	wp.showAccount(account)
+
+	return wp
+}
+
+// initFilters creates the filter dropdowns. The categories and amount ranges
+// offered depend on the account.
+func (wp *walletPage) initFilters(account *internal.Account) {
+	wp.sortDropDown = wp.Theme.DropDown([]components.DropDownItem{
+		{Text: values.String(values.StrNewest)},
+		{Text: values.String(values.StrOldest)},
+	}, 0, 0)
+
+	wp.directionDropDown = wp.Theme.DropDown([]components.DropDownItem{
+		{Text: values.String(values.StrAll)},
+		{Text: values.String(values.StrReceived)},
+		{Text: values.String(values.StrSent)},
+	}, 0, 1)
+
+	wp.categories = internal.Categories(account.Transactions)
+	categoryItems := []components.DropDownItem{{Text: values.String(values.StrAllCategories)}}
+	for _, category := range wp.categories {
+		categoryItems = append(categoryItems, components.DropDownItem{Text: CategoryName(category)})
+	}
+	wp.categoryDropDown = wp.Theme.DropDown(categoryItems, 0, 2)
+
+	periodItems := []components.DropDownItem{{Text: values.String(values.StrAllTime)}}
+	for _, days := range periodDays {
+		periodItems = append(periodItems, components.DropDownItem{Text: values.StringF(values.StrLastDays, days)})
+	}
+	if !wp.since.IsZero() {
+		periodItems = append(periodItems, components.DropDownItem{Text: wp.sinceName()})
+	}
+	wp.periodDropDown = wp.Theme.DropDown(periodItems, 0, 3)
+	if !wp.since.IsZero() {
+		wp.periodDropDown.SetSelectedIndex(len(periodItems) - 1)
+	}
+
+	currency := account.Balance.Currency
+	low := internal.NewMoney(amountBounds[0], currency).Format(wp.Printer)
+	high := internal.NewMoney(amountBounds[1], currency).Format(wp.Printer)
+	wp.amountDropDown = wp.Theme.DropDown([]components.DropDownItem{
+		{Text: values.String(values.StrAnyAmount)},
+		{Text: values.StringF(values.StrUnderAmount, low)},
+		{Text: values.StringF(values.StrAmountRange, low, high)},
+		{Text: values.StringF(values.StrOverAmount, high)},
+	}, 0, 4)
+}
+
+// filterQuery returns the query selected by the search field and filter
+// dropdowns.
+func (wp *walletPage) filterQuery() internal.TransactionQuery {
+	query := internal.TransactionQuery{
+		Text: wp.searchEditor.Editor.Text(),
+	}
+
+	if wp.sortDropDown.SelectedIndex() == 1 {
+		query.Sort = internal.OldestFirst
+	}
+
+	switch wp.directionDropDown.SelectedIndex() {
+	case 1:
+		query.Direction = internal.Incoming
+	case 2:
+		query.Direction = internal.Outgoing
+	}
+
+	if i := wp.categoryDropDown.SelectedIndex(); i > 0 {
+		query.Category = wp.categories[i-1]
+	}
+
+	query.From, _ = wp.period()
+
+	low, high := amountBounds[0], amountBounds[1]
+	switch wp.amountDropDown.SelectedIndex() {
+	case 1:
+		max := low - 1
+		query.MaxAmount = &max
+	case 2:
+		query.MinAmount, query.MaxAmount = &low, &high
+	case 3:
+		min := high + 1
+		query.MinAmount = &min
+	}
+
+	return query
+}
+
+// period returns the start of the period selected by the date filter, the
+// zero time for all time, and its name.
+func (wp *walletPage) period() (time.Time, string) {
+	i := wp.periodDropDown.SelectedIndex()
+	switch {
+	case i > 0 && i <= len(periodDays):
+		return time.Now().AddDate(0, 0, -periodDays[i-1]), values.StringF(values.StrLastDays, periodDays[i-1])
+	case i > len(periodDays):
+		return wp.since, wp.sinceName()
+	}
+	return time.Time{}, values.String(values.StrAllTime)
+}
+
+func (wp *walletPage) sinceName() string {
+	return values.StringF(values.StrSinceDate, wp.since.Local().Format(sinceDateFormat))
+}
+
+// OnNavigatedTo is called when the page is about to be displayed and
+// may be used to initialize page features that are only relevant when
+// the page is displayed.
+// Part of the load.Page interface.
+func (wp *walletPage) OnNavigatedTo() {
+	var events <-chan internal.SyncEvent
+	events, wp.unsubscribe = wp.WL.Syncer().Subscribe()
+	go wp.listenForSyncEvents(events)
+
+	if id := wp.openTransaction; id != "" {
+		wp.openTransaction = ""
+		wp.showTransaction(id)
+	}
+}
+
+// showTransaction shows the details of the transaction with the given ID in
+// a modal.
+func (wp *walletPage) showTransaction(id string) {
+	wp.rowsLock.Lock()
+	account := wp.account
+	wp.rowsLock.Unlock()
+
+	var transaction *internal.Transaction
+	for _, t := range account.Transactions {
+		if t.ID == id {
+			transaction = t
+			break
+		}
+	}
+	if transaction == nil {
+		wp.Toast.NotifyError(values.StringF(values.StrTransactionNotFound, id))
+		return
+	}
+
+	body := []string{
+		wp.FormatMoney(transaction.Amount),
+		transaction.Created.Local().Format(transactionTimeFormat),
+	}
+	if details := transactionDetails(transaction); details != "" {
+		body = append(body, details)
+	}
+	wp.ParentWindow().ShowModal(modal.NewInfoModal(wp.Load).
+		Title(transactionTitle(account, transaction)).
+		Body(strings.Join(body, "\n")).
+		PositiveButton(values.String(values.StrGotIt), func(isChecked bool) bool { return true }))
+}
+
+// listenForSyncEvents refreshes the account after every sync and received
+// transaction until the subscription is cancelled.
+func (wp *walletPage) listenForSyncEvents(events <-chan internal.SyncEvent) {
+	for event := range events {
+		switch event.(type) {
+		case internal.SyncFinished, internal.TransactionReceived:
+		default:
+			continue
+		}
+
+		wp.rowsLock.Lock()
+		id := wp.account.ID
+		wp.rowsLock.Unlock()
+
+		for _, account := range wp.WL.AccountsList() {
+			if account.ID == id {
+				wp.showAccount(account)
+				wp.ParentWindow().Reload()
+				break
+			}
+		}
+	}
+}
+
+// showAccount displays account and the transactions that match the query.
+func (wp *walletPage) showAccount(account *internal.Account) {
+	wp.rowsLock.Lock()
+	wp.account = account
+	wp.rowsLock.Unlock()
+
+	wp.refresh()
+}
+
+// refresh runs the query against the account and groups the first pages of
+// matching transactions by day.
+func (wp *walletPage) refresh() {
+	wp.rowsLock.Lock()
+	defer wp.rowsLock.Unlock()
+
+	query := wp.query
+	query.Limit = wp.pages * transactionsPageSize
+	result := wp.account.Query(query)
+
+	var rows []transactionRow
+	lastDay := ""
+	for _, transaction := range result.Transactions {
+		if day := transactionDay(transaction); day != lastDay {
+			rows = append(rows, transactionRow{day: day})
+			lastDay = day
+		}
+		rows = append(rows, transactionRow{transaction: transaction})
+	}
+
+	wp.rows = rows
+	wp.shown = len(result.Transactions)
+	wp.total = result.Total
+}
+
+// transactionDay returns the header of the day the transaction was created
+// on, in local time.
+func transactionDay(transaction *internal.Transaction) string {
+	created := transaction.Created.Local()
+	now := time.Now()
+	switch {
+	case sameDay(created, now):
+		return values.String(values.StrToday)
+	case sameDay(created, now.AddDate(0, 0, -1)):
+		return values.String(values.StrYesterday)
+	default:
+		return created.Format(dayHeaderFormat)
+	}
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// HandleUserInteractions is called just before Layout() to determine
+// if any user interaction recently occurred on the page and may be
+// used to update the page's UI components shortly before they are
+// displayed.
+// Part of the load.Page interface.
+func (wp *walletPage) HandleUserInteractions() {
+	if wp.backButton.Button.Clicked() {
+		wp.ParentNavigator().CloseCurrentPage()
+	}
+
+	if wp.exportButton.Button.Clicked() {
+		wp.showExportModal()
+	}
+
+	if wp.analyticsButton.Button.Clicked() {
+		wp.ParentNavigator().Display(NewAnalyticsPage(wp.Load))
+	}
+
+	if wp.mandatesButton.Button.Clicked() {
+		wp.ParentNavigator().Display(NewMandatesPage(wp.Load))
+	}
+
+	_, changed := components.HandleEditorEvents(wp.searchEditor.Editor)
+	for _, dropDown := range []*components.DropDown{wp.sortDropDown, wp.directionDropDown,
+		wp.categoryDropDown, wp.periodDropDown, wp.amountDropDown} {
+		if dropDown.Changed() {
+			changed = true
+		}
+	}
+
+	if changed {
+		wp.rowsLock.Lock()
+		wp.query = wp.filterQuery()
+		wp.pages = 1
+		wp.rowsLock.Unlock()
+
+		wp.refresh()
+		wp.transactionList.Position.First, wp.transactionList.Position.Offset = 0, 0
+		return
+	}
+
+	// Show the next page once the end of the list is visible.
+	wp.rowsLock.Lock()
+	more := wp.shown < wp.total
+	wp.rowsLock.Unlock()
+	if position := wp.transactionList.Position; more && position.Count > 0 && !position.BeforeEnd {
+		wp.rowsLock.Lock()
+		wp.pages++
+		wp.rowsLock.Unlock()
+
+		wp.refresh()
+	}
+}
+
+// showExportModal asks for the format to export the transactions of the
+// period selected by the date filter in, and saves them to exportDir.
+func (wp *walletPage) showExportModal() {
+	wp.rowsLock.Lock()
+	account := wp.account
+	wp.rowsLock.Unlock()
+
+	opts := export.Options{}
+	var period string
+	opts.From, period = wp.period()
+
+	exportModal := modal.NewInfoModal(wp.Load).
+		Title(values.String(values.StrExportTransactions)).
+		Body(values.StringF(values.StrExportPeriod, period)).
+		UseCustomWidget(wp.exportFormats).
+		NegativeButton(values.String(values.StrCancel), func() {})
+
+	exportModal.PositiveButton(values.String(values.StrExport), func(isChecked bool) bool {
+		path, err := exportTransactions(account, export.Format(wp.exportFormat.Value), opts)
+		if err != nil {
+			wp.Toast.NotifyError(values.StringF(values.StrExportFailed, err))
+			return false
+		}
+
+		wp.Toast.Notify(values.StringF(values.StrExported, path))
+		wp.ParentWindow().DismissModal(exportModal.ID())
+		return false
+	})
+	wp.ParentWindow().ShowModal(exportModal)
+}
+
+// exportFormats lays out a radio button for every export format.
+func (wp *walletPage) exportFormats(gtx C) D {
+	formats := make([]layout.FlexChild, 0, len(export.Formats))
+	for _, format := range export.Formats {
+		format := format
+		formats = append(formats, layout.Rigid(func(gtx C) D {
+			radio := material.RadioButton(wp.Theme.Base, wp.exportFormat, string(format), strings.ToUpper(string(format)))
+			radio.Color = wp.Theme.Color.Text
+			radio.IconColor = wp.Theme.Color.Primary
+			return layout.Inset{Right: values.MarginPadding16}.Layout(gtx, radio.Layout)
+		}))
+	}
+	return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, formats...)
+}
+
+// exportTransactions writes a statement of the account to a new file in
+// exportDir and returns its path.
+func exportTransactions(account *internal.Account, format export.Format, opts export.Options) (string, error) {
+	dir, err := exportDir()
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, export.FileName(account, format, opts))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := export.Write(f, format, account, opts); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// exportDir returns the Downloads directory of the user if there is one, or
+// their home directory.
+func exportDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(filepath.Join(home, "Downloads")); err == nil && info.IsDir() {
+		return filepath.Join(home, "Downloads"), nil
+	}
+	return home, nil
+}
+
+// checkPassphrase returns internal.ErrWrongPassphrase unless passphrase
+// unlocks the token store.
+func checkPassphrase(passphrase string) error {
+	dir, err := internal.AppDataDir()
+	if err != nil {
+		return err
+	}
+	_, err = internal.OpenTokenStore(filepath.Join(dir, internal.TokenFileName), passphrase)
+	return err
+}
+
+// OnNavigatedFrom is called when the page is about to be removed from
+// the displayed window. This method should ideally be used to disable
+// features that are irrelevant when the page is NOT displayed.
+// NOTE: The page may be re-displayed on the app's window, in which case
+// OnNavigatedTo() will be called again. This method should not destroy UI
+// components unless they'll be recreated in the OnNavigatedTo() method.
+// Part of the load.Page interface.
+func (wp *walletPage) OnNavigatedFrom() {
+	if wp.unsubscribe != nil {
+		wp.unsubscribe()
+	}
+}
+
+// Layout draws the page UI components into the provided C
+// to be eventually drawn on screen.
+// Part of the load.Page interface.
+func (wp *walletPage) Layout(gtx C) D {
+	wp.rowsLock.Lock()
+	account, rows := wp.account, wp.rows
+	wp.rowsLock.Unlock()
+
+	gtx.Constraints.Min = gtx.Constraints.Max // use maximum height & width
+	return components.UniformPadding(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx C) D {
+				return wp.header(gtx, account)
+			}),
+			layout.Rigid(func(gtx C) D {
+				return layout.Inset{Top: values.MarginPadding16}.Layout(gtx, func(gtx C) D {
+					return wp.accountSummary(gtx, account)
+				})
+			}),
+			layout.Flexed(1, func(gtx C) D {
+				return layout.Inset{Top: values.MarginPadding16}.Layout(gtx, func(gtx C) D {
+					return wp.transactionSection(gtx, account, rows)
+				})
+			}),
+		)
+	})
+}
+
+func (wp *walletPage) header(gtx C, account *internal.Account) D {
+	return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+		layout.Rigid(wp.backButton.Layout),
+		layout.Flexed(1, func(gtx C) D {
+			return layout.Inset{Left: values.MarginPadding10}.Layout(gtx,
+				wp.Theme.H6(account.AccountNumber).Layout)
+		}),
+		layout.Rigid(wp.mandatesButton.Layout),
+		layout.Rigid(wp.analyticsButton.Layout),
+		layout.Rigid(wp.exportButton.Layout),
+	)
+}
+
+func (wp *walletPage) accountSummary(gtx C, account *internal.Account) D {
+	wp.shadowBox.SetShadowRadius(14)
+	return components.LinearLayout{
+		Width:       components.MatchParent,
+		Height:      components.WrapContent,
+		Orientation: layout.Vertical,
+		Padding:     layout.UniformInset(values.MarginPadding16),
+		Background:  wp.Theme.Color.Surface,
+		Shadow:      wp.shadowBox,
+		Border:      components.Border{Radius: components.NewRadius(14)},
+	}.Layout(gtx,
+		layout.Rigid(func(gtx C) D {
+			return wp.summaryRow(gtx, values.String(values.StrAcctNum), account.AccountNumber)
+		}),
+		layout.Rigid(func(gtx C) D {
+			return wp.summaryRow(gtx, values.String(values.StrSortCode), formatSortCode(account.SortCode))
+		}),
+		layout.Rigid(func(gtx C) D {
+			return wp.summaryRow(gtx, values.String(values.StrBalance), wp.FormatMoney(account.Balance))
+		}),
+		layout.Rigid(func(gtx C) D {
+			return wp.summaryRow(gtx, values.String(values.StrSpendToday), wp.FormatMoney(account.SpendToday))
+		}),
+	)
+}
+
+func (wp *walletPage) summaryRow(gtx C, label, value string) D {
+	return layout.Inset{Bottom: values.MarginPadding5}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+			layout.Flexed(1, func(gtx C) D {
+				lbl := wp.Theme.Body2(label)
+				lbl.Color = wp.Theme.Color.GrayText2
+				return lbl.Layout(gtx)
+			}),
+			layout.Rigid(wp.Theme.Body1(value).Layout),
+		)
+	})
+}
+
+func (wp *walletPage) transactionSection(gtx C, account *internal.Account, rows []transactionRow) D {
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(wp.Theme.Text(values.TextSize20, values.String(values.StrTransactions)).Layout),
+		layout.Rigid(func(gtx C) D {
+			return layout.Inset{Top: values.MarginPadding8}.Layout(gtx, wp.searchEditor.Layout)
+		}),
+		layout.Flexed(1, func(gtx C) D {
+			return layout.Inset{Top: values.MarginPadding8}.Layout(gtx, func(gtx C) D {
+				return wp.filteredTransactions(gtx, account, rows)
+			})
+		}),
+	)
+}
+
+// filteredTransactions lays out the filter dropdowns above the transaction
+// list. The dropdowns are stacked on top of the list so that their menus
+// open over it.
+func (wp *walletPage) filteredTransactions(gtx C, account *internal.Account, rows []transactionRow) D {
+	dropDowns := []*components.DropDown{wp.sortDropDown, wp.directionDropDown,
+		wp.categoryDropDown, wp.periodDropDown, wp.amountDropDown}
+
+	children := []layout.StackChild{
+		layout.Expanded(func(gtx C) D {
+			return layout.Inset{Top: values.MarginPadding60}.Layout(gtx, func(gtx C) D {
+				if len(rows) == 0 {
+					message := values.StrNoTransactions
+					if len(account.Transactions) > 0 {
+						message = values.StrNoMatchingTransactions
+					}
+					return wp.Theme.Body1(values.String(message)).Layout(gtx)
+				}
+
+				// Only the rows that are visible are laid out, so long
+				// histories do not slow down drawing.
+				return wp.Theme.List(wp.transactionList).Layout(gtx, len(rows), func(gtx C, i int) D {
+					if rows[i].transaction == nil {
+						return wp.dayHeader(gtx, rows[i].day)
+					}
+					return wp.transactionItem(gtx, account, rows[i].transaction)
+				})
+			})
+		}),
+	}
+
+	pos := 0
+	for _, dropDown := range dropDowns {
+		dropDown, dropPos := dropDown, pos
+		children = append(children, layout.Stacked(func(gtx C) D {
+			return dropDown.Layout(gtx, dropPos, false)
+		}))
+		pos += dropDown.Width
+	}
+
+	return layout.Stack{Alignment: layout.NW}.Layout(gtx, children...)
+}
+
+func (wp *walletPage) dayHeader(gtx C, day string) D {
+	lbl := wp.Theme.Caption(day)
+	lbl.Color = wp.Theme.Color.GrayText3
+	return layout.Inset{Top: values.MarginPadding16, Bottom: values.MarginPadding5}.Layout(gtx, lbl.Layout)
+}
+
+func (wp *walletPage) transactionItem(gtx C, account *internal.Account, transaction *internal.Transaction) D {
+	return layout.Inset{Top: values.MarginPadding5, Bottom: values.MarginPadding5}.Layout(gtx, func(gtx C) D {
+		return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+			layout.Flexed(1, func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(wp.Theme.Body1(transactionTitle(account, transaction)).Layout),
+					layout.Rigid(func(gtx C) D {
+						details := wp.Theme.Caption(transactionDetails(transaction))
+						details.Color = wp.Theme.Color.GrayText3
+						if transaction.Status == internal.TransactionDeclined {
+							details.Color = wp.Theme.Color.Danger
+						}
+						return details.Layout(gtx)
+					}),
+				)
+			}),
+			layout.Rigid(func(gtx C) D {
+				return layout.Flex{Axis: layout.Vertical, Alignment: layout.End}.Layout(gtx,
+					layout.Rigid(func(gtx C) D {
+						amount := wp.Theme.Body1(wp.FormatMoney(transaction.Amount))
+						switch {
+						case transaction.Status == internal.TransactionDeclined:
+							amount.Color = wp.Theme.Color.GrayText3
+						case transaction.Amount.Sign() > 0:
+							amount.Color = wp.Theme.Color.GreenText
+						}
+						return amount.Layout(gtx)
+					}),
+					layout.Rigid(func(gtx C) D {
+						if transaction.LocalAmount.Currency == transaction.Amount.Currency {
+							return D{}
+						}
+						local := wp.Theme.Caption(wp.FormatMoney(transaction.LocalAmount))
+						local.Color = wp.Theme.Color.GrayText3
+						return local.Layout(gtx)
+					}),
+				)
+			}),
+		)
+	})
+}
+
+// transactionTitle returns the title of the transaction. Pot transfers are
+// described by the ID of the pot, so its name is shown instead.
+func transactionTitle(account *internal.Account, transaction *internal.Transaction) string {
+	for _, pot := range account.Pots {
+		if pot.ID == transaction.Description {
+			return pot.Name
+		}
+	}
+	return transaction.Title()
+}
+
+// transactionDetails returns the line shown below the transaction title: its
+// category, status and notes.
+func transactionDetails(transaction *internal.Transaction) string {
+	var parts []string
+	switch transaction.Status {
+	case internal.TransactionDeclined:
+		parts = append(parts, values.String(values.StrDeclined))
+	case internal.TransactionPending:
+		parts = append(parts, values.String(values.StrPending))
+	}
+	if transaction.Category != "" {
+		parts = append(parts, CategoryName(transaction.Category))
+	}
+	if transaction.Notes != "" {
+		parts = append(parts, transaction.Notes)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// CategoryName turns a category as returned by the API, e.g. "eating_out",
+// into a readable name, e.g. "Eating out".
+func CategoryName(category string) string {
+	name := strings.ReplaceAll(category, "_", " ")
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// formatSortCode formats a sort code as three pairs of digits, e.g. 04-00-04.
+func formatSortCode(sortCode string) string {
+	if len(sortCode) != 6 {
+		return sortCode
+	}
+	return fmt.Sprintf("%s-%s-%s", sortCode[:2], sortCode[2:4], sortCode[4:])
+}
 
 //
 //type MainPage struct {
@@ -11,7 +11,8 @@ type Icons struct {
 	ContentAdd, NavigationCheck, NavigationMore, ActionCheckCircle, ActionInfo, NavigationArrowBack,
 	NavigationArrowForward, ActionCheck, ChevronRight, NavigationCancel, NavMoreIcon,
 	ImageBrightness1, ContentClear, DropDownIcon, Cached, ContentRemove, ConcealIcon, RevealIcon,
-	SearchIcon, PlayIcon *widget.Icon
+	SearchIcon, PlayIcon, FileDownload, Chart, Event, Settings, AccountBalance, Receipt, Savings,
+	NavigationMenu *widget.Icon
 
 	MonzoLogo, SuccessIcon, FailedIcon, RedAlert image.Image
 }
@@ -19,6 +20,19 @@ type Icons struct {
 func (i *Icons) StandardMaterialIcons() *Icons {
 	icon := MustIcon(widget.NewIcon(icons.ActionInfo))
 	i.ActionInfo = icon
+	i.NavigationArrowBack = MustIcon(widget.NewIcon(icons.NavigationArrowBack))
+	i.SearchIcon = MustIcon(widget.NewIcon(icons.ActionSearch))
+	i.FileDownload = MustIcon(widget.NewIcon(icons.FileFileDownload))
+	i.Chart = MustIcon(widget.NewIcon(icons.EditorInsertChart))
+	i.ContentRemove = MustIcon(widget.NewIcon(icons.ContentRemoveCircleOutline))
+	i.Event = MustIcon(widget.NewIcon(icons.ActionEvent))
+	i.Settings = MustIcon(widget.NewIcon(icons.ActionSettings))
+	i.AccountBalance = MustIcon(widget.NewIcon(icons.ActionAccountBalance))
+	i.Receipt = MustIcon(widget.NewIcon(icons.ActionReceipt))
+	i.Savings = MustIcon(widget.NewIcon(icons.EditorMonetizationOn))
+	i.NavigationMenu = MustIcon(widget.NewIcon(icons.NavigationMenu))
+	i.ConcealIcon = MustIcon(widget.NewIcon(icons.ActionVisibilityOff))
+	i.RevealIcon = MustIcon(widget.NewIcon(icons.ActionVisibility))
 
 	return i
 }
@@ -30,10 +44,11 @@ func (i *Icons) DefaultIcons() *Icons {
 
 	i.MonzoLogo = decredIcons["monzo_logo"]
 	i.SuccessIcon = decredIcons["success_check"]
-	i.FailedIcon = decredIcons["crossmark_red"]
+	i.FailedIcon = decredIcons["red_alert"]
 	i.RedAlert = decredIcons["red_alert"]
 
 	i.ImageBrightness1 = MustIcon(widget.NewIcon(icons.ImageBrightness1))
+	i.Cached = MustIcon(widget.NewIcon(icons.ActionCached))
 	return i
 }
 
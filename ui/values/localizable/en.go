@@ -5,7 +5,7 @@ const ENGLISH = "en"
 // one string per line, no multiline
 // semicolon is not compulsory
 const EN = `
-"appName" = "godcr";
+"appName" = "Monzo Wallet";
 "appTitle" = "godcr (%s)";
 "recentTransactions" = "Recent Transactions";
 "recentProposals" = "Recent Proposals";
@@ -44,7 +44,7 @@ const EN = `
 "walletNotSynced" = "Not Synced";
 "cancel" = "Cancel";
 "resumeAccountDiscoveryTitle" = "Unlock to resume restoration";
-unlockWithPassword = "Unlock with password"
+"unlockWithPassword" = "Unlock with password";
 "unlock" = "Unlock";
 "syncingProgress" = "Syncing progress";
 "syncingProgressStat" = "%s behind";
@@ -470,4 +470,160 @@ unlockWithPassword = "Unlock with password"
 "whatToCallWallet" = "What would you like to call your wallet?"
 "existingWalletName" = "What is your wallet existing wallet name?"
 "accessToken" = "Insert access token"
+"loginTimedOut" = "Login timed out, please try again"
+"loginCancelled" = "Login cancelled"
+"loginStateMismatch" = "Login failed: the response did not match the request"
+"loginFailed" = "Login failed: %s"
+"syncNow" = "Sync now"
+"syncFailed" = "Sync failed"
+"syncingAccounts" = "Syncing %d/%d"
+"today" = "Today"
+"sortCode" = "Sort Code"
+"spendToday" = "Spent today"
+"declined" = "Declined"
+"searchTransactions" = "Search transactions"
+"allCategories" = "All categories"
+"allTime" = "All time"
+"lastDays" = "Last %d days"
+"anyAmount" = "Any amount"
+"underAmount" = "Under %s"
+"amountRange" = "%s to %s"
+"overAmount" = "Over %s"
+"noMatchingTransactions" = "No matching transactions"
+"pots" = "Pots"
+"deposit" = "Deposit"
+"withdraw" = "Withdraw"
+"potGoal" = "%s of %s"
+"noPots" = "No pots"
+"invalidAmount" = "Enter a valid amount"
+"confirmDeposit" = "Move %s from your account into %s?"
+"confirmWithdraw" = "Move %s from %s into your account? Enter your startup password to confirm."
+"depositedToPot" = "Moved %s into %s"
+"withdrawnFromPot" = "Moved %s out of %s"
+"potTransferFailed" = "Could not move money: %v"
+"export" = "Export"
+"exportTransactions" = "Export transactions"
+"exportPeriod" = "Period: %s"
+"exported" = "Saved %s"
+"exportFailed" = "Could not export: %v"
+"accountGroup" = "%s · %s"
+"accountTypeRetail" = "Current accounts"
+"accountTypeJoint" = "Joint accounts"
+"accountTypeBusiness" = "Business accounts"
+"profiles" = "Profiles"
+"addProfile" = "Add profile"
+"profileName" = "Profile name"
+"addProfileInfo" = "Log in with another Monzo account, e.g. a partner's or a company's. Choose the types of accounts to show."
+"missingProfileName" = "Enter a name for the profile"
+"noAccountTypes" = "Select at least one account type"
+"logInProfile" = "Log in to %s"
+"logInProfileInfo" = "Enter the startup password, then log in to the Monzo account of %s in the browser."
+"logIn" = "Log in"
+"profileAdded" = "Added %s"
+"removeProfile" = "Remove profile"
+"confirmRemoveProfile" = "Remove %s and its accounts from this device? You can add it again later."
+"profileRemoved" = "Removed %s"
+"profileNotLoggedIn" = "%s is not logged in, remove it and add it again"
+"invalidConfig" = "Invalid configuration"
+"invalidConfigInfo" = "The configuration in %s cannot be used: %v"
+"fixConfig" = "Fix"
+"setupTitle" = "Set up Monzo Wallet"
+"setupIntro" = "Monzo Wallet talks to your account through an OAuth client that you register yourself. It only takes a minute:"
+"setupStepPortal" = "1. Sign in at https://developers.monzo.com with your Monzo email address."
+"setupStepClient" = "2. Open Clients and create a New OAuth Client. Set the Redirect URL below and make it Confidential."
+"setupStepCopy" = "3. Copy the Client ID and Client secret of the new client here."
+"clientID" = "Client ID"
+"clientSecret" = "Client secret"
+"redirectURL" = "Redirect URL"
+"saveConfig" = "Save and continue"
+"configSaved" = "Saved the configuration to %s"
+"transactionReceived" = "%s: %s"
+"transactionDeclined" = "Declined %s: %s"
+"webhooksFailed" = "Real-time notifications are off: %s"
+"analytics" = "Analytics"
+"thisMonth" = "This month"
+"lastMonths" = "Last %d months"
+"income" = "Income"
+"outgoings" = "Outgoings"
+"net" = "Net"
+"incomeVsOutgoings" = "Income vs outgoings"
+"spendingByCategory" = "Spending by category"
+"topMerchants" = "Top merchants"
+"balanceOverTime" = "Balance over time"
+"otherCategories" = "Other"
+"noSpending" = "No spending in this period"
+"uncategorized" = "Uncategorized"
+"budgets" = "Budgets"
+"addBudget" = "Add budget"
+"budgetCategory" = "Category, e.g. groceries"
+"budgetLimit" = "Monthly limit"
+"budgetSpent" = "%s of %s"
+"budgetProjected" = "Projected %s, %s over budget"
+"budgetWarningAt" = "You are warned at %d%% of a budget."
+"noBudgets" = "No budgets yet. Add one to track the spending of a category each month."
+"knownCategories" = "Categories: %s"
+"budgetWarningAlert" = "%s: %s of your %s budget spent this month"
+"budgetExceededAlert" = "%s: over budget, %s of %s spent this month"
+"budgetSaved" = "Budget for %s saved"
+"budgetRemoved" = "Budget for %s removed"
+"missingCategory" = "Enter a category"
+"invalidLimit" = "Enter a positive amount, e.g. 250.00"
+"removeBudgetConfirm" = "Remove the budget for %s?"
+"subscriptions" = "Subscriptions"
+"cadenceWeekly" = "weekly"
+"cadenceFortnightly" = "fortnightly"
+"cadenceMonthly" = "monthly"
+"cadenceQuarterly" = "quarterly"
+"cadenceYearly" = "yearly"
+"recurringAmount" = "%s %s"
+"nextPaymentDue" = "Next payment of %s due %s"
+"paymentMissing" = "Missing, %s was due %s"
+"paymentEnded" = "Ended, last paid %s"
+"priceIncreased" = "Up %s from %s"
+"noSubscriptions" = "No recurring payments yet. Payments show up here once they have been taken regularly a few times."
+"paymentMissingAlert" = "%s: the payment of %s due %s has not been taken"
+"priceIncreasedAlert" = "%s: price went up from %s to %s"
+"mandates" = "Direct debits and standing orders"
+"directDebits" = "Direct debits"
+"standingOrders" = "Standing orders"
+"noMandates" = "This account has no direct debits or standing orders."
+"reference" = "Reference: %s"
+"lastPaid" = "Last paid %s on %s"
+"nextPayment" = "Next payment of %s on %s"
+"nextCollection" = "Next collection on %s"
+"cancelMandate" = "Cancel mandate"
+"confirmCancelDirectDebit" = "Cancel the direct debit to %s? They will not be able to collect any more payments from this account."
+"confirmCancelStandingOrder" = "Cancel the standing order to %s? No more payments will be made."
+"directDebitCancelled" = "Direct debit to %s cancelled"
+"standingOrderCancelled" = "Standing order to %s cancelled"
+"cancelMandateFailed" = "Could not cancel: %v"
+"budgetCurrency" = "Default budget currency"
+"syncEvery" = "Sync every"
+"autoLock" = "Lock when idle for"
+"privacyMode" = "Privacy mode: hide all amounts"
+"never" = "Never"
+"minutesShort" = "%d min"
+"hoursShort" = "%d h"
+"saveSettingsFailed" = "Could not save settings: %v"
+"accounts" = "Accounts"
+"sinceDate" = "Since %s"
+"openLinkFailed" = "Could not open %s: %v"
+"transactionNotFound" = "Transaction %s was not found"
+"walletLocked" = "Wallet locked"
+"walletLockedInfo" = "Enter your startup password or unlock PIN to continue."
+"passwordOrPIN" = "Password or PIN"
+"unlockThrottled" = "Too many failed attempts, try again in %s"
+"setUnlockPIN" = "Set unlock PIN"
+"setUnlockPINInfo" = "The PIN unlocks the wallet when it locks while idle, in addition to the startup password. It needs at least %d digits."
+"removeUnlockPIN" = "Remove unlock PIN"
+"invalidUnlockPIN" = "The PIN must be at least %d digits"
+"unlockPINSaved" = "Unlock PIN saved"
+"unlockPINRemoved" = "Unlock PIN removed"
+"pin" = "PIN"
+"privacyModeOn" = "Privacy mode on, amounts are hidden"
+"privacyModeOff" = "Privacy mode off"
+"loginExpired" = "The login of %s has expired. Enter the startup password, then log in to it again in the browser."
+"loggedInAgain" = "%s is logged in again"
+"potLockedError" = "%s is locked, money cannot be withdrawn until it unlocks"
+"profileSyncFailed" = "Not synced: %v"
 `
@@ -472,6 +472,162 @@ const (
 	StrSelectWalletType            = "selectWalletType"
 	StrWhatToCallWallet            = "whatToCallWallet"
 	StrExistingWalletName          = "existingWalletName"
+	StrLoginTimedOut               = "loginTimedOut"
+	StrLoginCancelled              = "loginCancelled"
+	StrLoginStateMismatch          = "loginStateMismatch"
+	StrLoginFailed                 = "loginFailed"
+	StrSyncNow                     = "syncNow"
+	StrSyncFailed                  = "syncFailed"
+	StrSyncingAccounts             = "syncingAccounts"
+	StrToday                       = "today"
+	StrSortCode                    = "sortCode"
+	StrSpendToday                  = "spendToday"
+	StrDeclined                    = "declined"
+	StrSearchTransactions          = "searchTransactions"
+	StrAllCategories               = "allCategories"
+	StrAllTime                     = "allTime"
+	StrLastDays                    = "lastDays"
+	StrAnyAmount                   = "anyAmount"
+	StrUnderAmount                 = "underAmount"
+	StrAmountRange                 = "amountRange"
+	StrOverAmount                  = "overAmount"
+	StrNoMatchingTransactions      = "noMatchingTransactions"
+	StrPots                        = "pots"
+	StrDeposit                     = "deposit"
+	StrWithdraw                    = "withdraw"
+	StrPotGoal                     = "potGoal"
+	StrNoPots                      = "noPots"
+	StrInvalidAmount               = "invalidAmount"
+	StrConfirmDeposit              = "confirmDeposit"
+	StrConfirmWithdraw             = "confirmWithdraw"
+	StrDepositedToPot              = "depositedToPot"
+	StrWithdrawnFromPot            = "withdrawnFromPot"
+	StrPotTransferFailed           = "potTransferFailed"
+	StrExport                      = "export"
+	StrExportTransactions          = "exportTransactions"
+	StrExportPeriod                = "exportPeriod"
+	StrExported                    = "exported"
+	StrExportFailed                = "exportFailed"
+	StrAccountGroup                = "accountGroup"
+	StrAccountTypeRetail           = "accountTypeRetail"
+	StrAccountTypeJoint            = "accountTypeJoint"
+	StrAccountTypeBusiness         = "accountTypeBusiness"
+	StrProfiles                    = "profiles"
+	StrAddProfile                  = "addProfile"
+	StrProfileName                 = "profileName"
+	StrAddProfileInfo              = "addProfileInfo"
+	StrMissingProfileName          = "missingProfileName"
+	StrNoAccountTypes              = "noAccountTypes"
+	StrLogInProfile                = "logInProfile"
+	StrLogInProfileInfo            = "logInProfileInfo"
+	StrLogIn                       = "logIn"
+	StrProfileAdded                = "profileAdded"
+	StrRemoveProfile               = "removeProfile"
+	StrConfirmRemoveProfile        = "confirmRemoveProfile"
+	StrProfileRemoved              = "profileRemoved"
+	StrProfileNotLoggedIn          = "profileNotLoggedIn"
+	StrInvalidConfig               = "invalidConfig"
+	StrInvalidConfigInfo           = "invalidConfigInfo"
+	StrFixConfig                   = "fixConfig"
+	StrSetupTitle                  = "setupTitle"
+	StrSetupIntro                  = "setupIntro"
+	StrSetupStepPortal             = "setupStepPortal"
+	StrSetupStepClient             = "setupStepClient"
+	StrSetupStepCopy               = "setupStepCopy"
+	StrClientID                    = "clientID"
+	StrClientSecret                = "clientSecret"
+	StrRedirectURL                 = "redirectURL"
+	StrSaveConfig                  = "saveConfig"
+	StrConfigSaved                 = "configSaved"
+	StrTransactionReceived         = "transactionReceived"
+	StrTransactionDeclined         = "transactionDeclined"
+	StrWebhooksFailed              = "webhooksFailed"
+	StrAnalytics                   = "analytics"
+	StrThisMonth                   = "thisMonth"
+	StrLastMonths                  = "lastMonths"
+	StrIncome                      = "income"
+	StrOutgoings                   = "outgoings"
+	StrNet                         = "net"
+	StrIncomeVsOutgoings           = "incomeVsOutgoings"
+	StrSpendingByCategory          = "spendingByCategory"
+	StrTopMerchants                = "topMerchants"
+	StrBalanceOverTime             = "balanceOverTime"
+	StrOtherCategories             = "otherCategories"
+	StrNoSpending                  = "noSpending"
+	StrUncategorized               = "uncategorized"
+	StrBudgets                     = "budgets"
+	StrAddBudget                   = "addBudget"
+	StrBudgetCategory              = "budgetCategory"
+	StrBudgetLimit                 = "budgetLimit"
+	StrBudgetSpent                 = "budgetSpent"
+	StrBudgetProjected             = "budgetProjected"
+	StrBudgetWarningAt             = "budgetWarningAt"
+	StrNoBudgets                   = "noBudgets"
+	StrKnownCategories             = "knownCategories"
+	StrBudgetWarningAlert          = "budgetWarningAlert"
+	StrBudgetExceededAlert         = "budgetExceededAlert"
+	StrBudgetSaved                 = "budgetSaved"
+	StrBudgetRemoved               = "budgetRemoved"
+	StrMissingCategory             = "missingCategory"
+	StrInvalidLimit                = "invalidLimit"
+	StrRemoveBudgetConfirm         = "removeBudgetConfirm"
+	StrSubscriptions               = "subscriptions"
+	StrCadenceWeekly               = "cadenceWeekly"
+	StrCadenceFortnightly          = "cadenceFortnightly"
+	StrCadenceMonthly              = "cadenceMonthly"
+	StrCadenceQuarterly            = "cadenceQuarterly"
+	StrCadenceYearly               = "cadenceYearly"
+	StrRecurringAmount             = "recurringAmount"
+	StrNextPaymentDue              = "nextPaymentDue"
+	StrPaymentMissing              = "paymentMissing"
+	StrPaymentEnded                = "paymentEnded"
+	StrPriceIncreased              = "priceIncreased"
+	StrNoSubscriptions             = "noSubscriptions"
+	StrPaymentMissingAlert         = "paymentMissingAlert"
+	StrPriceIncreasedAlert         = "priceIncreasedAlert"
+	StrMandates                    = "mandates"
+	StrDirectDebits                = "directDebits"
+	StrStandingOrders              = "standingOrders"
+	StrNoMandates                  = "noMandates"
+	StrReference                   = "reference"
+	StrLastPaid                    = "lastPaid"
+	StrNextPayment                 = "nextPayment"
+	StrNextCollection              = "nextCollection"
+	StrCancelMandate               = "cancelMandate"
+	StrConfirmCancelDirectDebit    = "confirmCancelDirectDebit"
+	StrConfirmCancelStandingOrder  = "confirmCancelStandingOrder"
+	StrDirectDebitCancelled        = "directDebitCancelled"
+	StrStandingOrderCancelled      = "standingOrderCancelled"
+	StrCancelMandateFailed         = "cancelMandateFailed"
+	StrBudgetCurrency              = "budgetCurrency"
+	StrSyncEvery                   = "syncEvery"
+	StrAutoLock                    = "autoLock"
+	StrPrivacyMode                 = "privacyMode"
+	StrNever                       = "never"
+	StrMinutesShort                = "minutesShort"
+	StrHoursShort                  = "hoursShort"
+	StrSaveSettingsFailed          = "saveSettingsFailed"
+	StrAccounts                    = "accounts"
+	StrSinceDate                   = "sinceDate"
+	StrOpenLinkFailed              = "openLinkFailed"
+	StrTransactionNotFound         = "transactionNotFound"
+	StrWalletLocked                = "walletLocked"
+	StrWalletLockedInfo            = "walletLockedInfo"
+	StrPasswordOrPIN               = "passwordOrPIN"
+	StrUnlockThrottled             = "unlockThrottled"
+	StrSetUnlockPIN                = "setUnlockPIN"
+	StrSetUnlockPINInfo            = "setUnlockPINInfo"
+	StrRemoveUnlockPIN             = "removeUnlockPIN"
+	StrInvalidUnlockPIN            = "invalidUnlockPIN"
+	StrUnlockPINSaved              = "unlockPINSaved"
+	StrUnlockPINRemoved            = "unlockPINRemoved"
+	StrPIN                         = "pin"
+	StrPrivacyModeOn               = "privacyModeOn"
+	StrPrivacyModeOff              = "privacyModeOff"
+	StrLoginExpired                = "loginExpired"
+	StrLoggedInAgain               = "loggedInAgain"
+	StrPotLockedError              = "potLockedError"
+	StrProfileSyncFailed           = "profileSyncFailed"
 	DefaultLanguage                = localizable.ENGLISH
 	commentPrefix                  = "/"
 )
@@ -514,6 +670,17 @@ func init() {
 	languageStrings[localizable.ENGLISH] = en
 }
 
+// SetUserLanguage makes String prefer the strings of lang, falling back to
+// DefaultLanguage for those it lacks. Languages without strings are ignored.
+func SetUserLanguage(lang string) {
+	base := strings.SplitN(lang, "-", 2)[0]
+	if _, ok := languageStrings[base]; !ok || base == DefaultLanguage {
+		UserLanguages = []string{DefaultLanguage}
+		return
+	}
+	UserLanguages = []string{base, DefaultLanguage}
+}
+
 func String(key string) string {
 	for _, lang := range UserLanguages {
 		languageMap := languageStrings[lang]
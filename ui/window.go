@@ -4,9 +4,11 @@ import (
 	"errors"
 	giouiApp "gioui.org/app"
 	"gioui.org/io/key"
+	"gioui.org/io/pointer"
 	"gioui.org/io/system"
 	"gioui.org/layout"
 	"gioui.org/op"
+	"gioui.org/op/clip"
 	"github.com/sirupsen/logrus"
 	"go-monzo-wallet/internal"
 	"go-monzo-wallet/ui/assets"
@@ -16,6 +18,8 @@ import (
 	"go-monzo-wallet/ui/values"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
+	"sync"
+	"time"
 )
 
 type (
@@ -23,10 +27,51 @@ type (
 	D = layout.Dimensions
 )
 
+const (
+	// windowKeyTag is the tag of the key events handled by the window
+	// itself, see KeysToHandle.
+	windowKeyTag = "window"
+	// activityTag is the tag of the pointer and key events that count as
+	// activity, which postpones locking the app.
+	activityTag = "activity"
+
+	// lockCheckInterval is how often the app checks whether it has been idle
+	// long enough to lock.
+	lockCheckInterval = 5 * time.Second
+)
+
+// activityKeys are the keys that count as activity when pressed while no
+// page or editor handles them.
+var activityKeys = key.Set("(Shift)-[A,B,C,D,E,F,G,H,I,J,K,L,M,N,O,P,Q,R,S,T,U,V,W,X,Y,Z," +
+	"0,1,2,3,4,5,6,7,8,9,Space," + key.NameEnter + "," + key.NameDeleteBackward + "," +
+	key.NameUpArrow + "," + key.NameDownArrow + "," + key.NamePageUp + "," + key.NamePageDown + "]")
+
 type Window struct {
 	*giouiApp.Window
 	load      *handlers.Load
 	navigator handlers.WindowNavigator
+	// router resolves the paths of monzo-wallet:// links.
+	router *handlers.Router
+
+	linkMtx     sync.Mutex
+	pendingLink string
+	// relogins are the profiles to log in to again after the background
+	// sync found their tokens revoked, see watchLogins. Guarded by linkMtx.
+	relogins []*internal.Profile
+
+	// forward holds the pages left by going back, most recent last, see
+	// goForward.
+	forward []forwardStep
+
+	// lockPage is displayed instead of the other pages while the app is
+	// locked.
+	lockPage handlers.Page
+}
+
+// forwardStep is a page that was left by going back and the page that going
+// back led to.
+type forwardStep struct {
+	page, from handlers.Page
 }
 
 func CreateWindow() (*Window, error) {
@@ -43,33 +88,246 @@ func CreateWindow() (*Window, error) {
 		return nil, err
 	}
 	win.load = l
+	win.router = pages.NewRouter(l)
+	go win.watchLogins()
 
 	return win, nil
 
 }
 
 func (win *Window) NewLoad() (*handlers.Load, error) {
-	th := components.NewTheme(assets.FontCollection(), assets.Icons, false)
+	// A missing or invalid config is reported by the start page, which
+	// offers to set it up. The defaults are used until then.
+	cfg, err := internal.LoadConfig()
+	if cfg == nil {
+		logrus.Info("reading config:", err)
+		cfg = internal.DefaultConfig()
+	}
+
+	th := components.NewTheme(assets.FontCollection(), assets.Icons, cfg.Theme == internal.ThemeDark)
 	if th == nil {
 		return nil, errors.New("unexpected error while loading theme")
 	}
 
 	l := &handlers.Load{
-		Theme:   th,
-		Toast:   components.NewToast(th),
-		Printer: message.NewPrinter(language.English),
-		WL:      &internal.Wallet{},
+		Theme:  th,
+		Toast:  components.NewToast(th),
+		WL:     internal.NewWallet(internal.MonzoProvider(internal.MonzoBaseURL)),
+		Locker: internal.NewLocker(cfg.AutoLock),
+	}
+	l.SetPrivacyMode(cfg.HideBalances)
+	setLanguage(l, cfg.Language)
+
+	if dir, err := internal.AppDataDir(); err != nil {
+		logrus.Info("reading unlock PIN:", err)
+	} else if err := l.Locker.LoadPIN(dir); err != nil {
+		logrus.Info("reading unlock PIN:", err)
+	}
+
+	budgets, err := cfg.BudgetList()
+	if err != nil {
+		logrus.Info("reading budgets:", err)
+	}
+	l.Budgets = internal.NewBudgetMonitor(budgets, cfg.BudgetWarning, l.WL)
+	go watchBudgets(l)
+	l.Recurring = internal.NewRecurringMonitor(l.WL)
+	go watchRecurring(l)
+
+	if l.SystemNotification, err = components.NewSystemNotification(); err != nil {
+		logrus.Info("setting up system notifications:", err)
+	} else {
+		go notifyTransactions(l)
+	}
+
+	// The settings callbacks apply the config as saved by the settings
+	// page.
+	l.DarkModeSettingChanged = func(isDarkModeOn bool) {
+		l.Theme.SwitchDarkMode(isDarkModeOn, assets.Icons)
+		win.navigator.Reload()
+	}
+	l.LanguageSettingChanged = func() {
+		if cfg, err := internal.LoadConfig(); cfg != nil {
+			setLanguage(l, cfg.Language)
+		} else {
+			logrus.Info("reading config:", err)
+		}
+	}
+	l.CurrencySettingChanged = func() {
+		cfg, err := internal.LoadConfig()
+		if cfg == nil {
+			logrus.Info("reading config:", err)
+			return
+		}
+		// The currency only sets that of the budgets without one.
+		budgets, err := cfg.BudgetList()
+		if err != nil {
+			logrus.Info("reading budgets:", err)
+			return
+		}
+		l.Budgets.SetBudgets(budgets, cfg.BudgetWarning)
+	}
+
+	// Privacy mode is switched right away, even if it cannot be saved.
+	l.TogglePrivacyMode = func() {
+		privacyMode := !l.IsPrivacyMode()
+		l.SetPrivacyMode(privacyMode)
+		msg := values.String(values.StrPrivacyModeOff)
+		if privacyMode {
+			msg = values.String(values.StrPrivacyModeOn)
+		}
+		l.Toast.Notify(msg)
+		win.navigator.Reload()
+
+		_, err := internal.UpdateConfig(func(cfg *internal.Config) {
+			cfg.HideBalances = privacyMode
+		})
+		if err != nil {
+			logrus.Info("saving privacy mode:", err)
+			l.Toast.NotifyError(values.StringF(values.StrSaveSettingsFailed, err))
+		}
+	}
+
+	l.ToggleSync = func() {
+		if syncer := l.WL.Syncer(); syncer.Running() {
+			syncer.Stop()
+		} else {
+			syncer.Start()
+		}
 	}
 
 	return l, nil
 
 }
 
+// setLanguage shows the UI in lang, or in English if lang is not a language
+// tag, and formats numbers for it.
+func setLanguage(l *handlers.Load, lang string) {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		tag = language.English
+	}
+	values.SetUserLanguage(tag.String())
+	l.Printer = message.NewPrinter(tag)
+}
+
+// notifyTransactions shows a system notification for every transaction
+// received between syncs, e.g. through a webhook.
+func notifyTransactions(l *handlers.Load) {
+	events, _ := l.WL.Syncer().Subscribe()
+	for event := range events {
+		received, ok := event.(internal.TransactionReceived)
+		if !ok {
+			continue
+		}
+
+		transaction := received.Transaction
+		// Notifications are shown outside of the app, so the amount is
+		// hidden while it is locked.
+		amount := l.FormatMoney(transaction.Amount)
+		msg := values.StringF(values.StrTransactionReceived, transaction.Title(), amount)
+		if transaction.Status == internal.TransactionDeclined {
+			msg = values.StringF(values.StrTransactionDeclined, transaction.Title(), amount)
+		}
+		if err := l.SystemNotification.Notify(msg); err != nil {
+			logrus.Info("showing notification:", err)
+		}
+	}
+}
+
+// watchBudgets checks the budgets whenever the transactions change and warns
+// about those that reach their warning threshold or limit.
+func watchBudgets(l *handlers.Load) {
+	events, _ := l.WL.Syncer().Subscribe()
+	for event := range events {
+		switch event.(type) {
+		case internal.SyncFinished, internal.TransactionReceived:
+		default:
+			continue
+		}
+
+		for _, status := range l.Budgets.Check(l.WL.AccountsList(), time.Now()) {
+			category := pages.CategoryName(status.Budget.Category)
+			spent := l.FormatMoney(status.Spent)
+			limit := l.FormatMoney(status.Budget.Limit)
+			msg := values.StringF(values.StrBudgetWarningAlert, category, spent, limit)
+			if status.Level == internal.BudgetExceeded {
+				msg = values.StringF(values.StrBudgetExceededAlert, category, spent, limit)
+			}
+			alert(l, msg)
+		}
+	}
+}
+
+// watchRecurring checks the recurring payments whenever the transactions
+// change and warns about payments that are missing or went up in price.
+func watchRecurring(l *handlers.Load) {
+	events, _ := l.WL.Syncer().Subscribe()
+	for event := range events {
+		switch event.(type) {
+		case internal.SyncFinished, internal.TransactionReceived:
+		default:
+			continue
+		}
+
+		for _, a := range l.Recurring.Check(l.WL.AccountsList(), time.Now()) {
+			payment := a.Payment
+			msg := values.StringF(values.StrPriceIncreasedAlert, payment.Merchant,
+				l.FormatMoney(payment.PreviousAmount), l.FormatMoney(payment.Amount))
+			if a.Missing {
+				msg = values.StringF(values.StrPaymentMissingAlert, payment.Merchant,
+					l.FormatMoney(payment.Amount), payment.NextDue.Local().Format(pages.DueDateFormat))
+			}
+			alert(l, msg)
+		}
+	}
+}
+
+// watchLogins asks for a new login whenever the
+// background sync skips a profile because its token was revoked.
+func (win *Window) watchLogins() {
+	events, _ := win.load.WL.Syncer().Subscribe()
+	for event := range events {
+		loginRequired, ok := event.(internal.LoginRequired)
+		if !ok {
+			continue
+		}
+
+		win.linkMtx.Lock()
+		win.relogins = append(win.relogins, loginRequired.Profile)
+		win.linkMtx.Unlock()
+		win.Invalidate()
+	}
+}
+
+// alert shows msg as a toast and as a system notification.
+func alert(l *handlers.Load, msg string) {
+	l.Toast.NotifyError(msg, components.Long)
+	if l.SystemNotification == nil {
+		return
+	}
+	if err := l.SystemNotification.Notify(msg); err != nil {
+		logrus.Info("showing notification:", err)
+	}
+}
+
 // HandleEvents runs main event handling and page rendering loop.
+// The app locks when no pointer or key activity was seen in its frames for
+// the auto-lock timeout.
 func (win *Window) HandleEvents() {
+	lockTicker := time.NewTicker(lockCheckInterval)
+	defer lockTicker.Stop()
 
 	for {
-		e := <-win.Events()
+		var e interface{}
+		select {
+		case e = <-win.Events():
+		case now := <-lockTicker.C:
+			if win.load.Locker.LockIfIdle(now) {
+				win.Invalidate()
+			}
+			continue
+		}
+
 		switch evt := e.(type) {
 
 		case system.DestroyEvent:
@@ -77,13 +335,59 @@ func (win *Window) HandleEvents() {
 			return // exits the loop, caller will exit the program.
 
 		case system.FrameEvent:
+			win.trackActivity(evt)
 			ops := win.handleFrameEvent(evt)
 			evt.Frame(ops)
 
 		default:
-			logrus.Info("Unhandled window event %v\n", e)
+			logrus.Infof("Unhandled window event %v", e)
+		}
+	}
+}
+
+// trackActivity postpones locking the app if evt has pointer or key events
+// for activityTag, see addActivityOps.
+func (win *Window) trackActivity(evt system.FrameEvent) {
+	if len(evt.Queue.Events(activityTag)) > 0 {
+		win.load.Locker.Touch(evt.Now)
+	}
+}
+
+// addActivityOps requests the events that count as activity: pointer events
+// anywhere in the window, which are passed on to the widgets below, and the
+// activityKeys that nothing else handles. The key handler is the window's
+// bottom-most one, so it must be added before any other.
+func (win *Window) addActivityOps(gtx C) {
+	key.InputOp{Tag: activityTag, Keys: activityKeys}.Add(gtx.Ops)
+
+	// The pointer handler is added after all other operations, so that it
+	// is above the widgets and modals that take the events it passes on.
+	m := op.Record(gtx.Ops)
+	area := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	pass := pointer.PassOp{}.Push(gtx.Ops)
+	pointer.InputOp{Tag: activityTag, Types: pointer.Press | pointer.Move}.Add(gtx.Ops)
+	pass.Pop()
+	area.Pop()
+	op.Defer(gtx.Ops, m.Stop())
+}
+
+// lockedPage returns the lockPage if the app is locked, displaying it if it
+// was not, or nil if the app is unlocked.
+func (win *Window) lockedPage() handlers.Page {
+	if !win.load.Locker.Locked() {
+		if win.lockPage != nil {
+			win.lockPage.OnNavigatedFrom()
+			win.lockPage = nil
 		}
+		return nil
+	}
+
+	if win.lockPage == nil {
+		win.lockPage = pages.NewLockPage(win.load)
+		win.lockPage.OnAttachedToNavigator(win.navigator)
+		win.lockPage.OnNavigatedTo()
 	}
+	return win.lockPage
 }
 
 // handleFrameEvent is called when a FrameEvent is received by the active
@@ -92,9 +396,14 @@ func (win *Window) HandleEvents() {
 // is returned to the caller for displaying on screen.
 func (win *Window) handleFrameEvent(evt system.FrameEvent) *op.Ops {
 	switch {
+	case win.lockedPage() != nil:
+		// Nothing but the lock page takes input while the app is locked.
+		win.lockPage.HandleUserInteractions()
+
 	case win.navigator.CurrentPage() == nil:
-		// Prepare to display the StartPage if no page is currently displayed.
-		win.navigator.Display(pages.NewStartPage(win.load))
+		// Prepare to display the MainPage, which starts with the accounts tab,
+		// if no page is currently displayed.
+		win.navigator.Display(pages.NewMainPage(win.load))
 
 	default:
 		// The app window may have received some user interaction such as key
@@ -103,6 +412,8 @@ func (win *Window) handleFrameEvent(evt system.FrameEvent) *op.Ops {
 		// ensures that the proper interface is displayed to the user based on
 		// the action(s) they just performed.
 		win.handleRelevantKeyPresses(evt)
+		win.showReloginModal()
+		win.openPendingLink()
 		win.navigator.CurrentPage().HandleUserInteractions()
 		if modal := win.navigator.TopModal(); modal != nil {
 			modal.Handle()
@@ -140,6 +451,138 @@ func (win *Window) handleRelevantKeyPresses(evt system.FrameEvent) {
 	} else {
 		handleKeyPressFor(win.navigator.CurrentPageID(), win.navigator.CurrentPage())
 	}
+	handleKeyPressFor(windowKeyTag, win)
+}
+
+// KeysToHandle returns the keys that navigate back, Back, Escape and
+// Alt+Left, the key that navigates forward, Alt+Right, and the key that
+// toggles privacy mode, Ctrl+Shift+H (Cmd+Shift+H on macOS).
+// Part of the load.KeyEventHandler interface.
+func (win *Window) KeysToHandle() key.Set {
+	return key.Set(key.NameBack + "|" + key.NameEscape + "|Alt-[" + key.NameLeftArrow + "," + key.NameRightArrow + "]" +
+		"|Short-Shift-H")
+}
+
+// HandleKeyPress navigates back or forward, or toggles privacy mode.
+// Part of the load.KeyEventHandler interface.
+func (win *Window) HandleKeyPress(evt *key.Event) {
+	switch evt.Name {
+	case "H":
+		win.load.TogglePrivacyMode()
+	case key.NameRightArrow:
+		win.goForward()
+	default:
+		win.goBack()
+	}
+}
+
+// pageNavigator returns the navigator of the pages on display: the current
+// page if it hosts pages, like the MainPage, or else the window.
+func (win *Window) pageNavigator() handlers.PageNavigator {
+	if navigator, ok := win.navigator.CurrentPage().(handlers.PageNavigator); ok {
+		return navigator
+	}
+	return win.navigator
+}
+
+// goBack dismisses the top modal if there is one and it is cancelable, or
+// else closes the current page. The page is kept for goForward unless it is
+// Closable, as it may not be displayed again once closed.
+func (win *Window) goBack() {
+	if modal := win.navigator.TopModal(); modal != nil {
+		if cancelable, ok := modal.(handlers.CancelableModal); ok && cancelable.IsCancelable() {
+			win.navigator.DismissModal(modal.ID())
+		}
+		return
+	}
+
+	navigator := win.pageNavigator()
+	page := navigator.CurrentPage()
+	navigator.CloseCurrentPage()
+	current := navigator.CurrentPage()
+	if page == nil || current == page {
+		return
+	}
+	if _, closable := page.(handlers.Closable); !closable {
+		win.forward = append(win.forward, forwardStep{page: page, from: current})
+	}
+}
+
+// goForward displays the page last left by goBack again, unless another page
+// was displayed since, which ends the forward history.
+func (win *Window) goForward() {
+	n := len(win.forward)
+	if n == 0 {
+		return
+	}
+	step := win.forward[n-1]
+	win.forward = win.forward[:n-1]
+
+	navigator := win.pageNavigator()
+	if navigator.CurrentPage() != step.from {
+		win.forward = nil
+		return
+	}
+	navigator.Display(step.page)
+}
+
+// OpenLink displays the page that a monzo-wallet:// link leads to, once the
+// wallet is loaded.
+func (win *Window) OpenLink(link string) {
+	win.linkMtx.Lock()
+	win.pendingLink = link
+	win.linkMtx.Unlock()
+	win.Invalidate()
+}
+
+// showReloginModal asks over the current page to log in to the next profile
+// passed by watchLogins again, if there is one. Each login waits for the
+// modals on display to be closed.
+func (win *Window) showReloginModal() {
+	if win.navigator.TopModal() != nil {
+		return
+	}
+
+	win.linkMtx.Lock()
+	if len(win.relogins) == 0 {
+		win.linkMtx.Unlock()
+		return
+	}
+	profile := win.relogins[0]
+	win.relogins = win.relogins[1:]
+	win.linkMtx.Unlock()
+
+	pages.ShowReloginModal(win.load, win.navigator, profile)
+}
+
+// openPendingLink displays the page of the link passed to OpenLink or
+// received with the last login, if the wallet is loaded.
+func (win *Window) openPendingLink() {
+	if !win.load.WL.LoadedWallet() {
+		return
+	}
+
+	win.linkMtx.Lock()
+	link := win.pendingLink
+	win.pendingLink = ""
+	win.linkMtx.Unlock()
+	if link == "" {
+		link = win.load.WL.TakeDeepLink()
+	}
+	if link == "" {
+		return
+	}
+
+	path, err := internal.DeepLinkPath(link)
+	if err == nil {
+		var page handlers.Page
+		if page, err = win.router.Resolve(path); err == nil {
+			win.pageNavigator().Display(page)
+			return
+		}
+	}
+	logrus.Info("opening link:", err)
+	win.load.Toast.NotifyError(values.StringF(values.StrOpenLinkFailed, link, err))
 }
 
 // prepareToDisplayUI creates an operation list and writes the layout of all the
@@ -151,6 +594,20 @@ func (win *Window) prepareToDisplayUI(evt system.FrameEvent) *op.Ops {
 		return components.Fill(gtx, win.load.Theme.Color.Gray4)
 	})
 
+	// The pages and modals are not laid out at all while the app is locked,
+	// so that nothing shows through.
+	if lockPage := win.lockedPage(); lockPage != nil {
+		ops := &op.Ops{}
+		gtx := layout.NewContext(ops, evt)
+		win.load.CurrentAppWidth = gtx.Constraints.Max.X
+		layout.Stack{Alignment: layout.N}.Layout(
+			gtx,
+			backgroundWidget,
+			layout.Stacked(lockPage.Layout),
+		)
+		return ops
+	}
+
 	currentPageWidget := layout.Stacked(func(gtx C) D {
 		if modal := win.navigator.TopModal(); modal != nil {
 			gtx = gtx.Disabled()
@@ -170,6 +627,8 @@ func (win *Window) prepareToDisplayUI(evt system.FrameEvent) *op.Ops {
 	// list via a graphical context that is linked to the ops.
 	ops := &op.Ops{}
 	gtx := layout.NewContext(ops, evt)
+	win.load.CurrentAppWidth = gtx.Constraints.Max.X
+	win.addActivityOps(gtx)
 	layout.Stack{Alignment: layout.N}.Layout(
 		gtx,
 		backgroundWidget,
@@ -186,6 +645,11 @@ func (win *Window) prepareToDisplayUI(evt system.FrameEvent) *op.Ops {
 // operations list with instructions to generate a FrameEvent if any of the
 // desired keys is pressed on the window.
 func (win *Window) addKeyEventRequestsToOps(ops *op.Ops) {
+	if win.lockPage != nil {
+		// The lock page only takes input through its editor.
+		return
+	}
+
 	requestKeyEvents := func(tag string, desiredKeys key.Set) {
 		if desiredKeys == "" {
 			return
@@ -212,4 +676,7 @@ func (win *Window) addKeyEventRequestsToOps(ops *op.Ops) {
 			requestKeyEvents(win.navigator.CurrentPageID(), handler.KeysToHandle())
 		}
 	}
+	// A focused editor that takes one of the window's keys, e.g. Alt+Left,
+	// gets it before the window does.
+	requestKeyEvents(windowKeyTag, win.KeysToHandle())
 }